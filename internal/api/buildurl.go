@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrForeignURL is returned by GetBuildByURL and WaitBuildByURL when an
+// absolute URL points somewhere other than the API. Those URLs usually come
+// from server responses, and following one elsewhere would send the API key
+// to whoever wrote it.
+var ErrForeignURL = errors.New("url is not on the API host")
+
+// WithAllowedHosts lets GetBuildByURL and WaitBuildByURL follow absolute
+// URLs on these hosts as well as the API's own, for deployments that serve
+// build status from a separate domain. A host may include a port; without
+// one it matches any port.
+func WithAllowedHosts(hosts ...string) ClientOption {
+	return func(c *Client) {
+		for _, host := range hosts {
+			if host = strings.TrimSpace(host); host != "" {
+				c.allowedHosts = append(c.allowedHosts, strings.ToLower(host))
+			}
+		}
+	}
+}
+
+// resolveBuildURL parses a status or wait URL. Relative URLs resolve against
+// the base URL; absolute ones must use the base URL's scheme and host, or an
+// allowed host.
+func (c *Client) resolveBuildURL(kind, raw string) (*url.URL, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, fmt.Errorf("%s url is empty", kind)
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s url: %w", kind, err)
+	}
+	if parsed.Scheme == "" && parsed.Host == "" {
+		return c.baseURL.ResolveReference(parsed), nil
+	}
+	if !c.trustsURL(parsed) {
+		return nil, fmt.Errorf("%s url %s://%s: %w", kind, parsed.Scheme, parsed.Host, ErrForeignURL)
+	}
+	if parsed.Scheme == "" {
+		// Scheme-relative: keep the API's scheme.
+		parsed.Scheme = c.baseURL.Scheme
+	}
+	return parsed, nil
+}
+
+func (c *Client) trustsURL(u *url.URL) bool {
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "" && scheme != "http" && scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	if host == strings.ToLower(c.baseURL.Host) && (scheme == "" || scheme == strings.ToLower(c.baseURL.Scheme)) {
+		return true
+	}
+	for _, allowed := range c.allowedHosts {
+		if host == allowed || strings.ToLower(u.Hostname()) == allowed {
+			return true
+		}
+	}
+	return false
+}
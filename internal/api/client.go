@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
@@ -11,7 +12,9 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,13 +26,88 @@ const defaultWaitTimeout = defaultTimeout + 10*time.Second
 var ErrMissingAPIKey = errors.New("missing API key")
 
 // Client wraps Twinkle API calls.
+//
+// A Client is safe for concurrent use by multiple goroutines. Its
+// configuration is fixed once NewClient returns; the only mutable state is
+// the cached server version, which is guarded by its own locks so requests
+// never wait on each other. Call observers and request signers may be
+// invoked concurrently and must be safe for that.
 type Client struct {
-	baseURL    *url.URL
-	apiKey     string
-	httpClient *http.Client
+	baseURL      *url.URL
+	apiKey       string
+	httpClient   *http.Client
+	retry        RetryPolicy
+	callObserver func(CallStats)
+	signer       RequestSigner
+	tracer       *tracer
+	curl         *curlPrinter
+	keepAlive    time.Duration
+	dialTimeout  time.Duration
+	headers      map[string]string
+
+	noIdempotencyKey bool
+	maxResponseSize  int64
+	allowedHosts     []string
+
+	waitIdleTimeout time.Duration
+	baseTransport   http.RoundTripper
+
+	versionMu       sync.Mutex // guards serverVersion
+	serverVersion   string
+	versionObserver func(string)
+
+	healthMu      sync.Mutex // guards the health check fields
+	healthChecked bool
+	healthErr     error
 }
 
-func NewClient(baseURL, apiKey string, httpClient *http.Client) (*Client, error) {
+// ClientOption configures optional Client behavior.
+type ClientOption func(*Client)
+
+// WithRetryPolicy overrides DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithCallObserver registers a function that receives stats for every
+// completed API call, after any retries. Concurrent calls report
+// concurrently.
+func WithCallObserver(observer func(CallStats)) ClientOption {
+	return func(c *Client) {
+		c.callObserver = observer
+	}
+}
+
+// WithHeaders adds headers to every API request. They can't replace the
+// headers the client sets itself, such as Authorization. Storage uploads are
+// sent without them.
+func WithHeaders(headers map[string]string) ClientOption {
+	return func(c *Client) {
+		c.headers = headers
+	}
+}
+
+// WithoutIdempotencyKey stops CreateUpload from sending an Idempotency-Key
+// header, generated or explicit, for gateways that reject it. Without the
+// key a failed create request is no longer retried, since a retry could
+// create a second build.
+func WithoutIdempotencyKey() ClientOption {
+	return func(c *Client) {
+		c.noIdempotencyKey = true
+	}
+}
+
+// NewClient returns a client for the API at baseURL. httpClient may be nil
+// for a default client. A supplied client's transport carries every request
+// the Client makes, including long-poll waits and storage uploads; the
+// Client only copies httpClient, adjusting timeouts or redirect handling per
+// request and layering its own wrappers (tracing, curl printing, wait idle
+// timeouts) on top of the transport. WithKeepAlive and WithDialTimeout are
+// the exception: they replace an *http.Transport with a reconfigured clone.
+// BaseTransport reports the transport in use.
+func NewClient(baseURL, apiKey string, httpClient *http.Client, opts ...ClientOption) (*Client, error) {
 	if strings.TrimSpace(apiKey) == "" {
 		return nil, ErrMissingAPIKey
 	}
@@ -40,7 +118,91 @@ func NewClient(baseURL, apiKey string, httpClient *http.Client) (*Client, error)
 	if httpClient == nil {
 		httpClient = &http.Client{Timeout: defaultTimeout}
 	}
-	return &Client{baseURL: parsed, apiKey: apiKey, httpClient: httpClient}, nil
+	client := &Client{baseURL: parsed, apiKey: apiKey, httpClient: httpClient, retry: DefaultRetryPolicy, maxResponseSize: DefaultMaxResponseSize}
+	for _, opt := range opts {
+		opt(client)
+	}
+	if client.keepAlive > 0 || client.dialTimeout > 0 {
+		client.httpClient = withDialer(client.httpClient, client.keepAlive, client.dialTimeout)
+	}
+	client.baseTransport = client.httpClient.Transport
+	if client.baseTransport == nil {
+		client.baseTransport = http.DefaultTransport
+	}
+	if client.tracer != nil {
+		client.httpClient = client.tracer.wrap(client.httpClient)
+	}
+	if client.curl != nil {
+		client.curl.apiHost = parsed.Host
+		client.httpClient = client.curl.wrap(client.httpClient)
+	}
+	return client, nil
+}
+
+// WithBaseURL returns a copy of c that sends requests to baseURL. The copy
+// shares c's HTTP client, and so its transport and connection pool, and all
+// of its options; c itself is unchanged. The cached server version and
+// health check belong to the old host and start over in the copy.
+func (c *Client) WithBaseURL(baseURL string) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse base url: %w", err)
+	}
+	return &Client{
+		baseURL:         parsed,
+		apiKey:          c.apiKey,
+		httpClient:      c.httpClient,
+		retry:           c.retry,
+		callObserver:    c.callObserver,
+		signer:          c.signer,
+		tracer:          c.tracer,
+		curl:            c.curl,
+		keepAlive:       c.keepAlive,
+		dialTimeout:     c.dialTimeout,
+		headers:         c.headers,
+		waitIdleTimeout: c.waitIdleTimeout,
+		baseTransport:   c.baseTransport,
+
+		noIdempotencyKey: c.noIdempotencyKey,
+		maxResponseSize:  c.maxResponseSize,
+		allowedHosts:     c.allowedHosts,
+	}, nil
+}
+
+// BaseTransport returns the transport that sends all of the client's
+// requests, beneath the client's own wrappers: the supplied HTTP client's
+// transport, http.DefaultTransport when it had none, or the clone made by
+// WithKeepAlive or WithDialTimeout.
+func (c *Client) BaseTransport() http.RoundTripper {
+	return c.baseTransport
+}
+
+func (c *Client) GetApp(ctx context.Context, appID string) (App, error) {
+	endpoint := c.withPath("/api/v1/apps/%s", appID)
+	var resp AppResponse
+	if err := c.doJSON(ctx, http.MethodGet, endpoint, nil, &resp); err != nil {
+		return App{}, err
+	}
+	return resp.App, nil
+}
+
+// DeleteApp permanently deletes an app and its builds.
+func (c *Client) DeleteApp(ctx context.Context, appID string) error {
+	endpoint := c.withPath("/api/v1/apps/%s", appID)
+	return c.doJSON(ctx, http.MethodDelete, endpoint, nil, nil)
+}
+
+// ListApps returns the apps the API key can access.
+func (c *Client) ListApps(ctx context.Context, params ListAppsParams) ([]App, error) {
+	endpoint := c.withPath("/api/v1/apps")
+	if params.Platform != "" {
+		endpoint.RawQuery = url.Values{"platform": {params.Platform}}.Encode()
+	}
+	var resp AppsResponse
+	if err := c.doJSON(ctx, http.MethodGet, endpoint, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Apps, nil
 }
 
 func (c *Client) GetBuild(ctx context.Context, appID, buildID string) (BuildResponse, error) {
@@ -52,16 +214,22 @@ func (c *Client) GetBuild(ctx context.Context, appID, buildID string) (BuildResp
 	return resp, nil
 }
 
-func (c *Client) GetBuildByURL(ctx context.Context, statusURL string) (BuildResponse, error) {
-	if strings.TrimSpace(statusURL) == "" {
-		return BuildResponse{}, fmt.Errorf("status url is empty")
+// GetBuildRaw fetches a build like GetBuild but returns the response body
+// exactly as the server sent it, for debugging model mismatches. Non-2xx
+// responses still come back as *APIError.
+func (c *Client) GetBuildRaw(ctx context.Context, appID, buildID string) (RawResponse, error) {
+	endpoint := c.withPath("/api/v1/apps/%s/builds/%s", appID, buildID)
+	var resp RawResponse
+	if err := c.doJSON(ctx, http.MethodGet, endpoint, nil, &resp); err != nil {
+		return RawResponse{}, err
 	}
-	parsed, err := url.Parse(statusURL)
+	return resp, nil
+}
+
+func (c *Client) GetBuildByURL(ctx context.Context, statusURL string) (BuildResponse, error) {
+	parsed, err := c.resolveBuildURL("status", statusURL)
 	if err != nil {
-		return BuildResponse{}, fmt.Errorf("parse status url: %w", err)
-	}
-	if parsed.Scheme == "" {
-		parsed = c.baseURL.ResolveReference(parsed)
+		return BuildResponse{}, err
 	}
 	var resp BuildResponse
 	if err := c.doJSON(ctx, http.MethodGet, parsed, nil, &resp); err != nil {
@@ -72,6 +240,7 @@ func (c *Client) GetBuildByURL(ctx context.Context, statusURL string) (BuildResp
 
 func (c *Client) WaitBuild(ctx context.Context, appID, buildID string, timeoutSeconds int) (BuildResponse, error) {
 	endpoint := c.withPath("/api/v1/apps/%s/builds/%s/wait", appID, buildID)
+	timeoutSeconds = c.waitWindow(timeoutSeconds)
 	if timeoutSeconds > 0 {
 		query := endpoint.Query()
 		query.Set("timeout", fmt.Sprintf("%d", timeoutSeconds))
@@ -86,16 +255,11 @@ func (c *Client) WaitBuild(ctx context.Context, appID, buildID string, timeoutSe
 }
 
 func (c *Client) WaitBuildByURL(ctx context.Context, waitURL string, timeoutSeconds int) (BuildResponse, error) {
-	if strings.TrimSpace(waitURL) == "" {
-		return BuildResponse{}, fmt.Errorf("wait url is empty")
-	}
-	parsed, err := url.Parse(waitURL)
+	parsed, err := c.resolveBuildURL("wait", waitURL)
 	if err != nil {
-		return BuildResponse{}, fmt.Errorf("parse wait url: %w", err)
-	}
-	if parsed.Scheme == "" {
-		parsed = c.baseURL.ResolveReference(parsed)
+		return BuildResponse{}, err
 	}
+	timeoutSeconds = c.waitWindow(timeoutSeconds)
 	if timeoutSeconds > 0 {
 		query := parsed.Query()
 		query.Set("timeout", fmt.Sprintf("%d", timeoutSeconds))
@@ -109,6 +273,97 @@ func (c *Client) WaitBuildByURL(ctx context.Context, waitURL string, timeoutSeco
 	return resp, nil
 }
 
+func (c *Client) ListBuilds(ctx context.Context, appID string, params ListBuildsParams) (BuildListResponse, error) {
+	endpoint := c.withPath("/api/v1/apps/%s/builds", appID)
+	endpoint.RawQuery = params.query().Encode()
+
+	var resp BuildListResponse
+	if err := c.doJSON(ctx, http.MethodGet, endpoint, nil, &resp); err != nil {
+		return BuildListResponse{}, err
+	}
+	return resp, nil
+}
+
+func (p ListBuildsParams) query() url.Values {
+	query := url.Values{}
+	if p.Limit > 0 {
+		query.Set("limit", fmt.Sprintf("%d", p.Limit))
+	}
+	if p.Cursor != "" {
+		query.Set("cursor", p.Cursor)
+	}
+	if !p.Since.IsZero() {
+		query.Set("since", p.Since.UTC().Format(time.RFC3339))
+	}
+	if !p.Until.IsZero() {
+		query.Set("until", p.Until.UTC().Format(time.RFC3339))
+	}
+	for key, value := range p.Metadata {
+		query.Set("metadata["+key+"]", value)
+	}
+	if p.AfterID > 0 {
+		query.Set("after_id", fmt.Sprintf("%d", p.AfterID))
+	}
+	return query
+}
+
+// findBuildMaxPages bounds how many pages FindBuildByMetadata reads. The
+// server filters by metadata, so a match is normally on the first page.
+const findBuildMaxPages = 10
+
+// FindBuildByMetadata returns the newest build of appID that was uploaded
+// with metadata key set to value and hasn't failed. The bool is false when
+// there is none within the first findBuildMaxPages pages. Only builds that
+// report the metadata count, in case the server didn't apply the filter.
+func (c *Client) FindBuildByMetadata(ctx context.Context, appID, key, value string) (Build, bool, error) {
+	params := ListBuildsParams{Metadata: map[string]string{key: value}}
+	for page := 0; page < findBuildMaxPages; page++ {
+		resp, err := c.ListBuilds(ctx, appID, params)
+		if err != nil {
+			return Build{}, false, err
+		}
+		for _, build := range resp.Builds {
+			if build.IsFailed() {
+				continue
+			}
+			if got, ok := build.CustomMetadata[key]; !ok || got != value {
+				continue
+			}
+			return build, true, nil
+		}
+		if resp.NextCursor == nil || *resp.NextCursor == "" {
+			break
+		}
+		params.Cursor = *resp.NextCursor
+	}
+	return Build{}, false, nil
+}
+
+func (c *Client) PromoteBuild(ctx context.Context, appID, buildID, targetChannel string) (BuildResponse, error) {
+	endpoint := c.withPath("/api/v1/apps/%s/builds/%s/promote", appID, buildID)
+	body := PromoteBuildRequest{Channel: targetChannel}
+	var resp BuildResponse
+	if err := c.doJSON(ctx, http.MethodPost, endpoint, body, &resp); err != nil {
+		return BuildResponse{}, err
+	}
+	return resp, nil
+}
+
+// RebuildBuild re-runs processing for an uploaded build without a new upload.
+func (c *Client) RebuildBuild(ctx context.Context, appID, buildID string) (BuildResponse, error) {
+	endpoint := c.withPath("/api/v1/apps/%s/builds/%s/rebuild", appID, buildID)
+	var resp BuildResponse
+	if err := c.doJSON(ctx, http.MethodPost, endpoint, nil, &resp); err != nil {
+		return BuildResponse{}, err
+	}
+	return resp, nil
+}
+
+func (c *Client) DeleteBuild(ctx context.Context, appID, buildID string) error {
+	endpoint := c.withPath("/api/v1/apps/%s/builds/%s", appID, buildID)
+	return c.doJSON(ctx, http.MethodDelete, endpoint, nil, nil)
+}
+
 func (c *Client) CreateUpload(ctx context.Context, appID string, params BuildUploadParams) (BuildUploadResponse, error) {
 	return c.CreateUploadWithOptions(ctx, appID, params)
 }
@@ -134,55 +389,247 @@ func (c *Client) CreateUploadWithOptions(ctx context.Context, appID string, para
 		opt(&options)
 	}
 	headers := map[string]string{}
-	idempotencyKey := strings.TrimSpace(options.idempotencyKey)
-	if idempotencyKey == "" {
-		idempotencyKey = uuid.NewString()
+	if !c.noIdempotencyKey {
+		idempotencyKey := strings.TrimSpace(options.idempotencyKey)
+		if idempotencyKey == "" {
+			idempotencyKey = uuid.NewString()
+		}
+		headers["Idempotency-Key"] = idempotencyKey
 	}
-	headers["Idempotency-Key"] = idempotencyKey
 	if err := c.doJSONWithHeaders(ctx, http.MethodPost, endpoint, body, &resp, headers); err != nil {
 		return BuildUploadResponse{}, err
 	}
 	return resp, nil
 }
 
+// errorCodeUploadAlreadyComplete is the 409 error code for completing an
+// upload that an earlier request already completed.
+const errorCodeUploadAlreadyComplete = "upload_already_complete"
+
+// CompleteUpload marks an upload as complete. If an earlier attempt already
+// completed it (for example before the process died), the build's current
+// state is fetched and returned as a successful completion.
 func (c *Client) CompleteUpload(ctx context.Context, appID string, buildID int) (BuildUploadCompleteResponse, error) {
 	endpoint := c.withPath("/api/v1/apps/%s/uploads/%d/complete", appID, buildID)
 	var resp BuildUploadCompleteResponse
 	if err := c.doJSON(ctx, http.MethodPost, endpoint, nil, &resp); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict && apiErr.Message == errorCodeUploadAlreadyComplete {
+			return c.alreadyCompletedUpload(ctx, appID, buildID)
+		}
 		return BuildUploadCompleteResponse{}, err
 	}
 	return resp, nil
 }
 
+// alreadyCompletedUpload describes an upload that was completed earlier,
+// confirming the build exists first.
+func (c *Client) alreadyCompletedUpload(ctx context.Context, appID string, buildID int) (BuildUploadCompleteResponse, error) {
+	id := strconv.Itoa(buildID)
+	build, err := c.GetBuild(ctx, appID, id)
+	if err != nil {
+		return BuildUploadCompleteResponse{}, fmt.Errorf("upload already complete; fetch build %d: %w", buildID, err)
+	}
+	return BuildUploadCompleteResponse{
+		BuildID:     BuildID{value: build.Build.ID},
+		StatusURL:   c.withPath("/api/v1/apps/%s/builds/%s", appID, id).String(),
+		UploadState: "complete",
+		WaitURL:     c.withPath("/api/v1/apps/%s/builds/%s/wait", appID, id).String(),
+	}, nil
+}
+
 func (c *Client) UploadFile(ctx context.Context, uploadURL, filePath, contentType string) error {
-	file, err := os.Open(filePath)
+	return c.UploadFileWithOptions(ctx, uploadURL, filePath, contentType)
+}
+
+// ErrUnexpectedContentType is returned when a successful response can't be
+// decoded because the server didn't send JSON, e.g. an HTML page from a proxy.
+var ErrUnexpectedContentType = errors.New("unexpected content type")
+
+// ErrUploadRedirected is returned when the storage backend redirects an
+// upload and redirects are not being followed.
+var ErrUploadRedirected = errors.New("upload redirected")
+
+// ErrUploadSizeMismatch is returned when the storage backend reports storing
+// a different number of bytes than the file holds.
+var ErrUploadSizeMismatch = errors.New("upload size mismatch")
+
+// storedContentLengthHeader is how Google Cloud Storage reports the size of
+// the object it stored.
+const storedContentLengthHeader = "X-Goog-Stored-Content-Length"
+
+type UploadOption func(*uploadOptions)
+
+type uploadOptions struct {
+	followRedirects bool
+	maxRate         int64
+	onRetry         func(UploadRetry)
+}
+
+// UploadRetry describes an upload attempt that is about to be retried.
+type UploadRetry struct {
+	// Attempt is the number of the retry about to start, from 1.
+	Attempt int
+	// Delay is how long the upload waits before retrying.
+	Delay time.Duration
+	// Err is the error of the failed attempt.
+	Err error
+}
+
+// WithUploadRetryObserver calls fn before each retry of the file upload, so
+// callers can report it.
+func WithUploadRetryObserver(fn func(UploadRetry)) UploadOption {
+	return func(opts *uploadOptions) {
+		opts.onRetry = fn
+	}
+}
+
+// WithFollowRedirects controls whether a redirect from the upload URL is
+// followed, re-sending the file to the new location. It defaults to true.
+func WithFollowRedirects(follow bool) UploadOption {
+	return func(opts *uploadOptions) {
+		opts.followRedirects = follow
+	}
+}
+
+// WithMaxUploadRate caps the file upload at bytesPerSecond. Zero or less
+// uploads at full speed.
+func WithMaxUploadRate(bytesPerSecond int64) UploadOption {
+	return func(opts *uploadOptions) {
+		opts.maxRate = bytesPerSecond
+	}
+}
+
+// maxUploadRetryWait caps the total time an upload spends waiting out
+// storage 503 responses.
+const maxUploadRetryWait = 2 * time.Minute
+
+// UploadFileWithOptions PUTs the file to uploadURL. When storage answers 503
+// with a Retry-After header, the file is sent again after the requested
+// delay, for up to maxUploadRetryWait in total.
+func (c *Client) UploadFileWithOptions(ctx context.Context, uploadURL, filePath, contentType string, opts ...UploadOption) error {
+	file, err := openUploadFile(filePath)
 	if err != nil {
 		return fmt.Errorf("open file: %w", err)
 	}
 	defer file.Close()
+	return c.uploadFrom(ctx, uploadURL, filePath, file, contentType, opts)
+}
+
+// UploadOpenFile is UploadFileWithOptions for a file the caller has already
+// opened and checked. Every attempt reads that handle, so the file is never
+// reopened by path. The caller closes it.
+func (c *Client) UploadOpenFile(ctx context.Context, uploadURL string, file *os.File, contentType string, opts ...UploadOption) error {
+	return c.uploadFrom(ctx, uploadURL, file.Name(), file, contentType, opts)
+}
 
+func (c *Client) uploadFrom(ctx context.Context, uploadURL, filePath string, file uploadSource, contentType string, opts []UploadOption) error {
+	options := uploadOptions{followRedirects: true}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var waited time.Duration
+	for retries := 1; ; retries++ {
+		retryAfter, err := c.uploadFileOnce(ctx, uploadURL, filePath, file, contentType, options)
+		if err == nil || retryAfter <= 0 || waited+retryAfter > maxUploadRetryWait {
+			return err
+		}
+		if options.onRetry != nil {
+			options.onRetry(UploadRetry{Attempt: retries, Delay: retryAfter, Err: err})
+		}
+		if waitErr := sleepContext(ctx, retryAfter); waitErr != nil {
+			return waitErr
+		}
+		waited += retryAfter
+	}
+}
+
+// uploadFileOnce makes a single upload attempt. On a 503 it also returns the
+// server's Retry-After delay, capped at maxRetryAfter, or zero without one.
+func (c *Client) uploadFileOnce(ctx context.Context, uploadURL, filePath string, file uploadSource, contentType string, options uploadOptions) (time.Duration, error) {
 	stat, err := file.Stat()
 	if err != nil {
-		return fmt.Errorf("stat file: %w", err)
+		return 0, fmt.Errorf("stat file: %w", err)
+	}
+
+	// body reads the file from the start. A followed 307 or 308 replays the
+	// upload through GetBody, so local always tracks the request in flight.
+	var local *fileReader
+	body := func() io.Reader {
+		local = &fileReader{r: io.NewSectionReader(file, 0, stat.Size()), path: filePath, size: stat.Size()}
+		if options.maxRate > 0 {
+			return &throttledReader{ctx: ctx, r: local, bucket: newTokenBucket(options.maxRate)}
+		}
+		return local
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, file)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, body())
 	if err != nil {
-		return fmt.Errorf("create upload request: %w", err)
+		return 0, fmt.Errorf("create upload request: %w", err)
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(body()), nil
 	}
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
 	}
 	req.ContentLength = stat.Size()
+	// The file is streamed, so upload signatures cover an empty body.
+	if c.signer != nil {
+		if err := c.signer(req, nil); err != nil {
+			return 0, fmt.Errorf("sign upload request: %w", err)
+		}
+	}
 
-	resp, err := c.httpClient.Do(req)
+	httpClient := c.httpClient
+	if !options.followRedirects {
+		noRedirects := *c.httpClient
+		noRedirects.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		httpClient = &noRedirects
+	}
+
+	resp, err := httpClient.Do(req)
+	if local.err != nil {
+		// The request failed, or was cut short, because of the disk.
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return 0, local.err
+	}
 	if err != nil {
-		return fmt.Errorf("upload file: %w", err)
+		return 0, fmt.Errorf("upload file: %w", err)
 	}
 	defer resp.Body.Close()
+	if !options.followRedirects && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		return 0, fmt.Errorf("upload file: %w: status %d to %s", ErrUploadRedirected, resp.StatusCode, resp.Header.Get("Location"))
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<10))
-		return fmt.Errorf("upload file: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		err := fmt.Errorf("upload file: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			return 0, err
+		}
+		return min(parseRetryAfter(resp.Header), maxRetryAfter), err
+	}
+	return 0, verifyStoredSize(resp.Header, stat.Size())
+}
+
+// verifyStoredSize compares the size the storage backend reports having
+// stored with the local file size. Backends that don't report a size pass.
+func verifyStoredSize(header http.Header, want int64) error {
+	value := strings.TrimSpace(header.Get(storedContentLengthHeader))
+	if value == "" {
+		return nil
+	}
+	stored, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil
+	}
+	if stored != want {
+		return fmt.Errorf("upload file: %w: storage received %d of %d bytes", ErrUploadSizeMismatch, stored, want)
 	}
 	return nil
 }
@@ -207,12 +654,55 @@ func (c *Client) doJSONWithHeaders(ctx context.Context, method string, endpoint
 }
 
 func (c *Client) doJSONWithHeadersAndClient(ctx context.Context, client *http.Client, method string, endpoint *url.URL, body interface{}, target interface{}, headers map[string]string) error {
-	var reader io.Reader
+	var payload []byte
 	if body != nil {
-		payload, err := json.Marshal(body)
+		encoded, err := json.Marshal(body)
 		if err != nil {
 			return fmt.Errorf("encode request: %w", err)
 		}
+		payload = encoded
+	}
+
+	maxAttempts := c.retry.MaxAttempts
+	if maxAttempts < 1 || !isRetryableRequest(method, headers) {
+		maxAttempts = 1
+	}
+
+	start := time.Now()
+	attempts := 0
+	var err error
+	for {
+		attempts++
+		err = c.doRequest(ctx, client, method, endpoint, payload, body != nil, target, headers)
+		if err == nil || attempts >= maxAttempts || ctx.Err() != nil || !isRetryableError(err) {
+			break
+		}
+		if waitErr := sleepContext(ctx, c.retry.delay(attempts, err)); waitErr != nil {
+			err = waitErr
+			break
+		}
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		apiErr.Attempts = attempts
+	}
+	if c.callObserver != nil {
+		c.callObserver(CallStats{
+			Method:   method,
+			Path:     endpoint.Path,
+			Attempts: attempts,
+			Elapsed:  time.Since(start),
+			Err:      err,
+		})
+	}
+	return err
+}
+
+// doRequest sends a single attempt of a JSON request.
+func (c *Client) doRequest(ctx context.Context, client *http.Client, method string, endpoint *url.URL, payload []byte, hasBody bool, target interface{}, headers map[string]string) error {
+	var reader io.Reader
+	if hasBody {
 		reader = bytes.NewReader(payload)
 	}
 
@@ -221,8 +711,13 @@ func (c *Client) doJSONWithHeadersAndClient(ctx context.Context, client *http.Cl
 		return fmt.Errorf("create request: %w", err)
 	}
 
+	// Extra and per-request headers may ask for another representation.
+	req.Header.Set("Accept", "application/json")
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-	if body != nil {
+	if hasBody {
 		req.Header.Set("Content-Type", "application/json")
 	}
 	for key, value := range headers {
@@ -231,6 +726,11 @@ func (c *Client) doJSONWithHeadersAndClient(ctx context.Context, client *http.Cl
 		}
 		req.Header.Set(key, value)
 	}
+	if c.signer != nil {
+		if err := c.signer(req, payload); err != nil {
+			return fmt.Errorf("sign request: %w", err)
+		}
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -238,20 +738,87 @@ func (c *Client) doJSONWithHeadersAndClient(ctx context.Context, client *http.Cl
 	}
 	defer resp.Body.Close()
 
+	if version := resp.Header.Get(apiVersionHeader); version != "" {
+		c.observeVersion(version)
+	}
+
+	respBody, err := decodedBody(resp)
+	if err != nil {
+		return err
+	}
+	defer respBody.Close()
+
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return decodeAPIError(resp.Body, resp.StatusCode)
+		return decodeAPIError(respBody, resp.StatusCode, resp.Header, req.Header.Get(RequestIDHeader))
+	}
+	body := limitResponseBody(respBody, c.maxResponseSize)
+
+	if raw, ok := target.(*RawResponse); ok {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return fmt.Errorf("read response: %w", err)
+		}
+		*raw = RawResponse{StatusCode: resp.StatusCode, Body: data}
+		return nil
+	}
+
+	// 204s and empty bodies leave target at its zero value.
+	if target == nil || resp.StatusCode == http.StatusNoContent || resp.ContentLength == 0 {
+		return nil
 	}
 
-	if target == nil {
+	if stream, ok := target.(streamDecoder); ok {
+		if err := stream.decodeStream(json.NewDecoder(body)); err != nil {
+			var se *streamError
+			if errors.As(err, &se) {
+				return se
+			}
+			if ctErr := contentTypeError(req, resp); ctErr != nil {
+				return &streamError{err: ctErr}
+			}
+			return &streamError{err: fmt.Errorf("decode response: %w", err)}
+		}
 		return nil
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+	if err := json.NewDecoder(body).Decode(target); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if ctErr := contentTypeError(req, resp); ctErr != nil {
+			return ctErr
+		}
 		return fmt.Errorf("decode response: %w", err)
 	}
 	return nil
 }
 
+// contentTypeError explains a decode failure caused by a declared non-JSON
+// body. It's nil when the response claims to be JSON or names no type.
+func contentTypeError(req *http.Request, resp *http.Response) error {
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" || isJSON(resp.Header) {
+		return nil
+	}
+	return fmt.Errorf("%w %q from %s (status %d): expected JSON; check the base URL and any proxy in between", ErrUnexpectedContentType, contentType, req.URL.Path, resp.StatusCode)
+}
+
+// decodedBody returns the response body, decompressing it when the server
+// sent gzip that the transport did not already decode. The default transport
+// handles this transparently; custom transports (or DisableCompression) may not.
+func decodedBody(resp *http.Response) (io.ReadCloser, error) {
+	if resp.Uncompressed || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return io.NopCloser(resp.Body), nil
+	}
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("decompress response: %w", err)
+	}
+	return reader, nil
+}
+
+// waitClient returns a copy of the HTTP client with a timeout long enough for
+// a long-poll; the shared client is never modified.
 func (c *Client) waitClient(timeoutSeconds int) *http.Client {
 	custom := *c.httpClient
 	if timeoutSeconds > 0 {
@@ -262,22 +829,12 @@ func (c *Client) waitClient(timeoutSeconds int) *http.Client {
 			custom.Timeout = defaultWaitTimeout
 		}
 	}
-	return &custom
-}
-
-func decodeAPIError(body io.Reader, status int) error {
-	payload, err := io.ReadAll(io.LimitReader(body, 32<<10))
-	if err != nil {
-		return fmt.Errorf("api error status %d", status)
-	}
-	var apiErr ErrorResponse
-	if jsonErr := json.Unmarshal(payload, &apiErr); jsonErr == nil && apiErr.Error != "" {
-		if len(apiErr.Details) > 0 {
-			if detailPayload, err := json.Marshal(apiErr.Details); err == nil {
-				return fmt.Errorf("api error status %d: %s: %s", status, apiErr.Error, strings.TrimSpace(string(detailPayload)))
-			}
+	if c.waitIdleTimeout > 0 {
+		base := custom.Transport
+		if base == nil {
+			base = http.DefaultTransport
 		}
-		return fmt.Errorf("api error status %d: %s", status, apiErr.Error)
+		custom.Transport = &headerTimeoutTransport{base: base, timeout: time.Duration(timeoutSeconds)*time.Second + waitIdleGrace}
 	}
-	return fmt.Errorf("api error status %d: %s", status, strings.TrimSpace(string(payload)))
+	return &custom
 }
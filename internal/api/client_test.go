@@ -1,15 +1,27 @@
 package api
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -159,18 +171,62 @@ func TestCreateUploadWithIdempotencyKey(t *testing.T) {
 	}
 }
 
+func TestWithoutIdempotencyKeyOmitsHeader(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		headers []http.Header
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = append(headers, r.Header.Clone())
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(BuildUploadResponse{BuildID: BuildID{value: 7}})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		opts    []ClientOption
+		wantKey bool
+	}{
+		{name: "default", wantKey: true},
+		{name: "without idempotency key", opts: []ClientOption{WithoutIdempotencyKey()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			headers = nil
+			mu.Unlock()
+
+			client, err := NewClient(server.URL, "test-key", server.Client(), tt.opts...)
+			if err != nil {
+				t.Fatalf("new client: %v", err)
+			}
+			if _, err := client.CreateUploadWithOptions(context.Background(), "app_123", BuildUploadParams{}, WithIdempotencyKey("idem-123")); err != nil {
+				t.Fatalf("create upload: %v", err)
+			}
+			if _, err := client.CreateUpload(context.Background(), "app_123", BuildUploadParams{}); err != nil {
+				t.Fatalf("create upload: %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for i, header := range headers {
+				_, present := header[http.CanonicalHeaderKey("Idempotency-Key")]
+				if present != tt.wantKey {
+					t.Fatalf("request %d: expected Idempotency-Key present=%v, got %v", i, tt.wantKey, header)
+				}
+			}
+		})
+	}
+}
+
 func TestUploadFile(t *testing.T) {
 	var receivedContentType string
 	var receivedSize int64
 
-	tmpDir := t.TempDir()
-	filePath := filepath.Join(tmpDir, "build.zip")
-	content := []byte("payload")
-	if err := os.WriteFile(filePath, content, 0644); err != nil {
-		t.Fatalf("write file: %v", err)
-	}
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	client, server, filePath := newUploadTestClient(t, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPut {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
@@ -179,6 +235,62 @@ func TestUploadFile(t *testing.T) {
 		data, _ := io.ReadAll(r.Body)
 		receivedSize = int64(len(data))
 		w.WriteHeader(http.StatusOK)
+	})
+
+	if err := client.UploadFile(context.Background(), server.URL, filePath, "application/zip"); err != nil {
+		t.Fatalf("upload file: %v", err)
+	}
+
+	if receivedContentType != "application/zip" {
+		t.Fatalf("expected content type application/zip, got %s", receivedContentType)
+	}
+	if receivedSize != int64(len("payload")) {
+		t.Fatalf("expected size %d, got %d", len("payload"), receivedSize)
+	}
+}
+
+// failingFile is an upload source whose reads fail with err past failAt,
+// and whose Stat can overstate the size.
+type failingFile struct {
+	*os.File
+	failAt int64
+	extra  int64
+	err    error
+}
+
+func (f *failingFile) ReadAt(p []byte, off int64) (int, error) {
+	if f.err != nil && off >= f.failAt {
+		return 0, f.err
+	}
+	if f.err != nil && int64(len(p)) > f.failAt-off {
+		p = p[:f.failAt-off]
+	}
+	return f.File.ReadAt(p, off)
+}
+
+func (f *failingFile) Stat() (os.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return sizedFileInfo{FileInfo: info, size: info.Size() + f.extra}, nil
+}
+
+type sizedFileInfo struct {
+	os.FileInfo
+	size int64
+}
+
+func (i sizedFileInfo) Size() int64 { return i.size }
+
+func TestUploadFileReportsLocalReadErrors(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "build.zip")
+	if err := os.WriteFile(filePath, bytes.Repeat([]byte("x"), 64<<10), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusOK)
 	}))
 	defer server.Close()
 
@@ -187,15 +299,330 @@ func TestUploadFile(t *testing.T) {
 		t.Fatalf("new client: %v", err)
 	}
 
-	if err := client.UploadFile(context.Background(), server.URL, filePath, "application/zip"); err != nil {
+	tests := []struct {
+		name       string
+		file       func(*os.File) *failingFile
+		wantOffset int64
+		wantMsg    string
+	}{
+		{
+			name:       "disk error",
+			file:       func(f *os.File) *failingFile { return &failingFile{File: f, failAt: 16 << 10, err: syscall.EIO} },
+			wantOffset: 16 << 10,
+			wantMsg:    "failed reading local file " + filePath + " at offset 16384: " + syscall.EIO.Error(),
+		},
+		{
+			name:       "truncated file",
+			file:       func(f *os.File) *failingFile { return &failingFile{File: f, extra: 100} },
+			wantOffset: 64 << 10,
+			wantMsg:    "failed reading local file " + filePath + " at offset 65536: file is 100 bytes shorter than when the upload started",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := openUploadFile
+			openUploadFile = func(name string) (uploadSource, error) {
+				f, err := os.Open(name)
+				if err != nil {
+					return nil, err
+				}
+				return tt.file(f), nil
+			}
+			t.Cleanup(func() { openUploadFile = original })
+
+			err := client.UploadFile(context.Background(), server.URL, filePath, "application/zip")
+			var readErr *FileReadError
+			if !errors.As(err, &readErr) {
+				t.Fatalf("expected a FileReadError, got %v", err)
+			}
+			if readErr.Offset != tt.wantOffset {
+				t.Fatalf("expected offset %d, got %d", tt.wantOffset, readErr.Offset)
+			}
+			if err.Error() != tt.wantMsg {
+				t.Fatalf("expected %q, got %q", tt.wantMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestTokenBucketPacesToRate(t *testing.T) {
+	clock := time.Unix(0, 0)
+	var slept time.Duration
+	bucket := newTokenBucket(1024)
+	bucket.now = func() time.Time { return clock }
+	bucket.last = clock
+	bucket.sleep = func(_ context.Context, d time.Duration) error {
+		slept += d
+		clock = clock.Add(d)
+		return nil
+	}
+
+	reader := &throttledReader{ctx: context.Background(), r: strings.NewReader(strings.Repeat("x", 4096)), bucket: bucket}
+	n, err := io.Copy(io.Discard, reader)
+	if err != nil || n != 4096 {
+		t.Fatalf("copy: %d, %v", n, err)
+	}
+	// The first second's worth is a free burst; the remaining 3 KB at 1 KB/s
+	// take three seconds.
+	if slept < 3*time.Second-time.Millisecond || slept > 3*time.Second+time.Millisecond {
+		t.Fatalf("expected ~3s of throttling, slept %v", slept)
+	}
+}
+
+func TestUploadFileMaxUploadRate(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "build.zip")
+	if err := os.WriteFile(filePath, make([]byte, 1500), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	var received int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received = int64(len(data))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("https://example.com", "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	start := time.Now()
+	if err := client.UploadFileWithOptions(context.Background(), server.URL, filePath, "application/zip", WithMaxUploadRate(1000)); err != nil {
 		t.Fatalf("upload file: %v", err)
 	}
+	// 1000 bytes go out as the initial burst; the last 500 need half a second.
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("expected the upload to be throttled, took %v", elapsed)
+	}
+	if received != 1500 {
+		t.Fatalf("expected 1500 bytes, got %d", received)
+	}
+}
 
-	if receivedContentType != "application/zip" {
-		t.Fatalf("expected content type application/zip, got %s", receivedContentType)
+func TestUploadFileMaxUploadRateHonorsCancellation(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "build.zip")
+	if err := os.WriteFile(filePath, make([]byte, 10000), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("https://example.com", "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = client.UploadFileWithOptions(ctx, server.URL, filePath, "application/zip", WithMaxUploadRate(100))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected cancellation to stop the upload promptly, took %v", elapsed)
+	}
+}
+
+func TestTraceLevels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req_1")
+		_, _ = io.WriteString(w, `{"build_id":7,"upload_url":"https://storage.example.com/7?sig=secret"}`)
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		level   TraceLevel
+		want    []string
+		notWant []string
+	}{
+		{
+			level:   TraceHeaders,
+			want:    []string{"> POST " + server.URL + "/api/v1/apps/app_123/uploads", "< 200 OK (", "< X-Request-Id: req_1"},
+			notWant: []string{"Authorization", `"build_id":7`},
+		},
+		{
+			level:   TraceBodies,
+			want:    []string{"> Authorization: [redacted]", `{"build":{"content_type":"application/zip"`, `"build_id":7`},
+			notWant: []string{"test-key"},
+		},
+	} {
+		var buf bytes.Buffer
+		client, err := NewClient(server.URL, "test-key", server.Client(), WithTrace(&buf, tt.level))
+		if err != nil {
+			t.Fatalf("new client: %v", err)
+		}
+		if _, err := client.CreateUpload(context.Background(), "app_123", BuildUploadParams{ContentType: "application/zip"}); err != nil {
+			t.Fatalf("create upload: %v", err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Fatalf("level %d: expected %q in trace:\n%s", tt.level, want, buf.String())
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(buf.String(), notWant) {
+				t.Fatalf("level %d: expected no %q in trace:\n%s", tt.level, notWant, buf.String())
+			}
+		}
+	}
+}
+
+func TestTraceRedactsSignedURLs(t *testing.T) {
+	var buf bytes.Buffer
+	client, server, filePath := newUploadTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}, WithTrace(&buf, TraceBodies))
+	if err := client.UploadFile(context.Background(), server.URL+"/storage/7?sig=secret", filePath, "application/zip"); err != nil {
+		t.Fatalf("upload file: %v", err)
+	}
+	if !strings.Contains(buf.String(), "> PUT "+server.URL+"/storage/7?[redacted]") || strings.Contains(buf.String(), "secret") {
+		t.Fatalf("expected a redacted upload URL, got:\n%s", buf.String())
+	}
+}
+
+func TestCurlPrinterMasksCredentials(t *testing.T) {
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storage.Close()
+	var buf bytes.Buffer
+	client, server, filePath := newUploadTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"build_id":7}`)
+	}, WithCurlPrinter(&buf))
+	if _, err := client.CreateUpload(context.Background(), "app_123", BuildUploadParams{ContentType: "application/zip", Version: "it's 1.0"}); err != nil {
+		t.Fatalf("create upload: %v", err)
+	}
+	if err := client.UploadFile(context.Background(), storage.URL+"/7?sig=secret", filePath, "application/zip"); err != nil {
+		t.Fatalf("upload file: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"curl -X POST '" + server.URL + "/api/v1/apps/app_123/uploads'",
+		`-H 'Authorization: Bearer [redacted]'`,
+		`-H 'Content-Type: application/json'`,
+		`--data-raw '{"build":{"content_type":"application/zip","version":"it'\''s 1.0"}}'`,
+		"curl -X PUT '" + storage.URL + "/7?[redacted]'",
+		"--data-binary @FILE",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %s in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "test-key") || strings.Contains(out, "secret") {
+		t.Fatalf("expected credentials to be masked, got:\n%s", out)
+	}
+}
+
+func TestWithKeepAliveConfiguresWaitTransport(t *testing.T) {
+	var newConns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(BuildResponse{Build: Build{ID: 42, Status: BuildStatusAvailable}})
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	base := &http.Client{Transport: &http.Transport{IdleConnTimeout: time.Second}}
+	client, err := NewClient(server.URL, "test-key", base, WithKeepAlive(15*time.Second))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	transport, ok := client.waitClient(300).Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected an *http.Transport, got %T", client.waitClient(300).Transport)
+	}
+	if transport.DialContext == nil || transport.IdleConnTimeout != keepAliveIdleTimeout {
+		t.Fatalf("expected keep-alive dialing and a long idle timeout, got idle timeout %v", transport.IdleConnTimeout)
+	}
+	if base.Transport.(*http.Transport).IdleConnTimeout != time.Second {
+		t.Fatal("expected the supplied transport to be left unchanged")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.WaitBuild(context.Background(), "app_123", "42", 300); err != nil {
+			t.Fatalf("wait build: %v", err)
+		}
+	}
+	if got := newConns.Load(); got != 1 {
+		t.Fatalf("expected both waits to share one connection, got %d connections", got)
+	}
+}
+
+func TestWithDialTimeoutFailsPromptly(t *testing.T) {
+	// The lookup never answers, so only the dial timeout can end it: no
+	// request timeout is set.
+	original := dialResolver
+	dialResolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	t.Cleanup(func() { dialResolver = original })
+
+	client, err := NewClient("http://api.hung-dns.test", "test-key", &http.Client{},
+		WithDialTimeout(200*time.Millisecond), WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	start := time.Now()
+	_, err = client.GetBuild(context.Background(), "app_123", "42")
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || opErr.Op != "dial" || !opErr.Timeout() {
+		t.Fatalf("expected a dial timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected failure within the dial timeout, took %s: %v", elapsed, err)
 	}
-	if receivedSize != int64(len(content)) {
-		t.Fatalf("expected size %d, got %d", len(content), receivedSize)
+}
+
+func TestUploadFileVerifiesStoredSize(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		stored  string
+		wantErr bool
+	}{
+		{name: "matching", stored: "7"},
+		{name: "not reported", stored: ""},
+		{name: "short", stored: "3", wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server, filePath := newUploadTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.Copy(io.Discard, r.Body)
+				if tt.stored != "" {
+					w.Header().Set("X-Goog-Stored-Content-Length", tt.stored)
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+			err := client.UploadFile(context.Background(), server.URL, filePath, "application/zip")
+			if tt.wantErr {
+				if !errors.Is(err, ErrUploadSizeMismatch) || !strings.Contains(err.Error(), "received 3 of 7 bytes") {
+					t.Fatalf("expected a size mismatch, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("upload file: %v", err)
+			}
+		})
 	}
 }
 
@@ -210,6 +637,46 @@ func TestAPITimeUnmarshal(t *testing.T) {
 	}
 }
 
+func TestBuildStatusHelpers(t *testing.T) {
+	tests := []struct {
+		status                                  BuildStatus
+		available, failed, terminal, processing bool
+	}{
+		{status: BuildStatusQueued, processing: true},
+		{status: BuildStatusProcessing, processing: true},
+		{status: BuildStatusNotarizing, processing: true},
+		{status: BuildStatusAvailable, available: true, terminal: true},
+		{status: BuildStatusFailed, failed: true, terminal: true},
+		{status: "archived"},
+	}
+	for _, tt := range tests {
+		build := Build{Status: tt.status}
+		if got := build.IsAvailable(); got != tt.available {
+			t.Errorf("%s: IsAvailable() = %v, want %v", tt.status, got, tt.available)
+		}
+		if got := build.IsFailed(); got != tt.failed {
+			t.Errorf("%s: IsFailed() = %v, want %v", tt.status, got, tt.failed)
+		}
+		if got := build.IsTerminal(); got != tt.terminal {
+			t.Errorf("%s: IsTerminal() = %v, want %v", tt.status, got, tt.terminal)
+		}
+		if got := build.IsProcessing(); got != tt.processing {
+			t.Errorf("%s: IsProcessing() = %v, want %v", tt.status, got, tt.processing)
+		}
+	}
+}
+
+func TestBuildResponseWaitsForPendingAppcast(t *testing.T) {
+	resp := BuildResponse{Build: Build{Status: BuildStatusAvailable}, Appcast: Appcast{Status: AppcastStatusNotarizing}}
+	if !resp.IsProcessing() {
+		t.Fatal("expected an available build with a notarizing appcast to be processing")
+	}
+	resp.Appcast.Status = "published"
+	if resp.IsProcessing() {
+		t.Fatal("expected a published appcast to end processing")
+	}
+}
+
 func TestBuildIDUnmarshalString(t *testing.T) {
 	var id BuildID
 	if err := json.Unmarshal([]byte(`"123"`), &id); err != nil {
@@ -289,6 +756,11 @@ func TestAPIErrorIncludesDetails(t *testing.T) {
 	if !strings.Contains(msg, "invalid_request") || !strings.Contains(msg, "version") || !strings.Contains(msg, "is required") {
 		t.Fatalf("expected error to include details, got %q", msg)
 	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Summary() != "api error status 422: invalid_request" {
+		t.Fatalf("expected summary without details, got %v", err)
+	}
 }
 
 func TestWaitBuildByURLAccepts202(t *testing.T) {
@@ -378,6 +850,60 @@ func TestWaitBuildUsesDefaultLongPollTimeout(t *testing.T) {
 	}
 }
 
+func TestBuildByURLRejectsForeignHosts(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(BuildResponse{Build: Build{ID: 1, Status: BuildStatusAvailable}})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	for _, foreign := range []string{"https://attacker.example/status", "//attacker.example/status", "file:///etc/passwd"} {
+		if _, err := client.GetBuildByURL(context.Background(), foreign); !errors.Is(err, ErrForeignURL) {
+			t.Fatalf("GetBuildByURL(%q): expected ErrForeignURL, got %v", foreign, err)
+		}
+		if _, err := client.WaitBuildByURL(context.Background(), foreign, 0); !errors.Is(err, ErrForeignURL) {
+			t.Fatalf("WaitBuildByURL(%q): expected ErrForeignURL, got %v", foreign, err)
+		}
+	}
+	if requests != 0 {
+		t.Fatalf("expected no requests for foreign URLs, got %d", requests)
+	}
+
+	if _, err := client.GetBuildByURL(context.Background(), server.URL+"/status"); err != nil {
+		t.Fatalf("expected same-host URL to be accepted, got %v", err)
+	}
+	if _, err := client.WaitBuildByURL(context.Background(), server.URL+"/wait", 0); err != nil {
+		t.Fatalf("expected same-host URL to be accepted, got %v", err)
+	}
+}
+
+func TestWithAllowedHostsAcceptsListedHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(BuildResponse{Build: Build{ID: 1, Status: BuildStatusAvailable}})
+	}))
+	defer server.Close()
+
+	// The API lives elsewhere; status URLs point at the test server.
+	client, err := NewClient("https://api.example.com", "test-key", server.Client(), WithAllowedHosts("127.0.0.1"))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if _, err := client.GetBuildByURL(context.Background(), server.URL+"/status"); err != nil {
+		t.Fatalf("expected allowed host to be accepted, got %v", err)
+	}
+	if _, err := client.GetBuildByURL(context.Background(), "https://other.example.com/status"); !errors.Is(err, ErrForeignURL) {
+		t.Fatalf("expected unlisted host to be rejected, got %v", err)
+	}
+}
+
 func strPtr(value string) *string {
 	return &value
 }
@@ -390,3 +916,1287 @@ func isUUIDFormat(value string) bool {
 	_, err := uuid.Parse(value)
 	return err == nil
 }
+
+func TestGetBuildRawReturnsServerBytes(t *testing.T) {
+	payload := `{"build": {"id": 42, "status": "available", "new_field": [1, 2]},  "appcast": {}}` + "\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/apps/app_123/builds/42" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": "build not found"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	raw, err := client.GetBuildRaw(context.Background(), "app_123", "42")
+	if err != nil {
+		t.Fatalf("get build raw: %v", err)
+	}
+	if raw.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", raw.StatusCode)
+	}
+	if string(raw.Body) != payload {
+		t.Fatalf("expected server bytes %q, got %q", payload, raw.Body)
+	}
+
+	_, err = client.GetBuildRaw(context.Background(), "app_123", "7")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 APIError, got %v", err)
+	}
+}
+
+func TestGetBuildDecodesGzipResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Always compress, as a gateway advertising gzip would, regardless of
+		// what the client asked for.
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		_ = json.NewEncoder(gz).Encode(BuildResponse{
+			Build: Build{ID: 42, Status: "available"},
+		})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		transport *http.Transport
+	}{
+		{name: "default transport", transport: &http.Transport{}},
+		{name: "compression disabled", transport: &http.Transport{DisableCompression: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(server.URL, "test-key", &http.Client{Transport: tt.transport})
+			if err != nil {
+				t.Fatalf("new client: %v", err)
+			}
+
+			resp, err := client.GetBuild(context.Background(), "app_123", "42")
+			if err != nil {
+				t.Fatalf("get build: %v", err)
+			}
+			if resp.Build.ID != 42 || resp.Build.Status != "available" {
+				t.Fatalf("unexpected build: %+v", resp.Build)
+			}
+		})
+	}
+}
+
+func TestGetBuildRejectsNonJSONResponse(t *testing.T) {
+	var gotAccept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAccept = r.Header.Get("Accept")
+		// A captive portal or misconfigured proxy answering in place of the API.
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = io.WriteString(w, "<html><body>Sign in to continue</body></html>")
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	_, err = client.GetBuild(context.Background(), "app_123", "42")
+	if !errors.Is(err, ErrUnexpectedContentType) {
+		t.Fatalf("expected ErrUnexpectedContentType, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `"text/html; charset=utf-8"`) {
+		t.Fatalf("expected the content type in the error, got %v", err)
+	}
+	if gotAccept != "application/json" {
+		t.Fatalf("expected Accept: application/json, got %q", gotAccept)
+	}
+}
+
+func TestMaxResponseSizeRejectsOversizeBodies(t *testing.T) {
+	// A valid response padded with a huge string field.
+	padding := strings.Repeat("x", 4<<10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"build": {"id": 42, "status": "available", "version": %q}}`, padding)
+	}))
+	defer server.Close()
+
+	small, err := NewClient(server.URL, "test-key", server.Client(), WithMaxResponseSize(1<<10))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if _, err := small.GetBuild(context.Background(), "app_123", "42"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+	}
+	if _, err := small.GetBuildRaw(context.Background(), "app_123", "42"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("expected ErrResponseTooLarge for raw responses, got %v", err)
+	}
+
+	roomy, err := NewClient(server.URL, "test-key", server.Client(), WithMaxResponseSize(8<<10))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	resp, err := roomy.GetBuild(context.Background(), "app_123", "42")
+	if err != nil {
+		t.Fatalf("expected a body under the limit to decode, got %v", err)
+	}
+	if resp.Build.Version == nil || *resp.Build.Version != padding {
+		t.Fatalf("expected the full body to be read, got %+v", resp.Build)
+	}
+}
+
+func TestRetryReportsAttempts(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(BuildResponse{Build: Build{ID: 42, Status: "available"}})
+	}))
+	defer server.Close()
+
+	var stats []CallStats
+	client, err := NewClient(server.URL, "test-key", server.Client(),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+		WithCallObserver(func(s CallStats) { stats = append(stats, s) }),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	if _, err := client.GetBuild(context.Background(), "app_123", "42"); err != nil {
+		t.Fatalf("get build: %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("expected 2 requests, got %d", requests)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 call report, got %d", len(stats))
+	}
+	if stats[0].Attempts != 2 || stats[0].Err != nil {
+		t.Fatalf("expected success after 2 attempts, got %+v", stats[0])
+	}
+	if stats[0].Path != "/api/v1/apps/app_123/builds/42" {
+		t.Fatalf("unexpected path %q", stats[0].Path)
+	}
+}
+
+func TestRetryExhaustedReturnsAPIError(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client(),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	_, err = client.GetBuild(context.Background(), "app_123", "42")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadGateway || apiErr.Attempts != 3 {
+		t.Fatalf("expected 502 after 3 attempts, got status %d after %d", apiErr.StatusCode, apiErr.Attempts)
+	}
+	if requests != 3 {
+		t.Fatalf("expected 3 requests, got %d", requests)
+	}
+}
+
+func TestDefaultRetryPolicySendsOnce(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	_, err = client.GetBuild(context.Background(), "app_123", "42")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Attempts != 1 || requests != 1 {
+		t.Fatalf("expected one attempt without a retry policy, got %d requests: %v", requests, err)
+	}
+}
+
+func TestCompleteUploadIsNotRetried(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client(),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	if _, err := client.CompleteUpload(context.Background(), "app_123", 1); err == nil {
+		t.Fatal("expected error")
+	}
+	if requests != 1 {
+		t.Fatalf("expected a single request for a non-idempotent POST, got %d", requests)
+	}
+}
+
+func TestCompleteUploadAlreadyComplete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/apps/app_123/uploads/7/complete":
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "upload_already_complete"})
+		case "/api/v1/apps/app_123/builds/7":
+			_ = json.NewEncoder(w).Encode(BuildResponse{Build: Build{ID: 7, Status: BuildStatusProcessing}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := client.CompleteUpload(context.Background(), "app_123", 7)
+	if err != nil {
+		t.Fatalf("complete upload: %v", err)
+	}
+	if resp.BuildID.Int() != 7 || resp.UploadState != "complete" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if resp.StatusURL != server.URL+"/api/v1/apps/app_123/builds/7" || resp.WaitURL != server.URL+"/api/v1/apps/app_123/builds/7/wait" {
+		t.Fatalf("unexpected build URLs %+v", resp)
+	}
+}
+
+func TestCompleteUploadOtherConflictFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "upload_not_received"})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	_, err = client.CompleteUpload(context.Background(), "app_123", 7)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Fatalf("expected a 409 API error, got %v", err)
+	}
+}
+
+func TestBuildsIteratorWalksPages(t *testing.T) {
+	var cursors []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/apps/app_123/builds" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if got := r.URL.Query().Get("limit"); got != "2" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		cursor := r.URL.Query().Get("cursor")
+		cursors = append(cursors, cursor)
+
+		var resp BuildListResponse
+		switch cursor {
+		case "":
+			resp.Builds = []Build{{ID: 3}, {ID: 2}}
+			resp.NextCursor = strPtr("page-2")
+		case "page-2":
+			resp.Builds = []Build{{ID: 1}}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	it := client.BuildsIterator("app_123", 2)
+	var ids []int
+	for {
+		build, ok, err := it.Next(context.Background())
+		if err != nil {
+			t.Fatalf("next: %v", err)
+		}
+		if !ok {
+			break
+		}
+		ids = append(ids, build.ID)
+	}
+
+	if len(ids) != 3 || ids[0] != 3 || ids[1] != 2 || ids[2] != 1 {
+		t.Fatalf("expected builds [3 2 1], got %v", ids)
+	}
+	if len(cursors) != 2 || cursors[0] != "" || cursors[1] != "page-2" {
+		t.Fatalf("expected two page fetches, got cursors %q", cursors)
+	}
+
+	// Exhausted iterators stay exhausted without refetching.
+	if _, ok, err := it.Next(context.Background()); ok || err != nil {
+		t.Fatalf("expected exhausted iterator, got ok=%v err=%v", ok, err)
+	}
+	if len(cursors) != 2 {
+		t.Fatalf("expected no extra fetch, got cursors %q", cursors)
+	}
+}
+
+func TestUnauthorizedWithClockSkewAddsHint(t *testing.T) {
+	serverTime := time.Now().Add(-2 * time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", serverTime.UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": "unauthorized"})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	_, err = client.GetBuild(context.Background(), "app_123", "1")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "your system clock appears to be off by 720") {
+		t.Fatalf("expected clock skew hint, got %q", err.Error())
+	}
+}
+
+func TestUnauthorizedWithoutClockSkewHasNoHint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	_, err = client.GetBuild(context.Background(), "app_123", "1")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if strings.Contains(err.Error(), "clock") {
+		t.Fatalf("expected no clock hint, got %q", err.Error())
+	}
+}
+
+func TestRequestSignerSignsJSONAndUploadRequests(t *testing.T) {
+	const secret = "s3cret"
+	fixed := time.Unix(1700000000, 0)
+
+	type signed struct {
+		path      string
+		timestamp string
+		signature string
+		body      []byte
+	}
+	var seen []signed
+	client, server, filePath := newUploadTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		seen = append(seen, signed{
+			path:      r.URL.Path,
+			timestamp: r.Header.Get("X-Twinkle-Timestamp"),
+			signature: r.Header.Get("X-Twinkle-Signature"),
+			body:      body,
+		})
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(BuildUploadResponse{BuildID: BuildID{value: 1}})
+	}, WithRequestSigner(newHMACSigner(secret, func() time.Time { return fixed })))
+
+	if _, err := client.CreateUpload(context.Background(), "app_123", BuildUploadParams{ContentType: "application/zip"}); err != nil {
+		t.Fatalf("create upload: %v", err)
+	}
+
+	if err := client.UploadFile(context.Background(), server.URL+"/upload", filePath, "application/zip"); err != nil {
+		t.Fatalf("upload file: %v", err)
+	}
+
+	if len(seen) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(seen))
+	}
+
+	// JSON request: signature covers "<timestamp>.<body>".
+	if seen[0].timestamp != "1700000000" {
+		t.Fatalf("expected timestamp header, got %q", seen[0].timestamp)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("1700000000." + string(seen[0].body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if seen[0].signature != want {
+		t.Fatalf("signature mismatch: got %q, want %q", seen[0].signature, want)
+	}
+
+	// Streamed upload: signed over an empty body.
+	mac = hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("1700000000."))
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); seen[1].signature != want {
+		t.Fatalf("upload signature mismatch: got %q, want %q", seen[1].signature, want)
+	}
+}
+
+func TestEmptyResponsesLeaveTargetZero(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{name: "204", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}},
+		{name: "empty 200", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", "0")
+			w.WriteHeader(http.StatusOK)
+		}},
+		{name: "empty chunked 200", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			client, err := NewClient(server.URL, "test-key", server.Client())
+			if err != nil {
+				t.Fatalf("new client: %v", err)
+			}
+
+			resp, err := client.GetBuild(context.Background(), "app_123", "1")
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if resp.Build.ID != 0 {
+				t.Fatalf("expected zero response, got %+v", resp)
+			}
+		})
+	}
+}
+
+func TestServerVersionFetchesHealthOnce(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok","api_version":"1.4"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		version, err := client.ServerVersion(context.Background())
+		if err != nil {
+			t.Fatalf("server version: %v", err)
+		}
+		if version != "1.4" {
+			t.Fatalf("expected version 1.4, got %q", version)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected 1 health request, got %d", got)
+	}
+}
+
+func TestServerVersionPrefersResponseHeader(t *testing.T) {
+	var healthCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/health" {
+			atomic.AddInt32(&healthCalls, 1)
+		}
+		w.Header().Set("X-API-Version", "2.1")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"build":{"id":1,"status":"available"},"appcast":{"status":"published"}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if _, err := client.GetBuild(context.Background(), "app_123", "1"); err != nil {
+		t.Fatalf("get build: %v", err)
+	}
+	version, err := client.ServerVersion(context.Background())
+	if err != nil {
+		t.Fatalf("server version: %v", err)
+	}
+	if version != "2.1" {
+		t.Fatalf("expected version 2.1, got %q", version)
+	}
+	if got := atomic.LoadInt32(&healthCalls); got != 0 {
+		t.Fatalf("expected no health request, got %d", got)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// timeoutError is a net.Error that reports a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// flakyTransport fails the first failures round trips with err and then
+// delegates to next.
+type flakyTransport struct {
+	next     http.RoundTripper
+	err      error
+	failures int32
+	calls    int32
+}
+
+func (f *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if atomic.AddInt32(&f.calls, 1) <= f.failures {
+		return nil, f.err
+	}
+	return f.next.RoundTrip(req)
+}
+
+func TestRetryOnNetworkTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(BuildResponse{Build: Build{ID: 42, Status: "available"}})
+	}))
+	defer server.Close()
+
+	transport := &flakyTransport{next: server.Client().Transport, err: timeoutError{}, failures: 1}
+	client, err := NewClient(server.URL, "test-key", &http.Client{Transport: transport},
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := client.GetBuild(context.Background(), "app_123", "42")
+	if err != nil {
+		t.Fatalf("get build: %v", err)
+	}
+	if resp.Build.ID != 42 {
+		t.Fatalf("expected build 42, got %d", resp.Build.ID)
+	}
+	if got := atomic.LoadInt32(&transport.calls); got != 2 {
+		t.Fatalf("expected 2 round trips, got %d", got)
+	}
+}
+
+func TestNoRetryAfterContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	transport := &flakyTransport{err: timeoutError{}, failures: 3}
+	cancellingTransport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		cancel()
+		return transport.RoundTrip(req)
+	})
+
+	client, err := NewClient("https://example.com", "test-key", &http.Client{Transport: cancellingTransport},
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	if _, err := client.GetBuild(ctx, "app_123", "42"); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := atomic.LoadInt32(&transport.calls); got != 1 {
+		t.Fatalf("expected a single attempt after cancellation, got %d", got)
+	}
+}
+
+func TestIsRetryableErrorClassifiesNetworkErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "timeout", err: &url.Error{Op: "Get", URL: "https://example.com", Err: timeoutError{}}, want: true},
+		{name: "connection reset", err: &url.Error{Op: "Get", URL: "https://example.com", Err: syscall.ECONNRESET}, want: true},
+		{name: "temporary dns", err: &net.DNSError{Err: "server misbehaving", IsTemporary: true}, want: true},
+		{name: "unknown host", err: &net.DNSError{Err: "no such host", IsNotFound: true}, want: false},
+		{name: "cancelled", err: &url.Error{Op: "Get", URL: "https://example.com", Err: context.Canceled}, want: false},
+		{name: "bad request", err: &APIError{StatusCode: http.StatusBadRequest}, want: false},
+	}
+	for _, tt := range tests {
+		if got := isRetryableError(tt.err); got != tt.want {
+			t.Errorf("%s: isRetryableError = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUploadFileRedirects(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		otherHits int32
+		received  []string
+	)
+	client, server, filePath := newUploadTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/other" {
+			atomic.AddInt32(&otherHits, 1)
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			received = append(received, r.Method+" "+string(body))
+			mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Location", "/other")
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+
+	err := client.UploadFileWithOptions(context.Background(), server.URL+"/storage", filePath, "application/zip", WithFollowRedirects(false))
+	if !errors.Is(err, ErrUploadRedirected) {
+		t.Fatalf("expected ErrUploadRedirected, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "307") || !strings.Contains(err.Error(), "/other") {
+		t.Fatalf("expected status and Location in error, got %q", err.Error())
+	}
+	if got := atomic.LoadInt32(&otherHits); got != 0 {
+		t.Fatalf("expected the redirect target not to be contacted, got %d requests", got)
+	}
+
+	// Followed redirects replay the whole file to the new location.
+	err = client.UploadFile(context.Background(), server.URL+"/storage", filePath, "application/zip")
+	if err != nil {
+		t.Fatalf("expected redirects to be followed by default, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 || received[0] != "PUT payload" {
+		t.Fatalf("expected one PUT of the full file at the redirect target, got %q", received)
+	}
+}
+
+// TestClientConcurrentUse hammers one Client from many goroutines; run with
+// -race to catch shared-state regressions.
+func TestClientConcurrentUse(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		w.Header().Set("X-API-Version", "1.0")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost:
+			_ = json.NewEncoder(w).Encode(BuildUploadResponse{BuildID: BuildID{value: 7}, UploadURL: "https://storage.example.com/7"})
+		default:
+			_ = json.NewEncoder(w).Encode(BuildResponse{Build: Build{ID: 42, Status: "available"}})
+		}
+	}))
+	defer server.Close()
+
+	var calls int32
+	client, err := NewClient(server.URL, "test-key", server.Client(),
+		WithCallObserver(func(CallStats) { atomic.AddInt32(&calls, 1) }),
+		WithRequestSigner(NewHMACSigner("secret")),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	const workers = 16
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*3)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := context.Background()
+			if _, err := client.GetBuild(ctx, "app_123", "42"); err != nil {
+				errs <- err
+			}
+			if _, err := client.CreateUpload(ctx, "app_123", BuildUploadParams{ContentType: "application/zip"}); err != nil {
+				errs <- err
+			}
+			if _, err := client.ServerVersion(ctx); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent call failed: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&calls); got < workers*2 {
+		t.Fatalf("expected at least %d observed calls, got %d", workers*2, got)
+	}
+	if atomic.LoadInt32(&maxInFlight) < 2 {
+		t.Fatal("expected requests to run in parallel, but they were serialized")
+	}
+}
+
+func TestStreamBuildsEmitsIncrementally(t *testing.T) {
+	const total = 2000
+	firstSeen := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/apps/app_123/builds" || r.URL.Query().Get("limit") != "2000" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		flusher := w.(http.Flusher)
+
+		_, _ = io.WriteString(w, `{"builds":[`)
+		for i := 1; i <= total; i++ {
+			if i > 1 {
+				_, _ = io.WriteString(w, ",")
+			}
+			_, _ = fmt.Fprintf(w, `{"id":%d,"status":"available","metadata":{"build_version":"padding-padding-padding"}}`, i)
+			if i == total/2 {
+				flusher.Flush()
+				// Hold the rest back until the client has emitted a row, proving
+				// it doesn't wait for the whole body.
+				select {
+				case <-firstSeen:
+				case <-time.After(5 * time.Second):
+					return
+				}
+			}
+		}
+		_, _ = io.WriteString(w, `],"next_cursor":"page2","extra":{"ignored":true}}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	var ids []int
+	next, err := client.StreamBuilds(context.Background(), "app_123", ListBuildsParams{Limit: total}, func(build Build) error {
+		if len(ids) == 0 {
+			close(firstSeen)
+		}
+		ids = append(ids, build.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream builds: %v", err)
+	}
+	if len(ids) != total {
+		t.Fatalf("expected %d callbacks, got %d", total, len(ids))
+	}
+	for i, id := range ids {
+		if id != i+1 {
+			t.Fatalf("expected build %d at position %d, got %d", i+1, i, id)
+		}
+	}
+	if next == nil || *next != "page2" {
+		t.Fatalf("expected next cursor page2, got %v", next)
+	}
+}
+
+func TestStreamBuildsStopsOnCallbackError(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"builds":[{"id":1},{"id":2},{"id":3}],"next_cursor":null}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	stop := errors.New("stop")
+	seen := 0
+	_, err = client.StreamBuilds(context.Background(), "app_123", ListBuildsParams{}, func(Build) error {
+		seen++
+		if seen == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("expected callback error to be returned as is, got %v", err)
+	}
+	if seen != 2 || requests != 1 {
+		t.Fatalf("expected to stop after 2 builds without retrying, got %d builds and %d requests", seen, requests)
+	}
+}
+
+func TestWithHeadersAddsHeadersToAPIRequests(t *testing.T) {
+	var apiHeader, apiAuth, uploadHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			uploadHeader = r.Header.Get("X-Team")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		apiHeader = r.Header.Get("X-Team")
+		apiAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(BuildResponse{Build: Build{ID: 1}})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client(), WithHeaders(map[string]string{
+		"X-Team":        "release",
+		"Authorization": "Bearer other",
+	}))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if _, err := client.GetBuild(context.Background(), "app_123", "1"); err != nil {
+		t.Fatalf("get build: %v", err)
+	}
+	if apiHeader != "release" {
+		t.Fatalf("expected X-Team on the API request, got %q", apiHeader)
+	}
+	if apiAuth != "Bearer test-key" {
+		t.Fatalf("expected the client's Authorization to win, got %q", apiAuth)
+	}
+
+	filePath := filepath.Join(t.TempDir(), "build.zip")
+	if err := os.WriteFile(filePath, []byte("payload"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := client.UploadFile(context.Background(), server.URL+"/upload", filePath, "application/zip"); err != nil {
+		t.Fatalf("upload file: %v", err)
+	}
+	if uploadHeader != "" {
+		t.Fatalf("expected no extra headers on the storage upload, got %q", uploadHeader)
+	}
+}
+
+func TestBuildUploadResponseExpiresWithin(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *APITime { return &APITime{Time: now.Add(d)} }
+
+	tests := []struct {
+		name      string
+		expiresAt *APITime
+		margin    time.Duration
+		want      bool
+	}{
+		{name: "unknown", expiresAt: nil, margin: time.Minute, want: false},
+		{name: "far off", expiresAt: at(time.Hour), margin: 30 * time.Second, want: false},
+		{name: "inside margin", expiresAt: at(10 * time.Second), margin: 30 * time.Second, want: true},
+		{name: "expired", expiresAt: at(-time.Second), margin: 0, want: true},
+	}
+	for _, tt := range tests {
+		resp := BuildUploadResponse{ExpiresAt: tt.expiresAt}
+		if got := resp.ExpiresWithin(now, tt.margin); got != tt.want {
+			t.Errorf("%s: ExpiresWithin = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	var decoded BuildUploadResponse
+	if err := json.Unmarshal([]byte(`{"build_id":1,"upload_url":"u","expires_at":"2024-05-10T12:15:00Z"}`), &decoded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if decoded.ExpiresAt == nil || !decoded.ExpiresAt.Equal(now.Add(15*time.Minute)) {
+		t.Fatalf("expected expires_at to decode, got %v", decoded.ExpiresAt)
+	}
+}
+
+func TestUploadFileRetriesStorage503WithRetryAfter(t *testing.T) {
+	var bodies []string
+	client, server, filePath := newUploadTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	start := time.Now()
+	if err := client.UploadFile(context.Background(), server.URL+"/upload", filePath, "application/zip"); err != nil {
+		t.Fatalf("upload file: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("expected the upload to wait out Retry-After, took %s", elapsed)
+	}
+	if len(bodies) != 2 || bodies[1] != "payload" {
+		t.Fatalf("expected the whole file to be sent twice, got %q", bodies)
+	}
+}
+
+func TestUploadFileReportsRetries(t *testing.T) {
+	attempts := 0
+	client, server, filePath := newUploadTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	var retries []UploadRetry
+	observe := WithUploadRetryObserver(func(retry UploadRetry) { retries = append(retries, retry) })
+	if err := client.UploadFileWithOptions(context.Background(), server.URL+"/upload", filePath, "application/zip", observe); err != nil {
+		t.Fatalf("upload file: %v", err)
+	}
+	if len(retries) != 1 || retries[0].Attempt != 1 || retries[0].Delay != time.Second || retries[0].Err == nil {
+		t.Fatalf("expected one reported retry after 1s, got %+v", retries)
+	}
+}
+
+func TestUploadFileStorage503WithoutRetryAfterFails(t *testing.T) {
+	attempts := 0
+	client, server, filePath := newUploadTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	err := client.UploadFile(context.Background(), server.URL+"/upload", filePath, "application/zip")
+	if err == nil || !strings.Contains(err.Error(), "status 503") {
+		t.Fatalf("expected a 503 error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected a single attempt, got %d", attempts)
+	}
+}
+
+func TestUploadFileStorage503WaitHonorsContext(t *testing.T) {
+	client, server, filePath := newUploadTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := client.UploadFile(ctx, server.URL+"/upload", filePath, "application/zip")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to stop with the context, got %v", err)
+	}
+}
+
+func TestFindBuildByMetadata(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		next := "page2"
+		switch {
+		case r.URL.Query().Get("metadata[commit]") == "missing":
+			_ = json.NewEncoder(w).Encode(BuildListResponse{})
+		case r.URL.Query().Get("cursor") == "":
+			_ = json.NewEncoder(w).Encode(BuildListResponse{
+				Builds: []Build{
+					{ID: 9, Status: BuildStatusFailed},
+					{ID: 8, Status: BuildStatusAvailable, CustomMetadata: map[string]string{"commit": "other"}},
+					{ID: 6, Status: BuildStatusAvailable},
+				},
+				NextCursor: &next,
+			})
+		default:
+			_ = json.NewEncoder(w).Encode(BuildListResponse{Builds: []Build{{ID: 7, Status: BuildStatusProcessing, CustomMetadata: map[string]string{"commit": "abc123"}}}})
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	build, found, err := client.FindBuildByMetadata(context.Background(), "app_123", "commit", "abc123")
+	if err != nil {
+		t.Fatalf("find build: %v", err)
+	}
+	if !found || build.ID != 7 {
+		t.Fatalf("expected build 7 past the failed, mismatched and untagged builds, got %v %+v", found, build)
+	}
+	if len(queries) != 2 || !strings.Contains(queries[0], "metadata%5Bcommit%5D=abc123") || !strings.Contains(queries[1], "cursor=page2") {
+		t.Fatalf("unexpected queries %v", queries)
+	}
+
+	if _, found, err := client.FindBuildByMetadata(context.Background(), "app_123", "commit", "missing"); err != nil || found {
+		t.Fatalf("expected no build, got %v, %v", found, err)
+	}
+}
+
+func TestFindBuildByMetadataStopsAfterMaxPages(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		next := fmt.Sprintf("page%d", requests+1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(BuildListResponse{
+			Builds:     []Build{{ID: requests, Status: BuildStatusAvailable}},
+			NextCursor: &next,
+		})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if _, found, err := client.FindBuildByMetadata(context.Background(), "app_123", "commit", "abc123"); err != nil || found {
+		t.Fatalf("expected no build, got %v, %v", found, err)
+	}
+	if requests != findBuildMaxPages {
+		t.Fatalf("expected %d page requests, got %d", findBuildMaxPages, requests)
+	}
+}
+
+func TestAPIErrorCarriesRequestID(t *testing.T) {
+	echo := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if echo {
+			w.Header().Set(RequestIDHeader, "server-side")
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", server.Client(), WithHeaders(map[string]string{RequestIDHeader: "client-side"}))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	for _, tt := range []struct {
+		echo bool
+		want string
+	}{{echo: false, want: "client-side"}, {echo: true, want: "server-side"}} {
+		echo = tt.echo
+		_, err := client.GetBuild(context.Background(), "app_123", "1")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.RequestID != tt.want {
+			t.Fatalf("echo=%v: expected request ID %q, got %v", tt.echo, tt.want, err)
+		}
+	}
+}
+
+func TestWaitBuildDetectsStalledConnection(t *testing.T) {
+	original := waitIdleGrace
+	waitIdleGrace = 0
+	t.Cleanup(func() { waitIdleGrace = original })
+
+	release := make(chan struct{})
+	timeouts := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeouts <- r.URL.Query().Get("timeout")
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := NewClient(server.URL, "test-key", server.Client(), WithWaitIdleTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	start := time.Now()
+	_, err = client.WaitBuild(context.Background(), "app_123", "42", 300)
+	if !errors.Is(err, ErrWaitStalled) {
+		t.Fatalf("expected ErrWaitStalled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the stall to be detected within the idle window, took %s", elapsed)
+	}
+	if got := <-timeouts; got != "1" {
+		t.Fatalf("expected the long-poll window capped at the idle timeout, got timeout=%q", got)
+	}
+}
+
+// countingTransport records the paths of the requests it carries.
+type countingTransport struct {
+	mu    sync.Mutex
+	base  http.RoundTripper
+	paths []string
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.paths = append(t.paths, req.Method+" "+req.URL.Path)
+	t.mu.Unlock()
+	return t.base.RoundTrip(req)
+}
+
+func TestSuppliedTransportCarriesAllRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(BuildResponse{Build: Build{ID: 42, Status: BuildStatusAvailable}})
+	}))
+	defer server.Close()
+
+	transport := &countingTransport{base: http.DefaultTransport}
+	client, err := NewClient(server.URL, "test-key", &http.Client{Transport: transport},
+		WithTrace(io.Discard, TraceHeaders), WithWaitIdleTimeout(time.Minute))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if client.BaseTransport() != transport {
+		t.Fatalf("expected BaseTransport to return the supplied transport, got %T", client.BaseTransport())
+	}
+
+	filePath := filepath.Join(t.TempDir(), "build.zip")
+	if err := os.WriteFile(filePath, []byte("payload"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := client.GetBuild(ctx, "app_123", "42"); err != nil {
+		t.Fatalf("get build: %v", err)
+	}
+	if _, err := client.WaitBuild(ctx, "app_123", "42", 30); err != nil {
+		t.Fatalf("wait build: %v", err)
+	}
+	if err := client.UploadFileWithOptions(ctx, server.URL+"/storage/7", filePath, "application/zip", WithFollowRedirects(false)); err != nil {
+		t.Fatalf("upload file: %v", err)
+	}
+
+	want := []string{
+		"GET /api/v1/apps/app_123/builds/42",
+		"GET /api/v1/apps/app_123/builds/42/wait",
+		"PUT /storage/7",
+	}
+	if strings.Join(transport.paths, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected the supplied transport to carry %v, got %v", want, transport.paths)
+	}
+}
+
+func TestBaseTransportDefaultsToDefaultTransport(t *testing.T) {
+	client, err := NewClient("https://example.com", "test-key", nil)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if client.BaseTransport() != http.DefaultTransport {
+		t.Fatalf("expected http.DefaultTransport, got %T", client.BaseTransport())
+	}
+}
+
+func TestWithBaseURLRebindsAndSharesTransport(t *testing.T) {
+	newServer := func(name string) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(AppResponse{App: App{ID: name}})
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	first := newServer("first")
+	second := newServer("second")
+
+	transport := &countingTransport{base: http.DefaultTransport}
+	client, err := NewClient(first.URL, "test-key", &http.Client{Transport: transport}, WithHeaders(map[string]string{"X-Team": "ios"}))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	rebound, err := client.WithBaseURL(second.URL)
+	if err != nil {
+		t.Fatalf("with base url: %v", err)
+	}
+
+	ctx := context.Background()
+	if app, err := rebound.GetApp(ctx, "app_123"); err != nil || app.ID != "second" {
+		t.Fatalf("expected the rebound client to hit the new host, got %q, %v", app.ID, err)
+	}
+	if app, err := client.GetApp(ctx, "app_123"); err != nil || app.ID != "first" {
+		t.Fatalf("expected the original client to keep its host, got %q, %v", app.ID, err)
+	}
+	if len(transport.paths) != 2 {
+		t.Fatalf("expected both requests to go through the shared transport, got %v", transport.paths)
+	}
+	if rebound.BaseTransport() != client.BaseTransport() || rebound.headers["X-Team"] != "ios" {
+		t.Fatal("expected the rebound client to keep the transport and options")
+	}
+
+	if _, err := client.WithBaseURL("://bad"); err == nil {
+		t.Fatal("expected an invalid base URL to fail")
+	}
+}
+
+// newUploadTestClient writes a build.zip holding "payload", serves handler
+// and returns a client for the server along with the file's path. opts go to
+// NewClient.
+func newUploadTestClient(t *testing.T, handler http.HandlerFunc, opts ...ClientOption) (*Client, *httptest.Server, string) {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), "build.zip")
+	if err := os.WriteFile(filePath, []byte("payload"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := NewClient(server.URL, "test-key", server.Client(), opts...)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	return client, server, filePath
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// WithCurlPrinter writes an equivalent curl command to w for every HTTP
+// request the client sends, before sending it. Credentials are masked, and
+// the query string of requests to other hosts (pre-signed storage URLs) is
+// redacted. Upload bodies are shown as a @FILE placeholder.
+func WithCurlPrinter(w io.Writer) ClientOption {
+	return func(c *Client) {
+		c.curl = &curlPrinter{w: w}
+	}
+}
+
+type curlPrinter struct {
+	mu      sync.Mutex // serializes output from concurrent requests
+	w       io.Writer
+	apiHost string
+}
+
+// wrap returns a copy of client whose transport prints through p.
+func (p *curlPrinter) wrap(client *http.Client) *http.Client {
+	printing := *client
+	base := printing.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	printing.Transport = &curlTransport{base: base, printer: p}
+	return &printing
+}
+
+type curlTransport struct {
+	base    http.RoundTripper
+	printer *curlPrinter
+}
+
+func (t *curlTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.printer.write(t.printer.command(req))
+	return t.base.RoundTrip(req)
+}
+
+func (p *curlPrinter) write(command string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, _ = io.WriteString(p.w, command)
+}
+
+// command renders req as a multi-line curl invocation.
+func (p *curlPrinter) command(req *http.Request) string {
+	target := req.URL.String()
+	if req.URL.Host != p.apiHost {
+		target = redactURL(req)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("curl")
+	if req.Method != http.MethodGet {
+		fmt.Fprintf(&buf, " -X %s", req.Method)
+	}
+	fmt.Fprintf(&buf, " %s", shellQuote(target))
+
+	keys := make([]string, 0, len(req.Header))
+	for key := range req.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := strings.Join(req.Header[key], ", ")
+		switch http.CanonicalHeaderKey(key) {
+		case "Authorization":
+			value = maskAuthorization(value)
+		case signatureHeader:
+			value = "[redacted]"
+		}
+		fmt.Fprintf(&buf, " \\\n  -H %s", shellQuote(key+": "+value))
+	}
+
+	switch {
+	case req.Body == nil || req.Body == http.NoBody:
+	case isJSON(req.Header) && req.GetBody != nil:
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(body)
+			body.Close()
+			fmt.Fprintf(&buf, " \\\n  --data-raw %s", shellQuote(string(data)))
+		}
+	default:
+		buf.WriteString(" \\\n  --data-binary @FILE")
+	}
+	buf.WriteString("\n")
+	return buf.String()
+}
+
+// maskAuthorization keeps the scheme so the command shows which kind of
+// credential goes there.
+func maskAuthorization(value string) string {
+	if scheme, _, ok := strings.Cut(value, " "); ok {
+		return scheme + " [redacted]"
+	}
+	return "[redacted]"
+}
+
+// shellQuote single-quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is returned for non-2xx responses from the Twinkle API.
+type APIError struct {
+	StatusCode int
+	// Message is the server's error string, when the body was a JSON error.
+	Message string
+	Details map[string]interface{}
+	// Body holds the raw response body when it wasn't a JSON error.
+	Body string
+	// RetryAfter is the server-requested delay from the Retry-After header.
+	RetryAfter time.Duration
+	// Attempts is how many times the request was sent before giving up.
+	Attempts int
+	// Hint is an optional suggestion for resolving the error.
+	Hint string
+	// RequestID identifies the failed request in server logs: the ID the
+	// server echoed back, or else the one the client sent.
+	RequestID string
+	// HelpURL points at documentation for the error, when the server sent
+	// one as help_url.
+	HelpURL string
+}
+
+// RequestIDHeader carries the request ID used to correlate client and
+// server logs.
+const RequestIDHeader = "X-Request-Id"
+
+// clockSkewThreshold is how far the local clock may drift from the server's
+// Date header before auth failures get a clock hint.
+const clockSkewThreshold = 5 * time.Minute
+
+func (e *APIError) Error() string {
+	return e.message(true)
+}
+
+// Summary is Error without the details payload, for callers that render
+// Details themselves.
+func (e *APIError) Summary() string {
+	return e.message(false)
+}
+
+func (e *APIError) message(withDetails bool) string {
+	msg := fmt.Sprintf("api error status %d", e.StatusCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+		if withDetails && len(e.Details) > 0 {
+			if detailPayload, err := json.Marshal(e.Details); err == nil {
+				msg += ": " + strings.TrimSpace(string(detailPayload))
+			}
+		}
+	} else if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	if e.Hint != "" {
+		msg += " (" + e.Hint + ")"
+	}
+	return msg
+}
+
+func decodeAPIError(body io.Reader, status int, header http.Header, requestID string) error {
+	apiErr := &APIError{StatusCode: status, RetryAfter: parseRetryAfter(header), RequestID: requestID}
+	if echoed := header.Get(RequestIDHeader); echoed != "" {
+		apiErr.RequestID = echoed
+	}
+	if status == http.StatusUnauthorized {
+		apiErr.Hint = clockSkewHint(header, time.Now())
+	}
+	payload, err := io.ReadAll(io.LimitReader(body, 32<<10))
+	if err != nil {
+		return apiErr
+	}
+	var errResp ErrorResponse
+	if jsonErr := json.Unmarshal(payload, &errResp); jsonErr == nil && errResp.Error != "" {
+		apiErr.Message = errResp.Error
+		apiErr.Details = errResp.Details
+		apiErr.HelpURL = errResp.HelpURL
+		if url, ok := errResp.Details["help_url"].(string); ok && apiErr.HelpURL == "" {
+			apiErr.HelpURL = url
+		}
+		return apiErr
+	}
+	apiErr.Body = strings.TrimSpace(string(payload))
+	return apiErr
+}
+
+// clockSkewHint compares the server's Date header with now and describes the
+// drift when it's large enough to explain an auth failure.
+func clockSkewHint(header http.Header, now time.Time) string {
+	if header == nil {
+		return ""
+	}
+	serverTime, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return ""
+	}
+	skew := now.Sub(serverTime)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew < clockSkewThreshold {
+		return ""
+	}
+	return fmt.Sprintf("your system clock appears to be off by %ds", int(skew.Round(time.Second).Seconds()))
+}
+
+// parseRetryAfter reads a Retry-After header given either as delay seconds
+// or as an HTTP date.
+func parseRetryAfter(header http.Header) time.Duration {
+	if header == nil {
+		return 0
+	}
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(at); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// FileReadError is returned when reading the local file fails partway
+// through an upload, so it isn't mistaken for a network failure.
+type FileReadError struct {
+	Path string
+	// Offset is how many bytes were read before the failure.
+	Offset int64
+	Err    error
+}
+
+func (e *FileReadError) Error() string {
+	return fmt.Sprintf("failed reading local file %s at offset %d: %v", e.Path, e.Offset, e.Err)
+}
+
+func (e *FileReadError) Unwrap() error {
+	return e.Err
+}
+
+// uploadSource is the local file an upload streams from. Each attempt and
+// each followed redirect reads it from the start with ReadAt, so one handle
+// serves the whole upload.
+type uploadSource interface {
+	io.ReaderAt
+	io.Closer
+	Stat() (os.FileInfo, error)
+}
+
+// openUploadFile opens the file to upload. Tests replace it to simulate
+// failing disks.
+var openUploadFile = func(name string) (uploadSource, error) {
+	return os.Open(name)
+}
+
+// fileReader tracks how far the upload has read and keeps the first read
+// error. The transport only reports a failed body as a generic request
+// error; this remembers that the fault was local. A file that ends before
+// its stat size was truncated while uploading, which counts as a read error
+// too.
+type fileReader struct {
+	r      io.Reader
+	path   string
+	size   int64
+	offset int64
+	err    *FileReadError
+}
+
+func (f *fileReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	f.offset += int64(n)
+	switch {
+	case err == io.EOF && f.offset < f.size:
+		err = fmt.Errorf("file is %d bytes shorter than when the upload started", f.size-f.offset)
+	case err == nil || err == io.EOF:
+		return n, err
+	}
+	if f.err == nil {
+		f.err = &FileReadError{Path: f.path, Offset: f.offset, Err: err}
+	}
+	return n, f.err
+}
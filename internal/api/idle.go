@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrWaitStalled is returned by WaitBuild and WaitBuildByURL when the server
+// accepted the request but sent no response within the idle timeout.
+var ErrWaitStalled = errors.New("wait request stalled")
+
+// waitIdleGrace is how long past the long-poll window the server may take to
+// start its response before the request counts as stalled. Tests shorten it.
+var waitIdleGrace = 5 * time.Second
+
+// WithWaitIdleTimeout bounds how long a long-poll wait may go without a
+// response. Each wait asks the server to hold the request for at most d, and
+// fails with ErrWaitStalled when no response headers arrive within d plus a
+// short grace period, instead of blocking for the whole wait window. Zero,
+// the default, leaves waits unbounded apart from the client timeout.
+func WithWaitIdleTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.waitIdleTimeout = d
+	}
+}
+
+// waitWindow caps the long-poll window requested from the server at the idle
+// timeout, rounded up to whole seconds.
+func (c *Client) waitWindow(timeoutSeconds int) int {
+	if c.waitIdleTimeout <= 0 {
+		return timeoutSeconds
+	}
+	idle := int((c.waitIdleTimeout + time.Second - 1) / time.Second)
+	if timeoutSeconds <= 0 || timeoutSeconds > idle {
+		return idle
+	}
+	return timeoutSeconds
+}
+
+// headerTimeoutTransport fails requests whose response headers don't arrive
+// within timeout, like http.Transport's ResponseHeaderTimeout, but with an
+// error callers can match and for any underlying transport.
+type headerTimeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *headerTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(t.timeout, cancel)
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if !timer.Stop() && req.Context().Err() == nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		cancel()
+		return nil, fmt.Errorf("%w: no response after %s", ErrWaitStalled, t.timeout)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases a request's context once its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
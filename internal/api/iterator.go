@@ -0,0 +1,61 @@
+package api
+
+import "context"
+
+// BuildIterator walks an app's builds, fetching pages from ListBuilds on
+// demand so callers never hold more than one page in memory.
+type BuildIterator struct {
+	client   *Client
+	appID    string
+	pageSize int
+
+	page    []Build
+	cursor  string
+	fetched bool
+	done    bool
+}
+
+// BuildsIterator returns an iterator over every build of appID. A pageSize
+// of 0 uses the server default.
+func (c *Client) BuildsIterator(appID string, pageSize int) *BuildIterator {
+	return &BuildIterator{client: c, appID: appID, pageSize: pageSize}
+}
+
+// Next returns the next build. The bool is false once all builds have been
+// returned; it is also false when err is non-nil.
+func (it *BuildIterator) Next(ctx context.Context) (Build, bool, error) {
+	for len(it.page) == 0 {
+		if it.done {
+			return Build{}, false, nil
+		}
+		if err := it.fetch(ctx); err != nil {
+			return Build{}, false, err
+		}
+	}
+
+	build := it.page[0]
+	it.page = it.page[1:]
+	return build, true, nil
+}
+
+func (it *BuildIterator) fetch(ctx context.Context) error {
+	if it.fetched && it.cursor == "" {
+		it.done = true
+		return nil
+	}
+
+	resp, err := it.client.ListBuilds(ctx, it.appID, ListBuildsParams{Limit: it.pageSize, Cursor: it.cursor})
+	if err != nil {
+		return err
+	}
+	it.fetched = true
+	it.page = resp.Builds
+	it.cursor = ""
+	if resp.NextCursor != nil {
+		it.cursor = *resp.NextCursor
+	}
+	if it.cursor == "" && len(it.page) == 0 {
+		it.done = true
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// keepAliveIdleTimeout keeps pooled connections around for longer than the
+// longest long-poll window, so the poll after a wait reuses the connection
+// instead of paying for a new TLS handshake.
+const keepAliveIdleTimeout = 5*time.Minute + defaultWaitTimeout
+
+// defaultDialTimeout and defaultKeepAlive match the dialer of
+// http.DefaultTransport.
+const (
+	defaultDialTimeout = 30 * time.Second
+	defaultKeepAlive   = 30 * time.Second
+)
+
+// dialResolver looks up API hosts; nil uses the system resolver. Tests
+// replace it to simulate a lookup that never answers.
+var dialResolver *net.Resolver
+
+// WithKeepAlive sends TCP keep-alive probes every interval on API
+// connections, including the long-poll used by WaitBuild, so intermediaries
+// don't drop them while a wait is idle. It applies when the HTTP client's
+// transport is the default or an *http.Transport; other transports are left
+// as they are.
+func WithKeepAlive(interval time.Duration) ClientOption {
+	return func(c *Client) {
+		c.keepAlive = interval
+	}
+}
+
+// WithDialTimeout bounds how long opening a connection may take, name
+// resolution included, so a hung DNS lookup fails within timeout rather than
+// using up the whole request timeout. Like WithKeepAlive it applies when the
+// HTTP client's transport is the default or an *http.Transport.
+func WithDialTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.dialTimeout = timeout
+	}
+}
+
+// withDialer returns a copy of client whose transport dials with the given
+// keep-alive interval and dial timeout; zero keeps the default for either.
+// The supplied client is not modified.
+func withDialer(client *http.Client, keepAlive, dialTimeout time.Duration) *http.Client {
+	var transport *http.Transport
+	switch base := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = base.Clone()
+	default:
+		return client
+	}
+
+	dialer := &net.Dialer{Timeout: defaultDialTimeout, KeepAlive: defaultKeepAlive, Resolver: dialResolver}
+	if dialTimeout > 0 {
+		dialer.Timeout = dialTimeout
+	}
+	if keepAlive > 0 {
+		dialer.KeepAlive = keepAlive
+		if transport.IdleConnTimeout > 0 && transport.IdleConnTimeout < keepAliveIdleTimeout {
+			transport.IdleConnTimeout = keepAliveIdleTimeout
+		}
+	}
+	transport.DialContext = dialer.DialContext
+
+	configured := *client
+	configured.Transport = transport
+	return &configured
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxResponseSize caps successful API response bodies unless
+// WithMaxResponseSize says otherwise. It's far above any real payload; the
+// point is that a misbehaving server can't exhaust memory.
+const DefaultMaxResponseSize = 32 << 20
+
+// ErrResponseTooLarge is returned when a response body exceeds the client's
+// maximum response size.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// WithMaxResponseSize caps how many bytes of a successful API response are
+// read, after decompression. Zero or less keeps DefaultMaxResponseSize.
+// Error bodies have their own, smaller cap, and storage uploads aren't
+// affected.
+func WithMaxResponseSize(n int64) ClientOption {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxResponseSize = n
+		}
+	}
+}
+
+// sizeLimitedReader fails with ErrResponseTooLarge once more than limit
+// bytes have been read, instead of silently truncating like io.LimitReader.
+type sizeLimitedReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func limitResponseBody(r io.Reader, limit int64) io.Reader {
+	// Reading one byte past the limit is enough to know it was exceeded.
+	return &sizeLimitedReader{r: io.LimitReader(r, limit+1), limit: limit}
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		return 0, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, l.limit)
+	}
+	return n, err
+}
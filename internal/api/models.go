@@ -100,14 +100,79 @@ type Appcast struct {
 	URL         *string  `json:"url"`
 }
 
+// AppcastStatusFailed is the appcast state when generating or publishing
+// the feed failed; Message says why.
+const AppcastStatusFailed = "failed"
+
+// AppcastStatusNotarizing is the appcast state while Apple notarizes a
+// build that has otherwise finished processing.
+const AppcastStatusNotarizing = "notarizing"
+
+// IsPending reports whether the appcast is still waiting on the server, for
+// example for notarization.
+func (a Appcast) IsPending() bool {
+	return a.Status == AppcastStatusNotarizing
+}
+
+// IsFailed reports whether the appcast couldn't be generated or published.
+func (a Appcast) IsFailed() bool {
+	return a.Status == AppcastStatusFailed
+}
+
+// BuildStatus is the processing state of a build.
+type BuildStatus string
+
+const (
+	BuildStatusQueued     BuildStatus = "queued"
+	BuildStatusProcessing BuildStatus = "processing"
+	BuildStatusNotarizing BuildStatus = "notarizing"
+	BuildStatusAvailable  BuildStatus = "available"
+	BuildStatusFailed     BuildStatus = "failed"
+)
+
+// IsPending reports whether s means the build is still being worked on.
+// Anything else ends a wait.
+func (s BuildStatus) IsPending() bool {
+	switch s {
+	case BuildStatusQueued, BuildStatusProcessing, BuildStatusNotarizing:
+		return true
+	default:
+		return false
+	}
+}
+
 type Build struct {
 	BuildNumber *string        `json:"build_number"`
 	ID          int            `json:"id"`
 	InsertedAt  APITime        `json:"inserted_at"`
 	Metadata    *BuildMetadata `json:"metadata"`
-	Status      string         `json:"status"`
+	Status      BuildStatus    `json:"status"`
 	UpdatedAt   APITime        `json:"updated_at"`
 	Version     *string        `json:"version"`
+	// CustomMetadata holds the metadata attached at upload, when the server
+	// reports it.
+	CustomMetadata map[string]string `json:"custom_metadata,omitempty"`
+}
+
+// IsAvailable reports whether the build processed successfully.
+func (b Build) IsAvailable() bool {
+	return b.Status == BuildStatusAvailable
+}
+
+// IsFailed reports whether processing failed.
+func (b Build) IsFailed() bool {
+	return b.Status == BuildStatusFailed
+}
+
+// IsTerminal reports whether processing has finished, successfully or not.
+func (b Build) IsTerminal() bool {
+	return b.IsAvailable() || b.IsFailed()
+}
+
+// IsProcessing reports whether the server is still working on the build,
+// i.e. its status is queued, processing or notarizing.
+func (b Build) IsProcessing() bool {
+	return b.Status.IsPending()
 }
 
 type BuildMetadata struct {
@@ -118,6 +183,9 @@ type BuildMetadata struct {
 	MinimumSystemVersion *string                `json:"minimum_system_version"`
 	ProcessingErrors     map[string]interface{} `json:"processing_errors"`
 	Signature            *string                `json:"signature"`
+	// ReleaseNotes are the notes shipped with the build, as HTML or
+	// markdown.
+	ReleaseNotes *string `json:"release_notes,omitempty"`
 }
 
 type BuildResponse struct {
@@ -126,8 +194,50 @@ type BuildResponse struct {
 	PollAfterMs *int    `json:"poll_after_ms,omitempty"`
 }
 
+// IsProcessing reports whether the server is still working on the build or,
+// once it is available, on publishing its appcast.
+func (r BuildResponse) IsProcessing() bool {
+	return r.Build.IsProcessing() || (r.Build.IsAvailable() && r.Appcast.IsPending())
+}
+
+// RawResponse is a successful response left undecoded.
+type RawResponse struct {
+	StatusCode int
+	Body       []byte
+}
+
+type BuildListResponse struct {
+	Builds     []Build `json:"builds"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+type ListBuildsParams struct {
+	Limit  int
+	Cursor string
+	// Since and Until restrict results to builds created in that range;
+	// zero values leave the range open.
+	Since time.Time
+	Until time.Time
+	// Metadata restricts results to builds uploaded with these custom
+	// metadata values.
+	Metadata map[string]string
+	// AfterID restricts results to builds with a greater ID; zero doesn't
+	// filter.
+	AfterID int
+}
+
+type PromoteBuildRequest struct {
+	Channel string `json:"channel"`
+}
+
 type BuildUploadParams struct {
-	ContentType string `json:"content_type,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Version     string            `json:"version,omitempty"`
+	Channel     string            `json:"channel,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	// Size is the archive's size in bytes, so the server can reject an
+	// oversize upload before the file is sent.
+	Size int64 `json:"size,omitempty"`
 }
 
 type BuildUploadRequest struct {
@@ -142,6 +252,18 @@ type BuildUploadResponse struct {
 	UploadURL      string  `json:"upload_url"`
 	WaitURL        string  `json:"wait_url"`
 	IdempotencyKey *string `json:"idempotency_key"`
+	// ExpiresAt is when UploadURL stops accepting the file, if the server
+	// says.
+	ExpiresAt *APITime `json:"expires_at,omitempty"`
+}
+
+// ExpiresWithin reports whether UploadURL expires within d of now. It's
+// false when the expiry is unknown.
+func (r BuildUploadResponse) ExpiresWithin(now time.Time, d time.Duration) bool {
+	if r.ExpiresAt == nil || r.ExpiresAt.IsZero() {
+		return false
+	}
+	return !now.Add(d).Before(r.ExpiresAt.Time)
 }
 
 type BuildUploadCompleteResponse struct {
@@ -149,9 +271,41 @@ type BuildUploadCompleteResponse struct {
 	StatusURL   string  `json:"status_url"`
 	UploadState string  `json:"upload_state"`
 	WaitURL     string  `json:"wait_url"`
+	// Metadata describes the stored build, when the server reports it at
+	// completion.
+	Metadata *BuildMetadata `json:"metadata,omitempty"`
+}
+
+type App struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	BundleID string `json:"bundle_id"`
+	// Platform is the OS the app ships for, e.g. macos or ios.
+	Platform string `json:"platform,omitempty"`
+}
+
+// ListAppsParams filters ListApps. The zero value lists every app.
+type ListAppsParams struct {
+	// Platform restricts results to apps for this platform.
+	Platform string
+}
+
+type AppResponse struct {
+	App App `json:"app"`
+}
+
+type AppsResponse struct {
+	Apps []App `json:"apps"`
+}
+
+// HealthResponse is returned by the health endpoint.
+type HealthResponse struct {
+	Status     string `json:"status"`
+	APIVersion string `json:"api_version"`
 }
 
 type ErrorResponse struct {
 	Details map[string]interface{} `json:"details"`
 	Error   string                 `json:"error"`
+	HelpURL string                 `json:"help_url,omitempty"`
 }
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// maxRetryAfter caps how long a server-provided Retry-After can stall a retry.
+const maxRetryAfter = 30 * time.Second
+
+// RetryPolicy controls how failed requests are retried. Only idempotent
+// requests (GET, DELETE, or requests carrying an Idempotency-Key) are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries, including the first.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy sends each request once. Callers opt in to retries
+// with WithRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 1}
+
+// TransientRetryPolicy retries transient failures twice with exponential
+// backoff.
+var TransientRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// CallStats describes a completed API call, including any retries.
+type CallStats struct {
+	Method   string
+	Path     string
+	Attempts int
+	Elapsed  time.Duration
+	Err      error
+}
+
+// delay returns how long to wait before the given retry attempt (1-based
+// count of attempts already made).
+func (p RetryPolicy) delay(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		if apiErr.RetryAfter > maxRetryAfter {
+			return maxRetryAfter
+		}
+		return apiErr.RetryAfter
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+func isRetryableRequest(method string, headers map[string]string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return true
+	}
+	return headers["Idempotency-Key"] != ""
+}
+
+// isRetryableError reports whether err is worth another attempt: a
+// throttling or gateway status from the API, or a transient network failure.
+// Callers must check their own context first; a cancelled request surfaces
+// here as an ordinary transport error.
+func isRetryableError(err error) bool {
+	var se *streamError
+	if errors.As(err, &se) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return isTransientNetworkError(err)
+}
+
+// isTransientNetworkError matches failures that carry no HTTP status but
+// usually clear up on their own: timeouts, temporary DNS failures and
+// connections dropped by the other side.
+func isTransientNetworkError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
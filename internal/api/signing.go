@@ -0,0 +1,52 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	signatureHeader = "X-Twinkle-Signature"
+	timestampHeader = "X-Twinkle-Timestamp"
+)
+
+// RequestSigner adds authentication headers to an outgoing request. body is
+// the exact request payload, or nil for bodiless and streamed requests.
+// Signers may be called from several goroutines at once.
+type RequestSigner func(req *http.Request, body []byte) error
+
+// WithRequestSigner runs signer on every request just before it is sent,
+// including file uploads.
+func WithRequestSigner(signer RequestSigner) ClientOption {
+	return func(c *Client) {
+		c.signer = signer
+	}
+}
+
+// NewHMACSigner returns a signer that sets X-Twinkle-Timestamp to the current
+// Unix time and X-Twinkle-Signature to the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with secret.
+func NewHMACSigner(secret string) RequestSigner {
+	return newHMACSigner(secret, time.Now)
+}
+
+func newHMACSigner(secret string, now func() time.Time) RequestSigner {
+	return func(req *http.Request, body []byte) error {
+		timestamp := strconv.FormatInt(now().Unix(), 10)
+		req.Header.Set(timestampHeader, timestamp)
+		req.Header.Set(signatureHeader, "sha256="+computeHMAC(secret, timestamp, body))
+		return nil
+	}
+}
+
+func computeHMAC(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
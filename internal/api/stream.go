@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// streamDecoder is implemented by response targets that consume the body
+// incrementally instead of decoding it in one go.
+type streamDecoder interface {
+	decodeStream(dec *json.Decoder) error
+}
+
+// streamError marks a failure after a streamed response began delivering
+// items. Such requests are never retried, so callbacks don't see repeats.
+type streamError struct {
+	err error
+}
+
+func (e *streamError) Error() string { return e.err.Error() }
+func (e *streamError) Unwrap() error { return e.err }
+
+// StreamBuilds fetches one page of builds like ListBuilds but hands each
+// build to fn as soon as it is decoded, so large pages never sit in memory.
+// It returns the cursor for the next page, or nil on the last page. An error
+// from fn stops decoding and is returned as is.
+func (c *Client) StreamBuilds(ctx context.Context, appID string, params ListBuildsParams, fn func(Build) error) (*string, error) {
+	endpoint := c.withPath("/api/v1/apps/%s/builds", appID)
+	endpoint.RawQuery = params.query().Encode()
+
+	stream := &buildStream{fn: fn}
+	if err := c.doJSON(ctx, http.MethodGet, endpoint, nil, stream); err != nil {
+		var se *streamError
+		if errors.As(err, &se) {
+			return nil, se.err
+		}
+		return nil, err
+	}
+	return stream.nextCursor, nil
+}
+
+// buildStream decodes a BuildListResponse, emitting builds one at a time.
+type buildStream struct {
+	fn         func(Build) error
+	nextCursor *string
+}
+
+func (s *buildStream) decodeStream(dec *json.Decoder) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+		switch key {
+		case "builds":
+			if err := s.decodeBuilds(dec); err != nil {
+				return err
+			}
+		case "next_cursor":
+			if err := dec.Decode(&s.nextCursor); err != nil {
+				return err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+func (s *buildStream) decodeBuilds(dec *json.Decoder) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected builds array, got %v", token)
+	}
+	for dec.More() {
+		var build Build
+		if err := dec.Decode(&build); err != nil {
+			return err
+		}
+		if err := s.fn(build); err != nil {
+			return &streamError{err: err}
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %q, got %v", want, token)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// tokenBucket limits throughput to rate bytes per second, allowing bursts of
+// up to one second's worth. Tokens may go negative; the caller then sleeps
+// off the debt.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+func newTokenBucket(bytesPerSecond int64) *tokenBucket {
+	b := &tokenBucket{
+		rate:  float64(bytesPerSecond),
+		burst: float64(bytesPerSecond),
+		now:   time.Now,
+		sleep: sleepContext,
+	}
+	b.tokens = b.burst
+	b.last = b.now()
+	return b
+}
+
+// wait takes n tokens, sleeping until the bucket is no longer in debt. It
+// returns early with the context's error if ctx is done.
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return nil
+	}
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	return b.sleep(ctx, delay)
+}
+
+// throttledReader reads from r no faster than bucket allows.
+type throttledReader struct {
+	ctx    context.Context
+	r      io.Reader
+	bucket *tokenBucket
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	// Keep reads within one burst so pacing stays smooth.
+	if limit := int(t.bucket.burst); limit > 0 && len(p) > limit {
+		p = p[:limit]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if waitErr := t.bucket.wait(t.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceLevel selects how much of each HTTP exchange WithTrace logs.
+type TraceLevel int
+
+const (
+	// TraceHeaders logs the request line, status, timing and response
+	// headers.
+	TraceHeaders TraceLevel = iota + 1
+	// TraceBodies also logs request headers and JSON bodies.
+	TraceBodies
+)
+
+// maxTracedBody caps how much of a body is logged.
+const maxTracedBody = 64 << 10
+
+// WithTrace logs every HTTP exchange, including uploads and long-poll waits,
+// to w in a curl -v like format. Credentials and signed URL query strings are
+// redacted.
+func WithTrace(w io.Writer, level TraceLevel) ClientOption {
+	return func(c *Client) {
+		c.tracer = &tracer{w: w, level: level}
+	}
+}
+
+type tracer struct {
+	mu    sync.Mutex // serializes output from concurrent requests
+	w     io.Writer
+	level TraceLevel
+}
+
+// wrap returns a copy of client whose transport traces through t.
+func (t *tracer) wrap(client *http.Client) *http.Client {
+	traced := *client
+	base := traced.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	traced.Transport = &traceTransport{base: base, tracer: t}
+	return &traced
+}
+
+type traceTransport struct {
+	base   http.RoundTripper
+	tracer *tracer
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "> %s %s\n", req.Method, redactURL(req))
+	if t.tracer.level >= TraceBodies {
+		writeTraceHeaders(&buf, ">", req.Header)
+		if isJSON(req.Header) && req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				writeTraceBody(&buf, body)
+				body.Close()
+			}
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		fmt.Fprintf(&buf, "< error after %s: %v\n", elapsed, err)
+		t.tracer.write(buf.Bytes())
+		return resp, err
+	}
+
+	fmt.Fprintf(&buf, "< %s (%s)\n", resp.Status, elapsed)
+	writeTraceHeaders(&buf, "<", resp.Header)
+	if t.tracer.level >= TraceBodies && isJSON(resp.Header) && resp.Header.Get("Content-Encoding") == "" {
+		data, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		if readErr != nil {
+			return resp, readErr
+		}
+		writeTraceBody(&buf, bytes.NewReader(data))
+	}
+	t.tracer.write(buf.Bytes())
+	return resp, nil
+}
+
+func (t *tracer) write(data []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, _ = t.w.Write(data)
+}
+
+// redactURL drops the query string, which carries the signature on
+// pre-signed storage URLs.
+func redactURL(req *http.Request) string {
+	u := *req.URL
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	u.RawQuery = ""
+	return u.String() + "?[redacted]"
+}
+
+func writeTraceHeaders(w io.Writer, prefix string, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := strings.Join(header[key], ", ")
+		switch http.CanonicalHeaderKey(key) {
+		case "Authorization", signatureHeader:
+			value = "[redacted]"
+		}
+		fmt.Fprintf(w, "%s %s: %s\n", prefix, key, value)
+	}
+}
+
+func writeTraceBody(w io.Writer, body io.Reader) {
+	data, _ := io.ReadAll(io.LimitReader(body, maxTracedBody+1))
+	truncated := len(data) > maxTracedBody
+	if truncated {
+		data = data[:maxTracedBody]
+	}
+	text := strings.TrimRight(string(data), "\n")
+	if text == "" {
+		return
+	}
+	fmt.Fprintln(w, text)
+	if truncated {
+		fmt.Fprintln(w, "[body truncated]")
+	}
+}
+
+func isJSON(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"net/http"
+)
+
+// apiVersionHeader carries the server's API version on every response.
+const apiVersionHeader = "X-API-Version"
+
+// ServerVersion returns the API version the server reports, taken from the
+// X-API-Version header of any response so far or, failing that, from the
+// health endpoint. The health endpoint is fetched at most once per Client.
+// An empty version with a nil error means the server doesn't report one.
+func (c *Client) ServerVersion(ctx context.Context) (string, error) {
+	if version := c.observedVersion(); version != "" {
+		return version, nil
+	}
+
+	// healthMu only serializes callers waiting on the health check; other
+	// requests never take it.
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+	if version := c.observedVersion(); version != "" {
+		return version, nil
+	}
+	if c.healthChecked {
+		return "", c.healthErr
+	}
+
+	var health HealthResponse
+	if err := c.doJSON(ctx, http.MethodGet, c.withPath("/api/v1/health"), nil, &health); err != nil {
+		// A cancelled caller shouldn't poison the result for everyone else.
+		if ctx.Err() == nil {
+			c.healthChecked = true
+			c.healthErr = err
+		}
+		return "", err
+	}
+	c.healthChecked = true
+	if health.APIVersion != "" {
+		c.observeVersion(health.APIVersion)
+	}
+	return c.observedVersion(), nil
+}
+
+// WithVersionObserver registers a function that receives the server's API
+// version the first time a response reports it. It costs no extra request,
+// unlike ServerVersion, and is never called if the server doesn't report a
+// version.
+func WithVersionObserver(observer func(version string)) ClientOption {
+	return func(c *Client) {
+		c.versionObserver = observer
+	}
+}
+
+func (c *Client) observeVersion(version string) {
+	c.versionMu.Lock()
+	first := c.serverVersion == ""
+	if first {
+		c.serverVersion = version
+	}
+	c.versionMu.Unlock()
+	if first && c.versionObserver != nil {
+		c.versionObserver(version)
+	}
+}
+
+func (c *Client) observedVersion() string {
+	c.versionMu.Lock()
+	defer c.versionMu.Unlock()
+	return c.serverVersion
+}
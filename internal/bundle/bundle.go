@@ -0,0 +1,98 @@
+// Package bundle reads metadata from zipped macOS app bundles.
+package bundle
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrNoInfoPlist is returned when an archive has no app bundle Info.plist.
+var ErrNoInfoPlist = errors.New("no app bundle Info.plist found in archive")
+
+// ChannelKey is the Info.plist key teams use to name a build's release
+// channel.
+const ChannelKey = "TWChannel"
+
+// maxInfoPlistSize guards against reading a huge or malicious entry.
+const maxInfoPlistSize = 4 << 20
+
+// Info holds the Info.plist fields the CLI cares about.
+type Info struct {
+	BundleIdentifier string
+	ShortVersion     string
+	BundleVersion    string
+	// Channel is the ChannelKey value, empty when the key is absent.
+	Channel string
+}
+
+// ReadInfo extracts Info.plist values from the outermost .app bundle in the
+// zip archive at zipPath.
+func ReadInfo(zipPath string) (Info, error) {
+	archive, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return Info{}, fmt.Errorf("open archive: %w", err)
+	}
+	defer archive.Close()
+
+	entry := findInfoPlist(archive.File)
+	if entry == nil {
+		return Info{}, ErrNoInfoPlist
+	}
+
+	reader, err := entry.Open()
+	if err != nil {
+		return Info{}, fmt.Errorf("open %s: %w", entry.Name, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxInfoPlistSize))
+	if err != nil {
+		return Info{}, fmt.Errorf("read %s: %w", entry.Name, err)
+	}
+
+	values, err := parsePlistStrings(data)
+	if err != nil {
+		return Info{}, fmt.Errorf("parse %s: %w", entry.Name, err)
+	}
+	return Info{
+		BundleIdentifier: values["CFBundleIdentifier"],
+		ShortVersion:     values["CFBundleShortVersionString"],
+		BundleVersion:    values["CFBundleVersion"],
+		Channel:          values[ChannelKey],
+	}, nil
+}
+
+// BundleIdentifier returns the CFBundleIdentifier of the app in zipPath.
+func BundleIdentifier(zipPath string) (string, error) {
+	info, err := ReadInfo(zipPath)
+	if err != nil {
+		return "", err
+	}
+	if info.BundleIdentifier == "" {
+		return "", errors.New("Info.plist has no CFBundleIdentifier")
+	}
+	return info.BundleIdentifier, nil
+}
+
+// findInfoPlist picks the Info.plist of the outermost app bundle, skipping
+// nested bundles such as helpers and frameworks.
+func findInfoPlist(files []*zip.File) *zip.File {
+	var best *zip.File
+	for _, file := range files {
+		name := strings.TrimPrefix(file.Name, "./")
+		if path.Base(name) != "Info.plist" || path.Base(path.Dir(name)) != "Contents" {
+			continue
+		}
+		if !strings.HasSuffix(path.Dir(path.Dir(name)), ".app") || strings.HasPrefix(name, "__MACOSX/") {
+			continue
+		}
+		if best == nil || strings.Count(name, "/") < strings.Count(best.Name, "/") {
+			best = file
+		}
+	}
+	return best
+}
@@ -0,0 +1,230 @@
+package bundle
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const xmlInfoPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleDocumentTypes</key>
+	<array>
+		<dict>
+			<key>CFBundleIdentifier</key>
+			<string>com.example.nested</string>
+		</dict>
+	</array>
+	<key>LSUIElement</key>
+	<true/>
+	<key>CFBundleIdentifier</key>
+	<string>com.example.app</string>
+	<key>CFBundleShortVersionString</key>
+	<string>1.2.0</string>
+	<key>CFBundleVersion</key>
+	<string>42</string>
+	<key>TWChannel</key>
+	<string>beta</string>
+</dict>
+</plist>
+`
+
+func writeZip(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+	zipPath := filepath.Join(t.TempDir(), "App.zip")
+	out, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatalf("create zip: %v", err)
+	}
+	writer := zip.NewWriter(out)
+	for name, data := range files {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatalf("create entry: %v", err)
+		}
+		if _, err := entry.Write(data); err != nil {
+			t.Fatalf("write entry: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("close file: %v", err)
+	}
+	return zipPath
+}
+
+// binaryPlistDict encodes a flat dictionary of ASCII strings in bplist00
+// format.
+func binaryPlistDict(pairs [][2]string) []byte {
+	data := []byte("bplist00")
+	var offsets []int
+
+	count := len(pairs)
+	offsets = append(offsets, len(data))
+	data = append(data, 0xD0|byte(count))
+	for i := range pairs {
+		data = append(data, byte(1+i))
+	}
+	for i := range pairs {
+		data = append(data, byte(1+count+i))
+	}
+	var strs []string
+	for _, pair := range pairs {
+		strs = append(strs, pair[0])
+	}
+	for _, pair := range pairs {
+		strs = append(strs, pair[1])
+	}
+	for _, s := range strs {
+		offsets = append(offsets, len(data))
+		if len(s) < 0x0F {
+			data = append(data, 0x50|byte(len(s)))
+		} else {
+			data = append(data, 0x5F, 0x10, byte(len(s)))
+		}
+		data = append(data, s...)
+	}
+
+	tableOffset := len(data)
+	for _, offset := range offsets {
+		data = append(data, byte(offset))
+	}
+	trailer := make([]byte, 32)
+	trailer[6] = 1
+	trailer[7] = 1
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(offsets)))
+	binary.BigEndian.PutUint64(trailer[16:], 0)
+	binary.BigEndian.PutUint64(trailer[24:], uint64(tableOffset))
+	return append(data, trailer...)
+}
+
+func TestReadInfoXMLPlist(t *testing.T) {
+	zipPath := writeZip(t, map[string][]byte{
+		"MyApp.app/Contents/Info.plist": []byte(xmlInfoPlist),
+		"MyApp.app/Contents/Frameworks/Sparkle.framework/Versions/B/Updater.app/Contents/Info.plist": []byte(`<plist><dict><key>CFBundleIdentifier</key><string>org.sparkle-project.Sparkle.Updater</string></dict></plist>`),
+	})
+
+	info, err := ReadInfo(zipPath)
+	if err != nil {
+		t.Fatalf("read info: %v", err)
+	}
+	if info.BundleIdentifier != "com.example.app" || info.ShortVersion != "1.2.0" || info.BundleVersion != "42" || info.Channel != "beta" {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+}
+
+func TestReadInfoBinaryPlist(t *testing.T) {
+	zipPath := writeZip(t, map[string][]byte{
+		"MyApp.app/Contents/Info.plist": binaryPlistDict([][2]string{
+			{"CFBundleIdentifier", "com.example.binary"},
+			{"CFBundleVersion", "7"},
+		}),
+	})
+
+	id, err := BundleIdentifier(zipPath)
+	if err != nil {
+		t.Fatalf("bundle identifier: %v", err)
+	}
+	if id != "com.example.binary" {
+		t.Fatalf("expected com.example.binary, got %q", id)
+	}
+}
+
+func TestReadInfoMissingPlist(t *testing.T) {
+	zipPath := writeZip(t, map[string][]byte{"README.txt": []byte("hello")})
+
+	if _, err := ReadInfo(zipPath); !errors.Is(err, ErrNoInfoPlist) {
+		t.Fatalf("expected ErrNoInfoPlist, got %v", err)
+	}
+}
+
+func TestReadInfoWithoutChannel(t *testing.T) {
+	zipPath := writeZip(t, map[string][]byte{
+		"MyApp.app/Contents/Info.plist": binaryPlistDict([][2]string{{"CFBundleIdentifier", "com.example.binary"}}),
+	})
+
+	info, err := ReadInfo(zipPath)
+	if err != nil {
+		t.Fatalf("read info: %v", err)
+	}
+	if info.Channel != "" {
+		t.Fatalf("expected no channel, got %q", info.Channel)
+	}
+}
+
+func TestParseBinaryPlistRejectsMalformedInput(t *testing.T) {
+	// The fixture is "bplist00", a one-entry dictionary at offset 8, the key
+	// string at 11, the value string at 32, the offset table and the trailer.
+	valid := binaryPlistDict([][2]string{{"CFBundleIdentifier", "com.example.app"}})
+	trailer := func(data []byte) []byte { return data[len(data)-32:] }
+	const huge = ^uint64(0)
+
+	tests := []struct {
+		name    string
+		mutate  func(data []byte) []byte
+		wantErr bool
+	}{
+		{name: "truncated", mutate: func(data []byte) []byte { return data[:39] }, wantErr: true},
+		{name: "zero ref size", mutate: func(data []byte) []byte { trailer(data)[7] = 0; return data }, wantErr: true},
+		{name: "oversized offset size", mutate: func(data []byte) []byte { trailer(data)[6] = 9; return data }, wantErr: true},
+		{name: "object count overflows table", mutate: func(data []byte) []byte {
+			binary.BigEndian.PutUint64(trailer(data)[8:], huge)
+			return data
+		}, wantErr: true},
+		{name: "table offset past end", mutate: func(data []byte) []byte {
+			binary.BigEndian.PutUint64(trailer(data)[24:], huge)
+			return data
+		}, wantErr: true},
+		{name: "top object out of range", mutate: func(data []byte) []byte {
+			binary.BigEndian.PutUint64(trailer(data)[16:], huge)
+			return data
+		}, wantErr: true},
+		{name: "dictionary count overflows", mutate: func(data []byte) []byte {
+			copy(data[8:], []byte{0xDF, 0x13, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+			return data
+		}, wantErr: true},
+		{name: "oversized length header", mutate: func(data []byte) []byte {
+			copy(data[8:], []byte{0xDF, 0x1F})
+			return data
+		}, wantErr: true},
+		{name: "utf16 string longer than the file", mutate: func(data []byte) []byte {
+			copy(data[32:], []byte{0x6F, 0x13, 0x7F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+			return data
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.mutate(append([]byte(nil), valid...))
+			values, err := parsePlistStrings(data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", values)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if _, ok := values["CFBundleIdentifier"]; ok {
+				t.Fatalf("expected the malformed value to be skipped, got %v", values)
+			}
+		})
+	}
+}
+
+func FuzzParsePlistStrings(f *testing.F) {
+	f.Add([]byte(xmlInfoPlist))
+	f.Add(binaryPlistDict([][2]string{{"CFBundleIdentifier", "com.example.app"}, {"CFBundleVersion", "7"}}))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Only the absence of panics matters here.
+		_, _ = parsePlistStrings(data)
+	})
+}
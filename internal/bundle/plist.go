@@ -0,0 +1,226 @@
+package bundle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// parsePlistStrings returns the string values of a property list's top-level
+// dictionary. Both XML and binary plists are supported; non-string values
+// are skipped.
+func parsePlistStrings(data []byte) (map[string]string, error) {
+	if bytes.HasPrefix(data, []byte("bplist00")) {
+		return parseBinaryPlistStrings(data)
+	}
+	return parseXMLPlistStrings(data)
+}
+
+func parseXMLPlistStrings(data []byte) (map[string]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	values := make(map[string]string)
+
+	depth := 0 // nesting below the top-level <dict>
+	inDict := false
+	key := ""
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if inDict {
+				return values, nil
+			}
+			return nil, errors.New("no top-level dictionary")
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if !inDict {
+				if t.Name.Local == "dict" {
+					inDict = true
+				}
+				continue
+			}
+			depth++
+			if depth != 1 {
+				continue
+			}
+			switch t.Name.Local {
+			case "key", "string":
+				var text string
+				if err := decoder.DecodeElement(&text, &t); err != nil {
+					return nil, err
+				}
+				depth--
+				if t.Name.Local == "key" {
+					key = text
+				} else if key != "" {
+					values[key] = text
+					key = ""
+				}
+			default:
+				key = ""
+			}
+		case xml.EndElement:
+			if !inDict {
+				continue
+			}
+			if depth == 0 {
+				return values, nil
+			}
+			depth--
+		}
+	}
+}
+
+// parseBinaryPlistStrings walks just enough of the bplist00 format to read
+// string values from the top-level dictionary. Every offset and length comes
+// from the file, so each one is bounds-checked before use.
+func parseBinaryPlistStrings(data []byte) (map[string]string, error) {
+	if len(data) < 8+32 {
+		return nil, errors.New("binary plist too short")
+	}
+	trailer := data[len(data)-32:]
+	p := &binaryPlist{
+		data:        data,
+		offsetSize:  uint64(trailer[6]),
+		refSize:     uint64(trailer[7]),
+		numObjects:  binary.BigEndian.Uint64(trailer[8:16]),
+		tableOffset: binary.BigEndian.Uint64(trailer[24:32]),
+	}
+	if p.offsetSize < 1 || p.offsetSize > 8 || p.refSize < 1 || p.refSize > 8 {
+		return nil, errors.New("binary plist has invalid integer sizes")
+	}
+	if _, err := p.span(p.tableOffset, p.numObjects, p.offsetSize); err != nil {
+		return nil, errors.New("binary plist offset table out of range")
+	}
+
+	offset, err := p.objectOffset(binary.BigEndian.Uint64(trailer[16:24]))
+	if err != nil {
+		return nil, err
+	}
+	if data[offset]>>4 != 0xD {
+		return nil, errors.New("top-level object is not a dictionary")
+	}
+	count, start, err := p.length(offset)
+	if err != nil {
+		return nil, err
+	}
+	// A dictionary lists all its key refs, then all its value refs.
+	keyRefs, err := p.span(start, count, p.refSize)
+	if err != nil {
+		return nil, errors.New("binary plist dictionary out of range")
+	}
+	valueRefs, err := p.span(start+uint64(len(keyRefs)), count, p.refSize)
+	if err != nil {
+		return nil, errors.New("binary plist dictionary out of range")
+	}
+
+	values := make(map[string]string)
+	for i := uint64(0); i < count; i++ {
+		keyRef := p.uint(keyRefs[i*p.refSize : (i+1)*p.refSize])
+		valueRef := p.uint(valueRefs[i*p.refSize : (i+1)*p.refSize])
+		key, ok, err := p.stringObject(keyRef)
+		if err != nil || !ok {
+			continue
+		}
+		if value, ok, err := p.stringObject(valueRef); err == nil && ok {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+type binaryPlist struct {
+	data        []byte
+	offsetSize  uint64
+	refSize     uint64
+	numObjects  uint64
+	tableOffset uint64
+}
+
+// span returns count items of size bytes starting at offset, or an error if
+// any of it lies outside the data. The checks can't overflow, however large
+// the values read from the file are.
+func (p *binaryPlist) span(offset, count, size uint64) ([]byte, error) {
+	total := uint64(len(p.data))
+	if offset > total || size == 0 || count > (total-offset)/size {
+		return nil, errors.New("binary plist offset out of range")
+	}
+	return p.data[offset : offset+count*size], nil
+}
+
+// uint decodes a big-endian unsigned integer of up to 8 bytes.
+func (p *binaryPlist) uint(b []byte) uint64 {
+	var value uint64
+	for _, c := range b {
+		value = value<<8 | uint64(c)
+	}
+	return value
+}
+
+func (p *binaryPlist) objectOffset(ref uint64) (uint64, error) {
+	if ref >= p.numObjects {
+		return 0, fmt.Errorf("binary plist object %d out of range", ref)
+	}
+	// The whole offset table was checked up front, so this can't overflow.
+	entry := p.tableOffset + ref*p.offsetSize
+	offset := p.uint(p.data[entry : entry+p.offsetSize])
+	if offset >= uint64(len(p.data)) {
+		return 0, errors.New("binary plist object offset out of range")
+	}
+	return offset, nil
+}
+
+// length decodes the element count of the object at offset and returns it
+// with the offset of the object's payload.
+func (p *binaryPlist) length(offset uint64) (uint64, uint64, error) {
+	count := uint64(p.data[offset] & 0x0F)
+	if count != 0x0F {
+		return count, offset + 1, nil
+	}
+	header, err := p.span(offset+1, 1, 1)
+	if err != nil || header[0]>>4 != 0x1 || header[0]&0x0F > 3 {
+		return 0, 0, errors.New("binary plist has malformed length")
+	}
+	size := uint64(1) << (header[0] & 0x0F)
+	b, err := p.span(offset+2, 1, size)
+	if err != nil {
+		return 0, 0, errors.New("binary plist has malformed length")
+	}
+	return p.uint(b), offset + 2 + size, nil
+}
+
+func (p *binaryPlist) stringObject(ref uint64) (string, bool, error) {
+	offset, err := p.objectOffset(ref)
+	if err != nil {
+		return "", false, err
+	}
+	kind := p.data[offset] >> 4
+	if kind != 0x5 && kind != 0x6 {
+		return "", false, nil
+	}
+	count, start, err := p.length(offset)
+	if err != nil {
+		return "", false, err
+	}
+	if kind == 0x5 {
+		b, err := p.span(start, count, 1)
+		if err != nil {
+			return "", false, errors.New("binary plist string out of range")
+		}
+		return string(b), true, nil
+	}
+	// The span check bounds count by the file size before anything is
+	// allocated from it.
+	b, err := p.span(start, count, 2)
+	if err != nil {
+		return "", false, errors.New("binary plist string out of range")
+	}
+	units := make([]uint16, count)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(units)), true, nil
+}
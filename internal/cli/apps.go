@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+func newAppsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apps",
+		Short: "Manage apps",
+	}
+
+	cmd.AddCommand(newAppsListCmd())
+	cmd.AddCommand(newAppsDeleteCmd())
+
+	return cmd
+}
+
+// knownAppPlatforms lists the platforms --platform accepts.
+var knownAppPlatforms = []string{"macos", "ios", "tvos"}
+
+func newAppsListCmd() *cobra.Command {
+	var platform string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the apps the API key can access",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			platform = strings.ToLower(strings.TrimSpace(platform))
+			if err := validateAppPlatform(platform); err != nil {
+				return err
+			}
+
+			appCtx, err := getAppContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			apps, err := appCtx.Client.ListApps(cmd.Context(), api.ListAppsParams{Platform: platform})
+			if err != nil {
+				return err
+			}
+			return renderOutputWithOptions(cmd, appCtx.outputOptions(), api.AppsResponse{Apps: filterAppsByPlatform(apps, platform)})
+		},
+	}
+
+	cmd.Flags().StringVar(&platform, "platform", "", "Only list apps for this platform ("+strings.Join(knownAppPlatforms, ", ")+")")
+
+	return cmd
+}
+
+func validateAppPlatform(platform string) error {
+	if platform == "" {
+		return nil
+	}
+	for _, known := range knownAppPlatforms {
+		if platform == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown platform %q: must be one of %s", platform, strings.Join(knownAppPlatforms, ", "))
+}
+
+// filterAppsByPlatform drops apps for other platforms, for servers that
+// ignore the platform query parameter. Apps without a platform are kept:
+// the server filtered them already or never reports platforms.
+func filterAppsByPlatform(apps []api.App, platform string) []api.App {
+	if platform == "" {
+		return apps
+	}
+	filtered := make([]api.App, 0, len(apps))
+	for _, app := range apps {
+		if app.Platform == "" || strings.EqualFold(app.Platform, platform) {
+			filtered = append(filtered, app)
+		}
+	}
+	return filtered
+}
+
+func init() {
+	registerPrinter(func(cmd *cobra.Command, resp api.AppsResponse, _ outputOptions) { printAppList(cmd, resp) })
+}
+
+func printAppList(cmd *cobra.Command, resp api.AppsResponse) {
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tBUNDLE ID\tPLATFORM")
+	for _, app := range resp.Apps {
+		platform := app.Platform
+		if platform == "" {
+			platform = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", app.ID, app.Name, app.BundleID, platform)
+	}
+	_ = tw.Flush()
+}
+
+func newAppsDeleteCmd() *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "delete <app-id>",
+		Short: "Delete an app and all of its builds",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appID := args[0]
+
+			appCtx, err := getAppContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			if !yes {
+				if err := confirmAppID(cmd, appID); err != nil {
+					return err
+				}
+			}
+
+			if err := appCtx.Client.DeleteApp(cmd.Context(), appID); err != nil {
+				return err
+			}
+
+			if appCtx.JSON {
+				return renderOutputWithOptions(cmd, appCtx.outputOptions(), map[string]interface{}{
+					"app_id":  appID,
+					"deleted": true,
+				})
+			}
+			Successf(cmd.OutOrStdout(), "App %s deleted", appID)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+// confirmAppID makes the user type the app ID before a deletion. A y/N
+// prompt is too easy to answer on autopilot for something this destructive.
+func confirmAppID(cmd *cobra.Command, appID string) error {
+	p, err := newPrompter(cmd)
+	if errors.Is(err, errNotInteractive) {
+		return errors.New("refusing to delete without confirmation: pass --yes to skip the prompt")
+	}
+	if err != nil {
+		return err
+	}
+
+	Statusf(cmd.ErrOrStderr(), "This permanently deletes app %s and all of its builds.", appID)
+	answer, err := p.line("Type the app ID to confirm: ")
+	if err != nil {
+		return err
+	}
+	if answer != appID {
+		return fmt.Errorf("delete cancelled: %q doesn't match the app ID", answer)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+// newAppListServer serves a mixed-platform app list, with one app that has
+// no platform, and ignores the platform query parameter, recording it
+// instead.
+func newAppListServer(t *testing.T) (*httptest.Server, *string) {
+	t.Helper()
+	var platform string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/v1/apps" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		platform = r.URL.Query().Get("platform")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(api.AppsResponse{Apps: []api.App{
+			{ID: "app_mac", Name: "Menu Bar", BundleID: "com.example.menubar", Platform: "macos"},
+			{ID: "app_ios", Name: "Companion", BundleID: "com.example.companion", Platform: "ios"},
+			{ID: "app_tv", Name: "Big Screen", BundleID: "com.example.tv", Platform: "tvos"},
+			{ID: "app_old", Name: "Legacy", BundleID: "com.example.legacy"},
+		}})
+	}))
+	t.Cleanup(server.Close)
+	return server, &platform
+}
+
+func TestAppsListShowsPlatformColumn(t *testing.T) {
+	server, _ := newAppListServer(t)
+
+	stdout, _, err := executeCLI(t, server.URL, "apps", "list")
+	if err != nil {
+		t.Fatalf("apps list: %v", err)
+	}
+	if !strings.Contains(stdout, "PLATFORM") {
+		t.Fatalf("expected a platform column, got %q", stdout)
+	}
+	for _, want := range []string{"app_mac", "app_ios", "app_tv"} {
+		if !strings.Contains(stdout, want) {
+			t.Fatalf("expected %s in the unfiltered list, got %q", want, stdout)
+		}
+	}
+}
+
+func TestAppsListFiltersByPlatform(t *testing.T) {
+	server, platform := newAppListServer(t)
+
+	stdout, _, err := executeCLI(t, server.URL, "--json", "apps", "list", "--platform", "macOS")
+	if err != nil {
+		t.Fatalf("apps list: %v", err)
+	}
+	if *platform != "macos" {
+		t.Fatalf("expected platform=macos in the query, got %q", *platform)
+	}
+	var resp api.AppsResponse
+	if err := json.Unmarshal([]byte(stdout), &resp); err != nil {
+		t.Fatalf("decode %q: %v", stdout, err)
+	}
+	if len(resp.Apps) != 2 || resp.Apps[0].ID != "app_mac" || resp.Apps[1].ID != "app_old" {
+		t.Fatalf("expected the macOS app and the one without a platform, got %+v", resp.Apps)
+	}
+}
+
+func TestAppsListRejectsUnknownPlatform(t *testing.T) {
+	server, platform := newAppListServer(t)
+
+	_, _, err := executeCLI(t, server.URL, "apps", "list", "--platform", "android")
+	if err == nil || !strings.Contains(err.Error(), `unknown platform "android": must be one of macos, ios, tvos`) {
+		t.Fatalf("expected a platform validation error, got %v", err)
+	}
+	if *platform != "" {
+		t.Fatalf("expected no request for an invalid platform, got platform=%q", *platform)
+	}
+}
+
+func newAppDeleteServer(t *testing.T) (*httptest.Server, *int) {
+	t.Helper()
+	deletes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/api/v1/apps/app_123" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		deletes++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server, &deletes
+}
+
+func TestAppsDeleteConfirmedByTypingID(t *testing.T) {
+	fakeTerminal(t)
+	server, deletes := newAppDeleteServer(t)
+
+	stdout, stderr, err := executeCLIWithInput(t, server.URL, "app_123\n", "apps", "delete", "app_123")
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if *deletes != 1 {
+		t.Fatalf("expected 1 delete request, got %d", *deletes)
+	}
+	if !strings.Contains(stderr, "Type the app ID to confirm:") {
+		t.Errorf("expected typed confirmation prompt on stderr, got %q", stderr)
+	}
+	if !strings.Contains(stdout, "App app_123 deleted") {
+		t.Errorf("expected success output, got %q", stdout)
+	}
+}
+
+func TestAppsDeleteRefusesMismatchedConfirmation(t *testing.T) {
+	fakeTerminal(t)
+	server, deletes := newAppDeleteServer(t)
+
+	for _, answer := range []string{"y\n", "app_12\n", "\n"} {
+		_, _, err := executeCLIWithInput(t, server.URL, answer, "apps", "delete", "app_123")
+		if err == nil || !strings.Contains(err.Error(), "delete cancelled") {
+			t.Fatalf("answer %q: expected cancellation, got %v", answer, err)
+		}
+	}
+	if *deletes != 0 {
+		t.Fatalf("expected no delete request, got %d", *deletes)
+	}
+}
+
+func TestAppsDeleteYesSkipsPrompt(t *testing.T) {
+	server, deletes := newAppDeleteServer(t)
+
+	_, stderr, err := executeCLI(t, server.URL, "apps", "delete", "app_123", "--yes")
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if *deletes != 1 {
+		t.Fatalf("expected 1 delete request, got %d", *deletes)
+	}
+	if strings.Contains(stderr, "confirm") {
+		t.Errorf("expected no prompt with --yes, got %q", stderr)
+	}
+}
+
+func TestAppsDeleteRefusesWithoutTerminal(t *testing.T) {
+	server, deletes := newAppDeleteServer(t)
+
+	_, _, err := executeCLIWithInput(t, server.URL, "app_123\n", "apps", "delete", "app_123")
+	if err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Fatalf("expected refusal mentioning --yes, got %v", err)
+	}
+	if *deletes != 0 {
+		t.Fatalf("expected no delete request, got %d", *deletes)
+	}
+}
+
+func TestAppsDeleteNoInputSkipsPrompt(t *testing.T) {
+	fakeTerminal(t)
+	server, deletes := newAppDeleteServer(t)
+
+	_, stderr, err := executeCLIWithInput(t, server.URL, "app_123\n", "--no-input", "apps", "delete", "app_123")
+	if err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Fatalf("expected refusal mentioning --yes, got %v", err)
+	}
+	if strings.Contains(stderr, "Type the app ID") {
+		t.Errorf("expected no prompt under --no-input, got %q", stderr)
+	}
+	if *deletes != 0 {
+		t.Fatalf("expected no delete request, got %d", *deletes)
+	}
+}
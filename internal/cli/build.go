@@ -1,18 +1,24 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/twinkle-apps/cli/internal/api"
+	"github.com/twinkle-apps/cli/internal/bundle"
 )
 
 func newBuildCmd() *cobra.Command {
@@ -22,55 +28,124 @@ func newBuildCmd() *cobra.Command {
 	}
 
 	cmd.AddCommand(newBuildStatusCmd())
+	cmd.AddCommand(newBuildListCmd())
 	cmd.AddCommand(newBuildWaitCmd())
 	cmd.AddCommand(newBuildUploadCmd())
+	cmd.AddCommand(newBuildDeleteCmd())
+	cmd.AddCommand(newBuildPromoteCmd())
+	cmd.AddCommand(newBuildRebuildCmd())
+	cmd.AddCommand(newBuildCompleteCmd())
+	cmd.AddCommand(newBuildDiffCmd())
+	cmd.AddCommand(newBuildOpenCmd())
+	cmd.AddCommand(newBuildMetadataCmd())
 
 	return cmd
 }
 
 func newBuildStatusCmd() *cobra.Command {
+	var (
+		buildRef   buildRefFlags
+		statusOnly bool
+		wait       bool
+		waiting    waitFlags
+		raw        bool
+	)
+
 	cmd := &cobra.Command{
 		Use:   "status <app-id> <build-id>",
 		Short: "Get build status",
-		Args:  cobra.ExactArgs(2),
+		Args:  buildRef.validateArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			appID := args[0]
-			buildID := args[1]
+			appID, buildID := buildRef.resolve(args)
+
+			if err := waiting.rejectUnlessWaiting(cmd, wait); err != nil {
+				return err
+			}
+			if err := waiting.validate(cmd); err != nil {
+				return err
+			}
 
 			appCtx, err := getAppContext(cmd)
 			if err != nil {
 				return err
 			}
+			if err := validateStatusOnly(statusOnly, appCtx); err != nil {
+				return err
+			}
+
+			if raw {
+				if wait || statusOnly {
+					return errors.New("--raw can't be combined with --wait or --status-only")
+				}
+				resp, err := appCtx.Client.GetBuildRaw(cmd.Context(), appID, buildID)
+				if err != nil {
+					return err
+				}
+				return writeRawOutput(cmd, appCtx.outputOptions(), resp.Body)
+			}
+
+			if !wait {
+				resp, err := appCtx.Client.GetBuild(cmd.Context(), appID, buildID)
+				if err != nil {
+					return err
+				}
+				if statusOnly {
+					return statusExitError(cmd, resp)
+				}
+				if err := renderOutputWithOptions(cmd, appCtx.outputOptions(), resp); err != nil {
+					return err
+				}
+				return appcastExitError(cmd, resp)
+			}
 
-			resp, err := appCtx.Client.GetBuild(cmd.Context(), appID, buildID)
+			start := time.Now()
+			jsonOut := appCtx.JSON || statusOnly
+			resp, err := waiting.poll(cmd, appCtx, appID, buildID, jsonOut)
 			if err != nil {
 				return err
 			}
-
-			return renderOutput(cmd, appCtx.JSON, appCtx.Verbose, resp)
+			if statusOnly {
+				return statusExitError(cmd, resp)
+			}
+			if err := waiting.render(cmd, appCtx, resp); err != nil {
+				return err
+			}
+			if !jsonOut {
+				Done(cmd.ErrOrStderr(), time.Since(start))
+			}
+			return appcastExitError(cmd, resp)
 		},
 	}
 
+	buildRef.register(cmd)
+	addStatusOnlyFlag(cmd, &statusOnly)
+	cmd.Flags().BoolVar(&wait, "wait", false, "Wait for processing to finish, like `build wait`")
+	cmd.Flags().BoolVar(&raw, "raw", false, "Print the server's response body exactly as received, without decoding it")
+	waiting.register(cmd)
+
 	return cmd
 }
 
-func newBuildWaitCmd() *cobra.Command {
-	var timeout int
-	const pollInterval = 5 * time.Second
+func newBuildListCmd() *cobra.Command {
+	var (
+		limit      int
+		cursor     string
+		since      string
+		until      string
+		sinceBuild int
+	)
 
 	cmd := &cobra.Command{
-		Use:   "wait <app-id> <build-id>",
-		Short: "Wait for build processing",
-		Args:  cobra.ExactArgs(2),
+		Use:   "list <app-id>",
+		Short: "List an app's builds",
+		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			appID := args[0]
-			buildID := args[1]
-
-			if timeout < 0 {
-				return errors.New("timeout must be >= 0")
+			if limit < 0 {
+				return errors.New("limit must be >= 0")
 			}
-			if timeout > 300 {
-				return errors.New("timeout must be <= 300")
+			if sinceBuild < 0 {
+				return errors.New("--since-build must be >= 0")
 			}
 
 			appCtx, err := getAppContext(cmd)
@@ -78,71 +153,190 @@ func newBuildWaitCmd() *cobra.Command {
 				return err
 			}
 
-			stderr := cmd.ErrOrStderr()
-			start := time.Now()
-			jsonOut := appCtx.JSON
+			params := api.ListBuildsParams{Limit: limit, Cursor: cursor, AfterID: sinceBuild}
+			now := timeNow()
+			if params.Since, err = parseTimeFilter("--since", since, now); err != nil {
+				return err
+			}
+			if params.Until, err = parseTimeFilter("--until", until, now); err != nil {
+				return err
+			}
+			if !params.Since.IsZero() && !params.Until.IsZero() && params.Since.After(params.Until) {
+				return errors.New("--since must not be after --until")
+			}
+			opts := appCtx.outputOptions()
 
-			if !jsonOut {
-				Statusf(stderr, "Waiting for build %s…", buildID)
+			var nextCursor *string
+			if opts.JSON || (opts.OutputFile != "" && opts.OutputFile != "-") {
+				resp, err := appCtx.Client.ListBuilds(cmd.Context(), appID, params)
+				if err != nil {
+					return err
+				}
+				resp.Builds = buildsAfter(resp.Builds, sinceBuild)
+				if err := renderOutputWithOptions(cmd, opts, resp); err != nil {
+					return err
+				}
+				nextCursor = resp.NextCursor
+			} else {
+				// Stream rows to the terminal as they decode.
+				out := cmd.OutOrStdout()
+				printBuildListHeader(out)
+				nextCursor, err = appCtx.Client.StreamBuilds(cmd.Context(), appID, params, func(build api.Build) error {
+					if build.ID > sinceBuild {
+						printBuildListRow(out, build)
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			if nextCursor != nil && *nextCursor != "" && !opts.JSON {
+				Statusf(cmd.ErrOrStderr(), "More builds available: pass --cursor %s", *nextCursor)
 			}
-			resp, err := pollBuildStatus(cmd.Context(), stderr, appCtx.Client, appID, buildID, "", timeout, pollInterval, appCtx.Verbose, jsonOut)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", 0, "Maximum builds to return (server default when 0)")
+	cmd.Flags().StringVar(&cursor, "cursor", "", "Continue from a previous page's cursor")
+	cmd.Flags().StringVar(&since, "since", "", "Only builds created at or after this time (RFC3339, or relative like 24h or 7d)")
+	cmd.Flags().StringVar(&until, "until", "", "Only builds created at or before this time (RFC3339, or relative like 24h or 7d)")
+	cmd.Flags().IntVar(&sinceBuild, "since-build", 0, "Only builds with an ID greater than this one, for polling for new builds")
+
+	return cmd
+}
+
+// buildsAfter keeps the builds with an ID greater than afterID, for servers
+// that ignore the after_id parameter. A filtered result is never nil, so
+// JSON output shows no new builds as an empty list rather than null.
+func buildsAfter(builds []api.Build, afterID int) []api.Build {
+	if afterID <= 0 {
+		return builds
+	}
+	newer := make([]api.Build, 0, len(builds))
+	for _, build := range builds {
+		if build.ID > afterID {
+			newer = append(newer, build)
+		}
+	}
+	return newer
+}
+
+func newBuildDeleteCmd() *cobra.Command {
+	var (
+		yes      bool
+		buildRef buildRefFlags
+	)
+
+	cmd := &cobra.Command{
+		Use:   "delete <app-id> <build-id>",
+		Short: "Delete a build",
+		Args:  buildRef.validateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appID, buildID := buildRef.resolve(args)
+
+			appCtx, err := getAppContext(cmd)
 			if err != nil {
 				return err
 			}
 
-			if err := renderOutput(cmd, jsonOut, appCtx.Verbose, resp); err != nil {
+			if !yes {
+				ok, err := confirm(cmd, fmt.Sprintf("Delete build %s of %s?", buildID, appID))
+				if errors.Is(err, errNotInteractive) {
+					return errors.New("refusing to delete without confirmation: pass --yes to skip the prompt")
+				}
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return errors.New("delete cancelled")
+				}
+			}
+
+			if err := appCtx.Client.DeleteBuild(cmd.Context(), appID, buildID); err != nil {
 				return err
 			}
-			if !jsonOut {
-				Done(stderr, time.Since(start))
+
+			if appCtx.JSON {
+				return renderOutputWithOptions(cmd, appCtx.outputOptions(), map[string]interface{}{
+					"app_id":   appID,
+					"build_id": buildID,
+					"deleted":  true,
+				})
 			}
+			Successf(cmd.OutOrStdout(), "Build %s deleted", buildID)
 			return nil
 		},
 	}
 
-	cmd.Flags().IntVar(&timeout, "timeout", 0, "Wait timeout in seconds (max 300)")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip the confirmation prompt")
+	buildRef.register(cmd)
 
 	return cmd
 }
 
-func newBuildUploadCmd() *cobra.Command {
-	return newBuildUploadCmdWithUse("upload <app-id> <file>", "Upload a build", nil)
-}
+func newBuildPromoteCmd() *cobra.Command {
+	var channel string
 
-func newShipCmd() *cobra.Command {
-	return newBuildUploadCmdWithUse("ship <app-id> <file>", "Alias for build upload", nil)
+	cmd := &cobra.Command{
+		Use:   "promote <app-id> <build-id>",
+		Short: "Promote a build to another channel",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appID := args[0]
+			buildID := args[1]
+
+			if strings.TrimSpace(channel) == "" {
+				return errors.New("target channel is required: set --to")
+			}
+
+			appCtx, err := getAppContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			if !appCtx.JSON {
+				Statusf(cmd.ErrOrStderr(), "Promoting build %s to %s…", buildID, channel)
+			}
+			resp, err := appCtx.Client.PromoteBuild(cmd.Context(), appID, buildID, channel)
+			if err != nil {
+				var apiErr *api.APIError
+				if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+					return fmt.Errorf("build %s can't be promoted to %s: %w", buildID, channel, err)
+				}
+				return err
+			}
+
+			return renderOutputWithOptions(cmd, appCtx.outputOptions(), resp)
+		},
+	}
+
+	cmd.Flags().StringVar(&channel, "to", "", "Target channel (e.g. stable)")
+
+	return cmd
 }
 
-func newBuildUploadCmdWithUse(use, short string, aliases []string) *cobra.Command {
+func newBuildRebuildCmd() *cobra.Command {
 	var (
 		wait    bool
-		timeout int
+		waiting waitFlags
 	)
-	const pollInterval = 5 * time.Second
 
 	cmd := &cobra.Command{
-		Use:     use,
-		Short:   short,
-		Aliases: aliases,
-		Args:    cobra.ExactArgs(2),
+		Use:   "rebuild <app-id> <build-id>",
+		Short: "Re-run processing for a build without re-uploading",
+		Args:  cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			appID := args[0]
-			filePath := args[1]
+			buildID := args[1]
 
-			if strings.TrimSpace(filePath) == "" {
-				return errors.New("file path is required")
-			}
-			if _, err := os.Stat(filePath); err != nil {
-				return fmt.Errorf("file not accessible: %w", err)
-			}
-			if strings.ToLower(filepath.Ext(filePath)) != ".zip" {
-				return errors.New("only .zip archives are supported")
-			}
-			if timeout < 0 {
-				return errors.New("timeout must be >= 0")
+			if err := waiting.rejectUnlessWaiting(cmd, wait); err != nil {
+				return err
 			}
-			if timeout > 300 {
-				return errors.New("timeout must be <= 300")
+			if err := waiting.validate(cmd); err != nil {
+				return err
 			}
 
 			appCtx, err := getAppContext(cmd)
@@ -151,149 +345,1403 @@ func newBuildUploadCmdWithUse(use, short string, aliases []string) *cobra.Comman
 			}
 
 			stderr := cmd.ErrOrStderr()
-			totalStart := time.Now()
-			verbose := appCtx.Verbose
+			start := time.Now()
 			jsonOut := appCtx.JSON
 
-			// Step 1: Prepare upload
-			stepStart := time.Now()
 			if !jsonOut {
-				Statusf(stderr, "Preparing upload for %s…", filepath.Base(filePath))
+				Statusf(stderr, "Rebuilding build %s…", buildID)
+			}
+			resp, err := appCtx.Client.RebuildBuild(cmd.Context(), appID, buildID)
+			if err != nil {
+				var apiErr *api.APIError
+				if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+					return fmt.Errorf("build %s is still processing; wait for it to finish before rebuilding: %w", buildID, err)
+				}
+				return err
 			}
 
-			resolvedContentType := "application/zip"
-			params := api.BuildUploadParams{
-				ContentType: resolvedContentType,
+			if wait {
+				resp, err = waiting.poll(cmd, appCtx, appID, buildID, jsonOut)
+				if err != nil {
+					return err
+				}
 			}
 
-			createResp, err := appCtx.Client.CreateUpload(cmd.Context(), appID, params)
-			if err != nil {
+			if err := waiting.render(cmd, appCtx, resp); err != nil {
 				return err
 			}
-			if verbose && !jsonOut {
-				VerboseStatus(stderr, "Prepared upload", time.Since(stepStart))
+			if wait && !jsonOut {
+				Done(stderr, time.Since(start))
 			}
+			return appcastExitError(cmd, resp)
+		},
+	}
 
-			// Step 2: Upload file
-			stepStart = time.Now()
-			if !jsonOut {
-				Statusf(stderr, "Uploading to edge network…")
-			}
+	cmd.Flags().BoolVar(&wait, "wait", false, "Wait for processing to finish")
+	waiting.register(cmd)
+
+	return cmd
+}
+
+func newBuildCompleteCmd() *cobra.Command {
+	var (
+		fromState string
+		wait      bool
+		waiting   waitFlags
+	)
 
-			if err := appCtx.Client.UploadFile(cmd.Context(), createResp.UploadURL, filePath, resolvedContentType); err != nil {
+	cmd := &cobra.Command{
+		Use:   "complete --from-state <file>",
+		Short: "Complete an upload saved with --save-state",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if fromState == "" {
+				return errors.New("state file is required: set --from-state")
+			}
+			if err := waiting.rejectUnlessWaiting(cmd, wait); err != nil {
 				return err
 			}
-			if verbose && !jsonOut {
-				VerboseStatus(stderr, "Uploaded", time.Since(stepStart))
+			if err := waiting.validate(cmd); err != nil {
+				return err
 			}
 
-			// Step 3: Complete upload
-			stepStart = time.Now()
-			if !jsonOut {
-				Status(stderr, "Finalizing upload…")
+			state, err := loadUploadState(fromState)
+			if err != nil {
+				return err
 			}
 
-			buildID := createResp.BuildID.Int()
-			completeResp, err := appCtx.Client.CompleteUpload(cmd.Context(), appID, buildID)
+			appCtx, err := getAppContext(cmd)
 			if err != nil {
 				return err
 			}
-			if verbose && !jsonOut {
-				VerboseStatus(stderr, "Finalized", time.Since(stepStart))
-			}
 
-			if !wait {
-				if err := renderOutput(cmd, jsonOut, verbose, completeResp); err != nil {
-					return err
-				}
-				if !jsonOut {
-					Done(stderr, time.Since(totalStart))
-				}
-				return nil
+			stderr := cmd.ErrOrStderr()
+			start := time.Now()
+			jsonOut := appCtx.JSON
+
+			result, err := finishUpload(cmd.Context(), stderr, appCtx.Client, uploadRequest{
+				AppID:                  state.AppID,
+				FilePath:               state.File,
+				Size:                   state.Size,
+				Wait:                   wait,
+				Waiting:                waiting,
+				Progress:               waiting.progress(cmd, appCtx),
+				PendingAppcastStatuses: appCtx.PendingAppcastStatuses,
+			}, state.Upload.BuildID.Int(), appCtx.Verbose, jsonOut)
+			if err != nil {
+				return err
 			}
 
-			// Step 4: Wait for processing
-			stepStart = time.Now()
+			if err := waiting.render(cmd, appCtx, result.payload()); err != nil {
+				return err
+			}
 			if !jsonOut {
-				Status(stderr, "Processing build…")
+				Done(stderr, time.Since(start))
+			}
+			return appcastExitError(cmd, result.payload())
+		},
+	}
+
+	cmd.Flags().StringVar(&fromState, "from-state", "", "State file written by `build upload --save-state`")
+	cmd.Flags().BoolVar(&wait, "wait", false, "Wait for processing to complete")
+	waiting.register(cmd)
+
+	_ = cmd.MarkFlagFilename("from-state", "json")
+
+	return cmd
+}
+
+func newBuildWaitCmd() *cobra.Command {
+	var (
+		waiting    waitFlags
+		buildRef   buildRefFlags
+		statusOnly bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "wait <app-id> <build-id>",
+		Short: "Wait for build processing",
+		Args:  buildRef.validateArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appID, buildID := buildRef.resolve(args)
+
+			if err := waiting.validate(cmd); err != nil {
+				return err
 			}
 
-			waitResp, err := pollBuildStatus(cmd.Context(), stderr, appCtx.Client, appID, fmt.Sprintf("%d", buildID), completeResp.WaitURL, timeout, pollInterval, verbose, jsonOut)
+			appCtx, err := getAppContext(cmd)
 			if err != nil {
 				return err
 			}
-			if verbose && !jsonOut {
-				VerboseStatus(stderr, "Processing complete", time.Since(stepStart))
+			if err := validateStatusOnly(statusOnly, appCtx); err != nil {
+				return err
+			}
+
+			stderr := cmd.ErrOrStderr()
+			start := time.Now()
+			// --status-only is as quiet as JSON output.
+			jsonOut := appCtx.JSON || statusOnly
+
+			resp, err := waiting.poll(cmd, appCtx, appID, buildID, jsonOut)
+			if err != nil {
+				return err
 			}
 
-			if err := renderOutput(cmd, jsonOut, verbose, waitResp); err != nil {
+			if statusOnly {
+				return statusExitError(cmd, resp)
+			}
+			if err := waiting.render(cmd, appCtx, resp); err != nil {
 				return err
 			}
 			if !jsonOut {
-				Done(stderr, time.Since(totalStart))
+				Done(stderr, time.Since(start))
 			}
-			return nil
+			return appcastExitError(cmd, resp)
 		},
 	}
 
-	cmd.Flags().BoolVar(&wait, "wait", false, "Wait for processing to complete")
-	cmd.Flags().IntVar(&timeout, "timeout", 0, "Wait timeout in seconds (max 300)")
-
-	_ = cmd.MarkFlagFilename("file")
+	waiting.register(cmd)
+	buildRef.register(cmd)
+	addStatusOnlyFlag(cmd, &statusOnly)
 
 	return cmd
 }
 
-func pollBuildStatus(ctx context.Context, stderr io.Writer, client *api.Client, appID, buildID, waitURL string, timeoutSeconds int, interval time.Duration, verbose, jsonOut bool) (api.BuildResponse, error) {
-	deadline := time.Time{}
-	if timeoutSeconds > 0 {
-		deadline = time.Now().Add(time.Duration(timeoutSeconds) * time.Second)
+// waitFlags are the flags of commands that wait for a build to finish
+// processing, and the polling they drive.
+type waitFlags struct {
+	timeout         int
+	timeoutStrategy string
+	failOnTimeout   bool
+	waitFor         string
+	statusInterval  time.Duration
+	onChange        string
+	heartbeat       time.Duration
+	jsonProgress    bool
+
+	// stream carries the --json-progress events and the final result once
+	// progress has been called.
+	stream *jsonLineStream
+}
+
+// buildPollInterval is how often the poll strategy checks a build's status.
+const buildPollInterval = 5 * time.Second
+
+func (f *waitFlags) register(cmd *cobra.Command) {
+	addTimeoutFlag(cmd, &f.timeout)
+	addTimeoutStrategyFlag(cmd, &f.timeoutStrategy)
+	cmd.Flags().BoolVar(&f.failOnTimeout, "fail-on-timeout", false, "Exit with an error if the build is still processing when --timeout runs out")
+	cmd.Flags().StringVar(&f.waitFor, "wait-for", "", "Stop waiting once the build reaches this status or a later one ("+strings.Join(knownBuildStatuses, ", ")+")")
+	cmd.Flags().DurationVar(&f.statusInterval, "status-interval", 0, "Print the \"Still processing\" line at most this often (e.g. 30s); polling keeps its own pace")
+	cmd.Flags().StringVar(&f.onChange, "on-change", "", "Shell command to run whenever the build status changes; the status is in $TWINKLE_BUILD_STATUS")
+	cmd.Flags().DurationVar(&f.heartbeat, "heartbeat", 0, "Print a keepalive line to stderr this often while waiting (e.g. 1m), for CI that kills silent jobs")
+	cmd.Flags().BoolVar(&f.jsonProgress, "json-progress", false, "With --json, print a compact {\"type\": \"progress\"} line to stdout on each check before the final result")
+}
+
+// names lists the registered flags, for rejecting them when waiting is off.
+func (f *waitFlags) names() []string {
+	return []string{"timeout", "timeout-strategy", "fail-on-timeout", "wait-for", "status-interval", "on-change", "heartbeat", "json-progress"}
+}
+
+func (f *waitFlags) validate(cmd *cobra.Command) error {
+	if jsonOut, _ := cmd.Flags().GetBool("json"); f.jsonProgress && !jsonOut {
+		return errors.New("--json-progress needs --json")
+	}
+	if f.statusInterval < 0 {
+		return errors.New("--status-interval must be >= 0")
+	}
+	if f.heartbeat < 0 {
+		return errors.New("--heartbeat must be >= 0")
+	}
+	if err := validateTimeout(f.timeout); err != nil {
+		return err
 	}
+	if err := validateTimeoutStrategy(f.timeoutStrategy); err != nil {
+		return err
+	}
+	return validateWaitFor(f.waitFor)
+}
 
-	pollStart := time.Now()
+// poll waits for the build using the flag values.
+func (f *waitFlags) poll(cmd *cobra.Command, appCtx *AppContext, appID, buildID string, jsonOut bool) (api.BuildResponse, error) {
+	stderr := cmd.ErrOrStderr()
+	if !jsonOut {
+		Statusf(stderr, "Waiting for build %s…", buildID)
+	}
+	stderr, stopHeartbeat := startHeartbeat(stderr, f.heartbeat)
+	defer stopHeartbeat()
+	opts := f.pollOptions(cmd.Context(), stderr, appID)
+	opts.BuildID = buildID
+	opts.Verbose = appCtx.Verbose
+	opts.JSON = jsonOut
+	opts.PendingAppcastStatuses = appCtx.PendingAppcastStatuses
+	opts.Progress = f.progress(cmd, appCtx)
+	return pollBuildStatus(cmd.Context(), stderr, appCtx.Client, opts)
+}
 
-	for {
-		var (
-			resp api.BuildResponse
-			err  error
-		)
-		if waitURL != "" {
-			resp, err = client.WaitBuildByURL(ctx, waitURL, timeoutSeconds)
-		} else {
-			resp, err = client.WaitBuild(ctx, appID, buildID, timeoutSeconds)
-		}
-		if err != nil {
-			return api.BuildResponse{}, err
-		}
+// pollOptions returns the polling settings the flags describe for a build of
+// appID. The --on-change hook reports to stderr, which should be the writer
+// returned by startHeartbeat while a heartbeat runs.
+func (f *waitFlags) pollOptions(ctx context.Context, stderr io.Writer, appID string) pollOptions {
+	opts := pollOptions{
+		AppID:          appID,
+		TimeoutSeconds: f.timeout,
+		Strategy:       f.timeoutStrategy,
+		WaitFor:        f.waitFor,
+		Interval:       buildPollInterval,
+		FailOnTimeout:  f.failOnTimeout,
+		StatusInterval: f.statusInterval,
+	}
+	if f.onChange != "" {
+		opts.OnChange = onChangeHook(stderr, f.onChange, appID)
+	}
+	return opts
+}
 
-		if resp.Build.Status != "processing" {
-			return resp, nil
-		}
+// progress returns the pollOptions.Progress for --json-progress, or nil
+// when it isn't set. The events go to the same output as the final result,
+// which render then writes to the same stream.
+func (f *waitFlags) progress(cmd *cobra.Command, appCtx *AppContext) func(resp api.BuildResponse, elapsed time.Duration) {
+	if !f.jsonProgress {
+		return nil
+	}
+	f.stream = &jsonLineStream{cmd: cmd, opts: appCtx.outputOptions()}
+	return func(resp api.BuildResponse, elapsed time.Duration) {
+		_ = f.stream.write(progressEvent{
+			Type:          "progress",
+			BuildID:       resp.Build.ID,
+			Status:        resp.Build.Status,
+			AppcastStatus: resp.Appcast.Status,
+			ElapsedMs:     elapsed.Milliseconds(),
+		})
+	}
+}
 
-		if !jsonOut {
-			if verbose {
-				VerboseStatus(stderr, "Still processing…", time.Since(pollStart))
-			} else {
-				Status(stderr, "Still processing…")
-			}
-		}
+// render writes the wait's final result: after the progress events when
+// --json-progress is on, like any other result otherwise.
+func (f *waitFlags) render(cmd *cobra.Command, appCtx *AppContext, payload interface{}) error {
+	if f.stream != nil {
+		return f.stream.write(payload)
+	}
+	return renderOutputWithOptions(cmd, appCtx.outputOptions(), payload)
+}
 
-		if !deadline.IsZero() && time.Now().After(deadline) {
-			return resp, nil
+// rejectUnlessWaiting fails if any wait flag was set without --wait.
+func (f *waitFlags) rejectUnlessWaiting(cmd *cobra.Command, wait bool) error {
+	if wait {
+		return nil
+	}
+	for _, name := range f.names() {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s only applies with --wait", name)
 		}
+	}
+	return nil
+}
 
-		// Respect server-guided backoff when the wait endpoint returns 202.
-		nextInterval := interval
-		if resp.PollAfterMs != nil && *resp.PollAfterMs > 0 {
-			nextInterval = time.Duration(*resp.PollAfterMs) * time.Millisecond
-		}
-		if !deadline.IsZero() {
-			remaining := time.Until(deadline)
-			if remaining <= 0 {
-				return resp, nil
-			}
-			if nextInterval > remaining {
-				nextInterval = remaining
+// progressEvent is an interim --json-progress line. The type field sets it
+// apart from the final payload, which has none.
+type progressEvent struct {
+	Type          string          `json:"type"`
+	BuildID       int             `json:"build_id"`
+	Status        api.BuildStatus `json:"status"`
+	AppcastStatus string          `json:"appcast_status,omitempty"`
+	ElapsedMs     int64           `json:"elapsed_ms"`
+}
+
+func newBuildUploadCmd() *cobra.Command {
+	return newBuildUploadCmdWithUse("upload <app-id> <file>", "Upload a build", nil, false)
+}
+
+func newShipCmd() *cobra.Command {
+	// --interactive prompts for both arguments instead.
+	return newBuildUploadCmdWithUse("ship [<app-id> <file>]", "Alias for build upload", nil, true)
+}
+
+// newBuildUploadCmdWithUse builds the upload command. allowInteractive adds
+// the --interactive guided mode.
+func newBuildUploadCmdWithUse(use, short string, aliases []string, allowInteractive bool) *cobra.Command {
+	var (
+		interactive     bool
+		wait            bool
+		waiting         waitFlags
+		manifestPath    string
+		concurrency     int
+		failFast        bool
+		saveState       string
+		checkBundle     bool
+		forceBundle     bool
+		allowIrregular  bool
+		checkBuildNum   string
+		metadata        []string
+		metadataFile    string
+		followRedirects bool
+		summary         bool
+		maxUploadRate   string
+		appIDFromPlist  bool
+		channel         string
+		autoChannel     bool
+		commit          string
+		version         string
+	)
+
+	cmd := &cobra.Command{
+		Use:     use,
+		Short:   short,
+		Aliases: aliases,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if manifestPath != "" || interactive {
+				return cobra.NoArgs(cmd, args)
+			}
+			if appIDFromPlist {
+				return cobra.RangeArgs(1, 2)(cmd, args)
+			}
+			return cobra.ExactArgs(2)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := waiting.rejectUnlessWaiting(cmd, wait); err != nil {
+				return err
+			}
+			if err := waiting.validate(cmd); err != nil {
+				return err
+			}
+			if err := validateBuildNumberCheck(checkBuildNum); err != nil {
+				return err
+			}
+
+			appCtx, err := getAppContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			if saveState != "" && wait {
+				return errors.New("--save-state can't be combined with --wait: the build isn't completed until `build complete` runs")
+			}
+
+			metadataMap, err := keyValuesFromFlags("metadata", metadataFile, metadata)
+			if err != nil {
+				return err
+			}
+			uploadRate, err := parseByteRate(maxUploadRate)
+			if err != nil {
+				return fmt.Errorf("invalid --max-upload-rate: %w", err)
+			}
+
+			req := uploadRequest{
+				Metadata:               metadataMap,
+				Channel:                channel,
+				AutoChannel:            autoChannel,
+				Commit:                 strings.TrimSpace(commit),
+				Version:                version,
+				Wait:                   wait,
+				Waiting:                waiting,
+				Progress:               waiting.progress(cmd, appCtx),
+				SaveState:              saveState,
+				CheckBundle:            checkBundle,
+				ForceBundle:            forceBundle,
+				FollowRedirects:        followRedirects,
+				MaxUploadRate:          uploadRate,
+				AllowIrregular:         allowIrregular,
+				CheckBuildNumber:       checkBuildNum,
+				PendingAppcastStatuses: appCtx.PendingAppcastStatuses,
+			}
+
+			if failFast && manifestPath == "" {
+				return errors.New("--fail-fast only applies with --manifest")
+			}
+			if manifestPath != "" {
+				if saveState != "" {
+					return errors.New("--save-state can't be used with --manifest")
+				}
+				if concurrency < 1 {
+					return errors.New("concurrency must be >= 1")
+				}
+				if waiting.jsonProgress {
+					return errors.New("--json-progress can't be used with --manifest")
+				}
+				if version != "" {
+					return errors.New("--version can't be used with --manifest: set version on each entry")
+				}
+				return runManifestShip(cmd, appCtx, manifestPath, concurrency, failFast, req)
+			}
+
+			if interactive {
+				if req, err = promptUploadRequest(cmd, appCtx.Client, req); err != nil {
+					return err
+				}
+			} else {
+				if len(args) == 2 {
+					req.AppID = args[0]
+				}
+				req.FilePath = args[len(args)-1]
+				if err := validateUploadFile(req.FilePath, req.AllowIrregular); err != nil {
+					return err
+				}
+				// An explicit app ID wins over the archive's bundle ID.
+				if req.AppID == "" {
+					if req.AppID, err = appIDFromArchive(req.FilePath); err != nil {
+						return err
+					}
+				}
+			}
+
+			stderr := cmd.ErrOrStderr()
+			totalStart := time.Now()
+			jsonOut := appCtx.JSON
+
+			result, err := runUpload(cmd.Context(), stderr, appCtx.Client, req, appCtx.Verbosity, jsonOut)
+			if err != nil {
+				return err
+			}
+
+			if err := waiting.render(cmd, appCtx, result.payload()); err != nil {
+				return err
+			}
+			if !jsonOut {
+				if summary {
+					printUploadSummary(stderr, result, time.Since(totalStart))
+				} else {
+					Done(stderr, time.Since(totalStart))
+				}
+			}
+			return appcastExitError(cmd, result.payload())
+		},
+	}
+
+	cmd.Flags().BoolVar(&wait, "wait", false, "Wait for processing to complete")
+	waiting.register(cmd)
+	cmd.Flags().StringVar(&manifestPath, "manifest", "", "Upload every build listed in a YAML manifest")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 4, "Maximum concurrent uploads when using --manifest")
+	cmd.Flags().BoolVar(&failFast, "fail-fast", false, "With --manifest, cancel the remaining uploads as soon as one fails")
+	cmd.Flags().BoolVar(&summary, "summary", true, "Finish with a summary of the build, upload size and timing (text output only)")
+	cmd.Flags().StringArrayVar(&metadata, "metadata", nil, "Attach custom metadata to the build as key=value (repeatable)")
+	cmd.Flags().StringVar(&metadataFile, "metadata-file", "", "Read metadata from a key=value or JSON file; --metadata values override it")
+	cmd.Flags().StringVar(&maxUploadRate, "max-upload-rate", "", "Cap the file upload speed in bytes per second (e.g. 500KB, 2MB)")
+	cmd.Flags().BoolVar(&followRedirects, "follow-redirects", true, "Follow storage redirects on the file upload (--follow-redirects=false fails instead)")
+	cmd.Flags().StringVar(&commit, "commit", "", "Record this commit SHA in the build metadata and skip the upload if a build that hasn't failed already exists for it")
+	cmd.Flags().StringVar(&version, "version", "", "Version to record for the build")
+	cmd.Flags().StringVar(&channel, "channel", "", "Release channel for the build (e.g. beta)")
+	cmd.Flags().BoolVar(&autoChannel, "auto-channel", false, "Read the release channel from the archive's Info.plist "+bundle.ChannelKey+" key; --channel overrides it")
+	cmd.Flags().BoolVar(&appIDFromPlist, "app-id-from-plist", false, "Use the archive's bundle identifier as the app ID when <app-id> is omitted")
+	cmd.Flags().BoolVar(&allowIrregular, "allow-irregular", false, "Upload the file even if it is a symlink, device or other non-regular file")
+	cmd.Flags().StringVar(&checkBuildNum, "check-build-number", "", "Before uploading, compare the archive's CFBundleVersion with the app's latest build: \"warn\" or \"fail\" if it isn't greater")
+	cmd.Flags().BoolVar(&checkBundle, "check-bundle", false, "Before uploading, compare the bundle ID in the archive's Info.plist with the app's and stop on a mismatch")
+	cmd.Flags().BoolVar(&forceBundle, "force-bundle", false, "With --check-bundle, upload even if the archive's bundle ID doesn't match the app")
+	cmd.Flags().StringVar(&saveState, "save-state", "", "Upload the file but skip completion, saving state to this file for `build complete --from-state`")
+	if allowInteractive {
+		cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Prompt for the app, file and version, then confirm before uploading")
+		cmd.MarkFlagsMutuallyExclusive("interactive", "manifest")
+	}
+
+	_ = cmd.MarkFlagFilename("file")
+	_ = cmd.MarkFlagFilename("manifest", "yaml", "yml")
+	_ = cmd.MarkFlagFilename("save-state", "json")
+
+	return cmd
+}
+
+// buildRefFlags lets commands that take <app-id> <build-id> accept them as
+// --app-id/--build-id instead. The two forms can't be mixed.
+type buildRefFlags struct {
+	appID   string
+	buildID string
+}
+
+func (f *buildRefFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.appID, "app-id", "", "App ID (instead of the positional argument)")
+	cmd.Flags().StringVar(&f.buildID, "build-id", "", "Build ID (instead of the positional argument)")
+}
+
+func (f *buildRefFlags) validateArgs(cmd *cobra.Command, args []string) error {
+	if f.appID == "" && f.buildID == "" {
+		return cobra.ExactArgs(2)(cmd, args)
+	}
+	if len(args) > 0 {
+		return errors.New("pass the app and build IDs either as arguments or as --app-id/--build-id, not both")
+	}
+	if f.appID == "" || f.buildID == "" {
+		return errors.New("--app-id and --build-id must be used together")
+	}
+	return nil
+}
+
+// resolve returns the app and build IDs after validateArgs has accepted args.
+func (f *buildRefFlags) resolve(args []string) (string, string) {
+	if len(args) == 2 {
+		return args[0], args[1]
+	}
+	return f.appID, f.buildID
+}
+
+// uploadRequest describes a single build upload.
+type uploadRequest struct {
+	AppID    string
+	FilePath string
+	Version  string
+	Metadata map[string]string
+	Wait     bool
+	// Waiting holds the wait flags: timeout, strategy, hooks and heartbeat.
+	Waiting waitFlags
+	// Progress, when set, receives each interim status while waiting.
+	Progress func(resp api.BuildResponse, elapsed time.Duration)
+	// PendingAppcastStatuses are extra appcast states to keep waiting in.
+	PendingAppcastStatuses []string
+	// FollowRedirects lets the file PUT follow storage redirects.
+	FollowRedirects bool
+	// MaxUploadRate caps the file PUT in bytes per second; zero is unlimited.
+	MaxUploadRate int64
+	// CheckBundle compares the archive's bundle ID with the app's before
+	// uploading.
+	CheckBundle bool
+	// ForceBundle uploads even when the archive's bundle ID doesn't match
+	// the app's.
+	ForceBundle bool
+	// SaveState, when set, stops after the file upload and records the
+	// pending upload there for `build complete --from-state`.
+	SaveState string
+	// Channel is the release channel to upload to; empty leaves it to the
+	// server.
+	Channel string
+	// AutoChannel reads the channel from the archive's Info.plist when
+	// Channel is empty.
+	AutoChannel bool
+	// Commit is recorded as the commit metadata value; an existing build
+	// for it makes the upload a no-op.
+	Commit string
+	// AllowIrregular accepts symlinks and other non-regular files.
+	AllowIrregular bool
+	// CheckBuildNumber compares the archive's build number with the app's
+	// latest build: "warn", "fail", or empty to skip the check.
+	CheckBuildNumber string
+	// Size is the number of bytes uploaded; a different build size reported
+	// by the server fails the upload. Zero skips the check.
+	Size int64
+}
+
+// uploadResult holds the outcome of runUpload. Build is only set when the
+// upload waited for processing; State is only set when the upload was saved
+// for completion later.
+type uploadResult struct {
+	Complete      api.BuildUploadCompleteResponse
+	Build         *api.BuildResponse
+	State         *uploadState
+	BytesUploaded int64
+	// Skipped is set when a build already existed for Commit; Build then
+	// holds it and nothing was uploaded.
+	Skipped bool
+	Commit  string
+}
+
+// payload returns the value to render for this result.
+func (r uploadResult) payload() interface{} {
+	switch {
+	case r.State != nil:
+		return *r.State
+	case r.Build != nil:
+		return *r.Build
+	default:
+		return r.Complete
+	}
+}
+
+// parseByteRate parses a byte count such as "500KB", "2MB" or "1048576",
+// optionally suffixed with "/s". Units are powers of 1024, matching how sizes
+// are printed. An empty value means no limit.
+func parseByteRate(raw string) (int64, error) {
+	value := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(raw)), "/S")
+	if value == "" {
+		return 0, nil
+	}
+
+	multipliers := []struct {
+		suffix string
+		factor float64
+	}{
+		{"GIB", 1 << 30}, {"GB", 1 << 30}, {"G", 1 << 30},
+		{"MIB", 1 << 20}, {"MB", 1 << 20}, {"M", 1 << 20},
+		{"KIB", 1 << 10}, {"KB", 1 << 10}, {"K", 1 << 10},
+		{"B", 1},
+	}
+	number, factor := value, 1.0
+	for _, m := range multipliers {
+		if strings.HasSuffix(value, m.suffix) {
+			number, factor = strings.TrimSpace(strings.TrimSuffix(value, m.suffix)), m.factor
+			break
+		}
+	}
+
+	amount, err := strconv.ParseFloat(number, 64)
+	if err != nil || amount <= 0 {
+		return 0, fmt.Errorf("%q is not a positive byte count (e.g. 500KB, 2MB)", raw)
+	}
+	rate := int64(amount * factor)
+	if rate < 1 {
+		return 0, fmt.Errorf("%q is less than 1 byte per second", raw)
+	}
+	return rate, nil
+}
+
+// validateUploadFile checks that filePath is a zip archive that can be
+// uploaded. Unless allowIrregular is set it must be a regular file: a
+// symlink in a CI workspace could otherwise point the upload at anything
+// the runner can read.
+func validateUploadFile(filePath string, allowIrregular bool) error {
+	if strings.TrimSpace(filePath) == "" {
+		return errors.New("file path is required")
+	}
+	if allowIrregular {
+		if _, err := os.Stat(filePath); err != nil {
+			return fmt.Errorf("file not accessible: %w", err)
+		}
+	} else {
+		info, err := os.Lstat(filePath)
+		if err != nil {
+			return fmt.Errorf("file not accessible: %w", err)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("%s is not a regular file (%s); pass --allow-irregular to upload it anyway", filePath, fileKind(info.Mode()))
+		}
+		if err := checkParentDirs(filePath); err != nil {
+			return err
+		}
+	}
+	if strings.ToLower(filepath.Ext(filePath)) != ".zip" {
+		return errors.New("only .zip archives are supported")
+	}
+	return nil
+}
+
+// openUploadFile opens filePath for the upload itself and returns it with
+// its stat. Unless allowIrregular is set, the opened file must be the regular
+// file validateUploadFile accepted: the path is checked again against the
+// open handle, so a symlink swapped in afterwards is caught. The upload then
+// reads only from the handle.
+func openUploadFile(filePath string, allowIrregular bool) (*os.File, os.FileInfo, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("file not accessible: %w", err)
+	}
+	info, err := file.Stat()
+	if err == nil && !allowIrregular {
+		err = checkOpenedFile(filePath, info)
+	}
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return file, info, nil
+}
+
+// checkOpenedFile verifies that filePath still names opened, a regular file
+// reached without symlinks.
+func checkOpenedFile(filePath string, opened os.FileInfo) error {
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return fmt.Errorf("file not accessible: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file (%s); pass --allow-irregular to upload it anyway", filePath, fileKind(info.Mode()))
+	}
+	if !os.SameFile(info, opened) {
+		return fmt.Errorf("%s changed while it was being opened", filePath)
+	}
+	return checkParentDirs(filePath)
+}
+
+// checkParentDirs rejects a file reached through a symlinked directory below
+// the working directory, where a checked-out repository could plant one.
+// Directories above it, like /var on macOS, belong to the system.
+func checkParentDirs(filePath string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil
+	}
+	rel, err := filepath.Rel(wd, filepath.Dir(abs))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+	dir := wd
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if err != nil {
+			return fmt.Errorf("file not accessible: %w", err)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is inside %s, which is a symlink; pass --allow-irregular to upload it anyway", filePath, dir)
+		}
+	}
+	return nil
+}
+
+// zipContentTypeAliases are media types some platforms report for zip
+// archives; Windows' registry, for one, maps .zip to
+// application/x-zip-compressed.
+var zipContentTypeAliases = map[string]bool{
+	"application/x-zip-compressed": true,
+	"application/x-zip":            true,
+	"application/zip-compressed":   true,
+	"multipart/x-zip":              true,
+}
+
+// uploadContentType returns the content type to declare for filePath. Zip
+// aliases from the system MIME table are normalized to application/zip, the
+// only type the server accepts for archives.
+func uploadContentType(filePath string) string {
+	return normalizeZipContentType(mime.TypeByExtension(strings.ToLower(filepath.Ext(filePath))))
+}
+
+func normalizeZipContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "application/zip" || zipContentTypeAliases[mediaType] {
+		return "application/zip"
+	}
+	return mediaType
+}
+
+// fileKind names the type of a non-regular file for error messages.
+func fileKind(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeSymlink != 0:
+		return "symlink"
+	case mode.IsDir():
+		return "directory"
+	case mode&os.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeDevice != 0:
+		return "device"
+	default:
+		return "irregular file"
+	}
+}
+
+// appIDFromArchive reads the bundle identifier from the archive's Info.plist
+// for --app-id-from-plist.
+func appIDFromArchive(zipPath string) (string, error) {
+	id, err := bundle.BundleIdentifier(zipPath)
+	if err != nil {
+		return "", fmt.Errorf("--app-id-from-plist: can't read the bundle identifier from %s: %w", filepath.Base(zipPath), err)
+	}
+	return id, nil
+}
+
+// uploadChannel picks the release channel for req: the explicit channel, or
+// with AutoChannel the archive's Info.plist value.
+func uploadChannel(req uploadRequest) (string, error) {
+	if req.Channel != "" || !req.AutoChannel {
+		return req.Channel, nil
+	}
+	info, err := bundle.ReadInfo(req.FilePath)
+	if err != nil {
+		return "", fmt.Errorf("--auto-channel: can't read Info.plist from %s: %w", filepath.Base(req.FilePath), err)
+	}
+	if info.Channel == "" {
+		return "", fmt.Errorf("--auto-channel: Info.plist in %s has no %s key (pass --channel instead)", filepath.Base(req.FilePath), bundle.ChannelKey)
+	}
+	return info.Channel, nil
+}
+
+// checkBundleID refuses to upload an archive whose bundle identifier differs
+// from the target app's, unless req.ForceBundle is set. It only runs with
+// req.CheckBundle, and is skipped when either identifier can't be
+// determined.
+func checkBundleID(ctx context.Context, stderr io.Writer, client *api.Client, req uploadRequest, verbose, jsonOut bool) error {
+	if !req.CheckBundle {
+		return nil
+	}
+	archiveID, err := bundle.BundleIdentifier(req.FilePath)
+	if err != nil {
+		if verbose && !jsonOut {
+			Statusf(stderr, "Skipping bundle ID check: %v", err)
+		}
+		return nil
+	}
+
+	app, err := client.GetApp(ctx, req.AppID)
+	if err != nil {
+		if verbose && !jsonOut {
+			Statusf(stderr, "Skipping bundle ID check: %v", err)
+		}
+		return nil
+	}
+	if app.BundleID == "" || app.BundleID == archiveID {
+		return nil
+	}
+
+	if req.ForceBundle {
+		if !jsonOut {
+			Statusf(stderr, "Uploading %s to app %s (bundle ID %s) because of --force-bundle", archiveID, req.AppID, app.BundleID)
+		}
+		return nil
+	}
+	return fmt.Errorf("bundle ID mismatch: %s contains %s but app %s expects %s (pass --force-bundle to upload anyway)", filepath.Base(req.FilePath), archiveID, req.AppID, app.BundleID)
+}
+
+// timeNow is the clock for relative time filters, upload URL expiry and
+// throttling wait status lines. Tests override it.
+var timeNow = time.Now
+
+// parseTimeFilter reads an RFC3339 timestamp or a duration relative to now
+// ("90m", "24h", "7d"). An empty value means no filter.
+func parseTimeFilter(flag, value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	var (
+		d   time.Duration
+		err error
+	)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(value)
+	}
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid %s %q: use RFC3339 (2024-05-01T00:00:00Z) or a relative duration like 24h or 7d", flag, value)
+	}
+	return now.Add(-d), nil
+}
+
+// Exit codes for --status-only.
+const (
+	exitStatusFailed  = 2
+	exitStatusPending = 3
+)
+
+func addStatusOnlyFlag(cmd *cobra.Command, statusOnly *bool) {
+	cmd.Flags().BoolVar(statusOnly, "status-only", false, fmt.Sprintf("Print nothing; exit 0 if available, %d if failed, %d if still processing", exitStatusFailed, exitStatusPending))
+}
+
+func validateStatusOnly(statusOnly bool, appCtx *AppContext) error {
+	if statusOnly && appCtx.JSON {
+		return errors.New("--status-only can't be combined with --json")
+	}
+	return nil
+}
+
+// statusExitError maps the build status to the --status-only exit code,
+// returning nil for a build that's available. An available build whose
+// appcast failed counts as failed.
+func statusExitError(cmd *cobra.Command, resp api.BuildResponse) error {
+	code := exitStatusPending
+	switch {
+	case resp.Build.IsFailed(), resp.Build.IsAvailable() && resp.Appcast.IsFailed():
+		code = exitStatusFailed
+	case resp.Build.IsAvailable():
+		return nil
+	}
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return &ExitError{Code: code}
+}
+
+// appcastExitError fails the command, after its output has been rendered,
+// when payload is a build whose appcast failed: the build is usable but
+// won't reach users.
+func appcastExitError(cmd *cobra.Command, payload interface{}) error {
+	resp, ok := payload.(api.BuildResponse)
+	if !ok || !resp.Appcast.IsFailed() {
+		return nil
+	}
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return &ExitError{Code: 1}
+}
+
+func addTimeoutFlag(cmd *cobra.Command, seconds *int) {
+	cmd.Flags().Var((*timeoutValue)(seconds), "timeout", "Wait timeout as seconds or a duration such as 2m30s (max 300s)")
+}
+
+// timeoutValue is a --timeout flag holding whole seconds. It accepts bare
+// seconds, as it always has, or a Go duration string.
+type timeoutValue int
+
+func (v *timeoutValue) String() string { return strconv.Itoa(int(*v)) }
+
+func (v *timeoutValue) Set(raw string) error {
+	seconds, err := parseTimeout(raw)
+	if err != nil {
+		return err
+	}
+	*v = timeoutValue(seconds)
+	return nil
+}
+
+func (v *timeoutValue) Type() string { return "duration" }
+
+// parseTimeout converts "120" or "2m" to seconds. Durations are rounded up to
+// the next whole second; the 300 second cap is checked by validateTimeout.
+func parseTimeout(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if seconds, err := strconv.Atoi(raw); err == nil {
+		return seconds, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: use seconds (120) or a duration (2m30s)", raw)
+	}
+	seconds := d / time.Second
+	if d%time.Second > 0 {
+		seconds++
+	}
+	return int(seconds), nil
+}
+
+func addTimeoutStrategyFlag(cmd *cobra.Command, strategy *string) {
+	cmd.Flags().StringVar(strategy, "timeout-strategy", timeoutStrategyLongPoll, "How to wait for processing: longpoll (server wait endpoint) or poll (client-side status checks)")
+}
+
+func validateTimeoutStrategy(strategy string) error {
+	switch strategy {
+	case timeoutStrategyLongPoll, timeoutStrategyPoll:
+		return nil
+	default:
+		return fmt.Errorf("timeout strategy must be %q or %q", timeoutStrategyLongPoll, timeoutStrategyPoll)
+	}
+}
+
+// knownBuildStatuses lists the build statuses --wait-for accepts, in the
+// order a build moves through them.
+var knownBuildStatuses = []string{
+	string(api.BuildStatusQueued),
+	string(api.BuildStatusProcessing),
+	string(api.BuildStatusNotarizing),
+	string(api.BuildStatusAvailable),
+	string(api.BuildStatusFailed),
+}
+
+func validateWaitFor(status string) error {
+	if status == "" {
+		return nil
+	}
+	for _, known := range knownBuildStatuses {
+		if status == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown status %q: must be one of %s", status, strings.Join(knownBuildStatuses, ", "))
+}
+
+func validateTimeout(timeout int) error {
+	if timeout < 0 {
+		return errors.New("timeout must be >= 0")
+	}
+	if timeout > 300 {
+		return errors.New("timeout must be <= 300 seconds (5m)")
+	}
+	return nil
+}
+
+// runUpload performs the prepare, upload, complete and optional wait steps
+// for a single build, reporting progress to stderr unless jsonOut is set.
+// verbosity is the -v count: 1 adds step timings, 2 adds transfer rates.
+func runUpload(ctx context.Context, stderr io.Writer, client *api.Client, req uploadRequest, verbosity int, jsonOut bool) (uploadResult, error) {
+	verbose := verbosity > 0
+	if req.Commit != "" {
+		existing, found, err := findBuildForCommit(ctx, client, req.AppID, req.Commit)
+		if err != nil {
+			return uploadResult{}, err
+		}
+		if found {
+			if !jsonOut {
+				Statusf(stderr, "Build %d already exists for commit %s (%s), skipping upload", existing.Build.ID, req.Commit, existing.Build.Status)
+			}
+			return uploadResult{Build: &existing, Skipped: true, Commit: req.Commit}, nil
+		}
+		req.Metadata = withKeyValue(req.Metadata, commitMetadataKey, req.Commit)
+	}
+
+	if err := checkBundleID(ctx, stderr, client, req, verbose, jsonOut); err != nil {
+		return uploadResult{}, err
+	}
+	if err := checkBuildNumber(ctx, stderr, client, req, verbose, jsonOut); err != nil {
+		return uploadResult{}, err
+	}
+
+	// Step 1: Prepare upload
+	stepStart := time.Now()
+	if !jsonOut {
+		Statusf(stderr, "Preparing upload for %s…", filepath.Base(req.FilePath))
+	}
+
+	channel, err := uploadChannel(req)
+	if err != nil {
+		return uploadResult{}, err
+	}
+	file, info, err := openUploadFile(req.FilePath, req.AllowIrregular)
+	if err != nil {
+		return uploadResult{}, err
+	}
+	defer file.Close()
+	resolvedContentType := uploadContentType(req.FilePath)
+	params := api.BuildUploadParams{
+		ContentType: resolvedContentType,
+		Version:     req.Version,
+		Channel:     channel,
+		Metadata:    req.Metadata,
+		Size:        info.Size(),
+	}
+
+	createResp, err := client.CreateUpload(ctx, req.AppID, params)
+	if err != nil {
+		var apiErr *api.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusRequestEntityTooLarge {
+			return uploadResult{}, fmt.Errorf("%s (%s) is larger than the server accepts: %w", filepath.Base(req.FilePath), formatBytes(int(info.Size())), err)
+		}
+		return uploadResult{}, err
+	}
+	if verbose && !jsonOut {
+		VerboseStatus(stderr, "Prepared upload", time.Since(stepStart))
+	}
+
+	// Step 2: Upload file
+	stepStart = time.Now()
+	if !jsonOut {
+		Statusf(stderr, "Uploading to edge network…")
+	}
+
+	uploadOpts := []api.UploadOption{api.WithFollowRedirects(req.FollowRedirects), api.WithMaxUploadRate(req.MaxUploadRate)}
+	if !jsonOut {
+		uploadOpts = append(uploadOpts, api.WithUploadRetryObserver(func(retry api.UploadRetry) {
+			Statusf(stderr, "Uploading to edge network… (retry %d in %s)", retry.Attempt, retry.Delay)
+		}))
+	}
+	if createResp.ExpiresWithin(timeNow(), uploadURLExpiryMargin) {
+		if createResp, err = reissueUpload(ctx, stderr, client, req.AppID, params, createResp, jsonOut); err != nil {
+			return uploadResult{}, err
+		}
+	}
+	err = client.UploadOpenFile(ctx, createResp.UploadURL, file, resolvedContentType, uploadOpts...)
+	if err != nil && ctx.Err() == nil && createResp.ExpiresWithin(timeNow(), 0) {
+		// The URL ran out while the file was being sent; one more try with
+		// a fresh one.
+		if createResp, err = reissueUpload(ctx, stderr, client, req.AppID, params, createResp, jsonOut); err == nil {
+			err = client.UploadOpenFile(ctx, createResp.UploadURL, file, resolvedContentType, uploadOpts...)
+		}
+	}
+	if err != nil {
+		return uploadResult{}, err
+	}
+	var uploaded int64
+	if info, err := file.Stat(); err == nil {
+		uploaded = info.Size()
+	}
+	if verbose && !jsonOut {
+		elapsed := time.Since(stepStart)
+		msg := "Uploaded"
+		if verbosity >= 2 && elapsed > 0 {
+			msg = fmt.Sprintf("Uploaded %s (%s/s)", formatBytes(int(uploaded)), formatBytes(int(float64(uploaded)/elapsed.Seconds())))
+		}
+		VerboseStatus(stderr, msg, elapsed)
+	}
+
+	buildID := createResp.BuildID.Int()
+	if req.SaveState != "" {
+		state := uploadState{AppID: req.AppID, File: req.FilePath, Upload: createResp, Size: uploaded}
+		if err := saveUploadState(req.SaveState, state); err != nil {
+			return uploadResult{}, err
+		}
+		if !jsonOut {
+			Statusf(stderr, "Saved upload state to %s", req.SaveState)
+		}
+		return uploadResult{State: &state, BytesUploaded: uploaded}, nil
+	}
+
+	req.Size = uploaded
+	result, err := finishUpload(ctx, stderr, client, req, buildID, verbose, jsonOut)
+	if err != nil {
+		return uploadResult{}, err
+	}
+	result.BytesUploaded = uploaded
+	return result, nil
+}
+
+// commitMetadataKey is the metadata key --commit records the SHA under.
+const commitMetadataKey = "commit"
+
+// findBuildForCommit looks up a build of appID uploaded for commit that
+// hasn't failed, fetching it in full so it renders like any other build.
+func findBuildForCommit(ctx context.Context, client *api.Client, appID, commit string) (api.BuildResponse, bool, error) {
+	build, found, err := client.FindBuildByMetadata(ctx, appID, commitMetadataKey, commit)
+	if err != nil {
+		return api.BuildResponse{}, false, fmt.Errorf("look up builds for commit %s: %w", commit, err)
+	}
+	if !found {
+		return api.BuildResponse{}, false, nil
+	}
+	resp, err := client.GetBuild(ctx, appID, strconv.Itoa(build.ID))
+	if err != nil {
+		return api.BuildResponse{}, false, fmt.Errorf("get build %d: %w", build.ID, err)
+	}
+	return resp, true, nil
+}
+
+// uploadURLExpiryMargin is how close to expiry a presigned upload URL may be
+// before it's replaced rather than used.
+const uploadURLExpiryMargin = 30 * time.Second
+
+// reissueUpload asks for a new upload URL after stale's expired. The new
+// upload is a separate build, so stale's pending build is deleted rather
+// than left behind; failing to delete it only warns.
+func reissueUpload(ctx context.Context, stderr io.Writer, client *api.Client, appID string, params api.BuildUploadParams, stale api.BuildUploadResponse, jsonOut bool) (api.BuildUploadResponse, error) {
+	if !jsonOut {
+		Status(stderr, "Upload URL expired, requesting a new one…")
+	}
+	resp, err := client.CreateUpload(ctx, appID, params)
+	if err != nil {
+		return api.BuildUploadResponse{}, fmt.Errorf("re-issue expired upload: %w", err)
+	}
+	if err := client.DeleteBuild(ctx, appID, strconv.Itoa(stale.BuildID.Int())); err != nil {
+		Statusf(stderr, "Warning: couldn't delete expired upload for build %d: %v", stale.BuildID.Int(), err)
+	}
+	return resp, nil
+}
+
+// printUploadSummary writes an aligned footer describing a finished upload.
+func printUploadSummary(w io.Writer, result uploadResult, elapsed time.Duration) {
+	rows := [][2]string{}
+	switch {
+	case result.Build != nil:
+		rows = append(rows,
+			[2]string{"Build", fmt.Sprintf("%d", result.Build.Build.ID)},
+			[2]string{"Status", string(result.Build.Build.Status)},
+		)
+	case result.State != nil:
+		rows = append(rows,
+			[2]string{"Build", fmt.Sprintf("%d", result.State.Upload.BuildID.Int())},
+			[2]string{"Status", "uploaded (not completed)"},
+		)
+	default:
+		rows = append(rows,
+			[2]string{"Build", fmt.Sprintf("%d", result.Complete.BuildID.Int())},
+			[2]string{"Status", result.Complete.UploadState},
+		)
+	}
+	if result.Skipped {
+		rows = append(rows, [2]string{"Upload", fmt.Sprintf("skipped, build %d already exists for commit %s", result.Build.Build.ID, result.Commit)})
+	} else {
+		rows = append(rows, [2]string{"Uploaded", formatBytes(int(result.BytesUploaded))})
+	}
+	if result.Build != nil && result.Build.Appcast.FeedURL != "" {
+		rows = append(rows, [2]string{"Feed", result.Build.Appcast.FeedURL})
+	}
+	rows = append(rows, [2]string{"Time", fmt.Sprintf("%.1fs", elapsed.Seconds())})
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintf(tw, "  %s\t%s\n", row[0], row[1])
+	}
+	_ = tw.Flush()
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		fmt.Fprintln(w, consoleFor(w).dim.Render(line))
+	}
+}
+
+// finishUpload completes an uploaded build and, when req.Wait is set, waits
+// for processing.
+func finishUpload(ctx context.Context, stderr io.Writer, client *api.Client, req uploadRequest, buildID int, verbose, jsonOut bool) (uploadResult, error) {
+	// Step 3: Complete upload
+	stepStart := time.Now()
+	if !jsonOut {
+		Status(stderr, "Finalizing upload…")
+	}
+
+	completeResp, err := client.CompleteUpload(ctx, req.AppID, buildID)
+	if err != nil {
+		return uploadResult{}, err
+	}
+	if err := checkBuildSize(completeResp.Metadata, req.Size); err != nil {
+		return uploadResult{}, err
+	}
+	if verbose && !jsonOut {
+		VerboseStatus(stderr, "Finalized", time.Since(stepStart))
+	}
+
+	result := uploadResult{Complete: completeResp}
+	if !req.Wait {
+		return result, nil
+	}
+
+	// Step 4: Wait for processing
+	stepStart = time.Now()
+	if !jsonOut {
+		Status(stderr, "Processing build…")
+	}
+
+	waitOut, stopHeartbeat := startHeartbeat(stderr, req.Waiting.heartbeat)
+	opts := req.Waiting.pollOptions(ctx, waitOut, req.AppID)
+	opts.BuildID = fmt.Sprintf("%d", buildID)
+	opts.WaitURL = completeResp.WaitURL
+	opts.StatusURL = completeResp.StatusURL
+	opts.Verbose = verbose
+	opts.JSON = jsonOut
+	opts.Progress = req.Progress
+	opts.PendingAppcastStatuses = req.PendingAppcastStatuses
+	waitResp, err := pollBuildStatus(ctx, waitOut, client, opts)
+	stopHeartbeat()
+	if err != nil {
+		return uploadResult{}, err
+	}
+	if err := checkBuildSize(waitResp.Build.Metadata, req.Size); err != nil {
+		return uploadResult{}, err
+	}
+	if verbose && !jsonOut {
+		VerboseStatus(stderr, "Processing complete", time.Since(stepStart))
+	}
+
+	result.Build = &waitResp
+	return result, nil
+}
+
+// checkBuildSize compares the build size the server reports with the number
+// of bytes uploaded. A size of zero, or no size from the server, passes.
+func checkBuildSize(meta *api.BuildMetadata, size int64) error {
+	if size <= 0 || meta == nil || meta.BuildSize == nil {
+		return nil
+	}
+	if stored := int64(*meta.BuildSize); stored != size {
+		return fmt.Errorf("complete upload: %w: server reports a %d-byte build, uploaded %d bytes", api.ErrUploadSizeMismatch, stored, size)
+	}
+	return nil
+}
+
+const (
+	timeoutStrategyLongPoll = "longpoll"
+	timeoutStrategyPoll     = "poll"
+
+	// pollRequestTimeout caps each status request under the poll strategy so
+	// a stalled request can't eat the whole wait budget.
+	pollRequestTimeout = 15 * time.Second
+)
+
+// pollOptions configures pollBuildStatus. WaitURL and StatusURL, when set,
+// take precedence over AppID/BuildID for the longpoll and poll strategies
+// respectively.
+type pollOptions struct {
+	AppID          string
+	BuildID        string
+	WaitURL        string
+	StatusURL      string
+	TimeoutSeconds int
+	Strategy       string
+	// WaitFor stops polling early once the build reaches this status.
+	WaitFor  string
+	Interval time.Duration
+	Verbose  bool
+	JSON     bool
+	// FailOnTimeout turns a deadline reached while the build is still
+	// pending into an error instead of returning the last response.
+	FailOnTimeout bool
+	// StatusInterval, when set, prints the "Still …" line at most this
+	// often; a changed status is always printed.
+	StatusInterval time.Duration
+	// OnChange, when set, is called with the first status seen and each
+	// time it changes afterwards. Its context ends at the wait's deadline.
+	OnChange func(ctx context.Context, previous api.BuildStatus, resp api.BuildResponse)
+	// Progress, when set, is called with every response that doesn't end
+	// the wait, whatever the output mode.
+	Progress func(resp api.BuildResponse, elapsed time.Duration)
+	// PendingAppcastStatuses are appcast states that keep the wait going in
+	// addition to the ones the API knows.
+	PendingAppcastStatuses []string
+}
+
+func pollBuildStatus(ctx context.Context, stderr io.Writer, client *api.Client, opts pollOptions) (api.BuildResponse, error) {
+	deadline := time.Time{}
+	// hookCtx ends with the wait, so a slow OnChange can't outlast it.
+	hookCtx := ctx
+	if opts.TimeoutSeconds > 0 {
+		deadline = time.Now().Add(time.Duration(opts.TimeoutSeconds) * time.Second)
+		var cancel context.CancelFunc
+		hookCtx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	pollStart := time.Now()
+	var (
+		lastMsg     string
+		lastPrinted time.Time
+		lastStatus  api.BuildStatus
+	)
+
+	for {
+		resp, err := fetchBuildStatus(ctx, client, opts)
+		if errors.Is(err, api.ErrWaitStalled) && ctx.Err() == nil && (deadline.IsZero() || time.Now().Before(deadline)) {
+			// The connection went quiet; a fresh request usually gets through.
+			if !opts.JSON {
+				Status(stderr, "Wait request stalled, retrying…")
+			}
+			continue
+		}
+		if delay, ok := rateLimitDelay(err, opts.Interval); ok && ctx.Err() == nil && (deadline.IsZero() || time.Now().Add(delay).Before(deadline)) {
+			// Being throttled says nothing about the build; keep waiting.
+			if !opts.JSON {
+				Statusf(stderr, "Rate limited, checking again in %s…", delay)
+			}
+			select {
+			case <-ctx.Done():
+				return api.BuildResponse{}, ctx.Err()
+			case <-time.After(delay):
+			}
+			continue
+		}
+		if err != nil {
+			return api.BuildResponse{}, err
+		}
+
+		if resp.Build.Status != lastStatus {
+			if opts.OnChange != nil {
+				opts.OnChange(hookCtx, lastStatus, resp)
+			}
+			lastStatus = resp.Build.Status
+		}
+
+		if pollDone(resp, opts) {
+			return resp, nil
+		}
+		if opts.Progress != nil {
+			opts.Progress(resp, time.Since(pollStart))
+		}
+
+		if msg := pollProgress(resp, opts); !opts.JSON && (msg != lastMsg || opts.StatusInterval <= 0 || timeNow().Sub(lastPrinted) >= opts.StatusInterval) {
+			if opts.Verbose {
+				VerboseStatus(stderr, msg, time.Since(pollStart))
+			} else {
+				Status(stderr, msg)
+			}
+			lastMsg, lastPrinted = msg, timeNow()
+		}
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return waitTimedOut(resp, opts)
+		}
+
+		// Respect server-guided backoff when the wait endpoint returns 202.
+		nextInterval := opts.Interval
+		if resp.PollAfterMs != nil && *resp.PollAfterMs > 0 {
+			nextInterval = time.Duration(*resp.PollAfterMs) * time.Millisecond
+		}
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return waitTimedOut(resp, opts)
+			}
+			if nextInterval > remaining {
+				nextInterval = remaining
 			}
 		}
 
@@ -304,3 +1752,102 @@ func pollBuildStatus(ctx context.Context, stderr io.Writer, client *api.Client,
 		}
 	}
 }
+
+// rateLimitDelay reports whether err is a 429 and how long to wait before
+// polling again: the server's Retry-After, or fallback without one.
+func rateLimitDelay(err error, fallback time.Duration) (time.Duration, bool) {
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter, true
+	}
+	return fallback, true
+}
+
+// waitTimedOut returns the last response seen at the deadline, or an error
+// when opts.FailOnTimeout is set.
+func waitTimedOut(resp api.BuildResponse, opts pollOptions) (api.BuildResponse, error) {
+	if opts.FailOnTimeout {
+		return resp, fmt.Errorf("timed out after %ds: build %d is still %s", opts.TimeoutSeconds, resp.Build.ID, resp.Build.Status)
+	}
+	return resp, nil
+}
+
+// pollDone reports whether polling can stop at resp. Without a target, any
+// build status outside the pending set ends the wait, unless the build is
+// available and its appcast is still pending (e.g. notarizing). With a
+// target, a build that skipped past it between polls also ends the wait.
+func pollDone(resp api.BuildResponse, opts pollOptions) bool {
+	build := resp.Build
+	if build.IsAvailable() && appcastPending(resp.Appcast, opts) {
+		return false
+	}
+	if opts.WaitFor == "" {
+		return !build.IsProcessing()
+	}
+	return reachedStatus(build.Status, opts.WaitFor) || build.IsTerminal()
+}
+
+// reachedStatus reports whether a build in status has reached target, or a
+// later pending status. Terminal targets are only reached exactly, since a
+// failed build never passes through available.
+func reachedStatus(status api.BuildStatus, target string) bool {
+	if string(status) == target {
+		return true
+	}
+	if !status.IsPending() || !api.BuildStatus(target).IsPending() {
+		return false
+	}
+	return statusIndex(string(status)) > statusIndex(target)
+}
+
+func statusIndex(status string) int {
+	for i, known := range knownBuildStatuses {
+		if status == known {
+			return i
+		}
+	}
+	return -1
+}
+
+// appcastPending reports whether the appcast is in a state the API or
+// opts.PendingAppcastStatuses treats as unfinished.
+func appcastPending(appcast api.Appcast, opts pollOptions) bool {
+	if appcast.IsPending() {
+		return true
+	}
+	for _, status := range opts.PendingAppcastStatuses {
+		if appcast.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// pollProgress describes what a wait is still waiting on.
+func pollProgress(resp api.BuildResponse, opts pollOptions) string {
+	if !resp.Build.IsProcessing() && appcastPending(resp.Appcast, opts) {
+		return fmt.Sprintf("Appcast still %s…", resp.Appcast.Status)
+	}
+	return fmt.Sprintf("Still %s…", resp.Build.Status)
+}
+
+// fetchBuildStatus performs a single status check using the configured
+// strategy.
+func fetchBuildStatus(ctx context.Context, client *api.Client, opts pollOptions) (api.BuildResponse, error) {
+	if opts.Strategy == timeoutStrategyPoll {
+		reqCtx, cancel := context.WithTimeout(ctx, pollRequestTimeout)
+		defer cancel()
+		if opts.StatusURL != "" {
+			return client.GetBuildByURL(reqCtx, opts.StatusURL)
+		}
+		return client.GetBuild(reqCtx, opts.AppID, opts.BuildID)
+	}
+
+	if opts.WaitURL != "" {
+		return client.WaitBuildByURL(ctx, opts.WaitURL, opts.TimeoutSeconds)
+	}
+	return client.WaitBuild(ctx, opts.AppID, opts.BuildID, opts.TimeoutSeconds)
+}
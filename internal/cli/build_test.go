@@ -0,0 +1,1970 @@
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+// newStatusSequenceServer serves the build and wait endpoints for build 42,
+// returning each status in turn and repeating the last one. It records the
+// request paths it saw.
+func newStatusSequenceServer(t *testing.T, statuses ...string) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var (
+		mu    sync.Mutex
+		calls int
+		paths []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/apps/app_123/builds/42" && r.URL.Path != "/api/v1/apps/app_123/builds/42/wait" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		mu.Lock()
+		status := statuses[len(statuses)-1]
+		if calls < len(statuses) {
+			status = statuses[calls]
+		}
+		calls++
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+
+		pollAfter := 10
+		resp := api.BuildResponse{
+			Build: api.Build{
+				ID:         42,
+				Status:     api.BuildStatus(status),
+				InsertedAt: api.APITime{Time: time.Now()},
+				UpdatedAt:  api.APITime{Time: time.Now()},
+			},
+			Appcast:     api.Appcast{Status: "published", FeedURL: "https://example.com/feed.xml"},
+			PollAfterMs: &pollAfter,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), paths...)
+	}
+}
+
+func TestPollBuildStatusStrategies(t *testing.T) {
+	tests := []struct {
+		strategy string
+		wantPath string
+	}{
+		{strategy: timeoutStrategyLongPoll, wantPath: "/api/v1/apps/app_123/builds/42/wait"},
+		{strategy: timeoutStrategyPoll, wantPath: "/api/v1/apps/app_123/builds/42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			server, paths := newStatusSequenceServer(t, "processing", "available")
+			client, err := api.NewClient(server.URL, "test-key", server.Client())
+			if err != nil {
+				t.Fatalf("new client: %v", err)
+			}
+
+			resp, err := pollBuildStatus(context.Background(), io.Discard, client, pollOptions{
+				AppID:          "app_123",
+				BuildID:        "42",
+				TimeoutSeconds: 5,
+				Strategy:       tt.strategy,
+				Interval:       10 * time.Millisecond,
+				JSON:           true,
+			})
+			if err != nil {
+				t.Fatalf("poll build status: %v", err)
+			}
+			if resp.Build.Status != "available" {
+				t.Fatalf("expected status available, got %s", resp.Build.Status)
+			}
+
+			got := paths()
+			if len(got) != 2 {
+				t.Fatalf("expected 2 requests, got %d: %v", len(got), got)
+			}
+			for _, path := range got {
+				if path != tt.wantPath {
+					t.Fatalf("expected requests to %s, got %v", tt.wantPath, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPollBuildStatusWaitForStopsPastTarget(t *testing.T) {
+	server, paths := newStatusSequenceServer(t, "queued", "notarizing", "available")
+	client, err := api.NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := pollBuildStatus(context.Background(), io.Discard, client, pollOptions{
+		AppID:          "app_123",
+		BuildID:        "42",
+		TimeoutSeconds: 5,
+		Strategy:       timeoutStrategyPoll,
+		Interval:       10 * time.Millisecond,
+		WaitFor:        "processing",
+		JSON:           true,
+	})
+	if err != nil {
+		t.Fatalf("poll build status: %v", err)
+	}
+	if resp.Build.Status != "notarizing" {
+		t.Fatalf("expected --wait-for processing to stop at notarizing, got %s", resp.Build.Status)
+	}
+	if got := paths(); len(got) != 2 {
+		t.Fatalf("expected 2 requests, got %d: %v", len(got), got)
+	}
+}
+
+// clockTransport advances a fake clock by step on every request, so each
+// poll happens a fixed amount of fake time after the previous one.
+type clockTransport struct {
+	base http.RoundTripper
+	step time.Duration
+
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.now = c.now.Add(c.step)
+	c.mu.Unlock()
+	return c.base.RoundTrip(req)
+}
+
+func (c *clockTransport) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func TestPollBuildStatusThrottlesStatusLines(t *testing.T) {
+	server, paths := newStatusSequenceServer(t,
+		"processing", "processing", "processing", "processing", "processing", "processing", "processing", "available")
+	clock := &clockTransport{
+		base: server.Client().Transport,
+		step: 10 * time.Second,
+		now:  time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC),
+	}
+	original := timeNow
+	timeNow = clock.Now
+	t.Cleanup(func() { timeNow = original })
+
+	client, err := api.NewClient(server.URL, "test-key", &http.Client{Transport: clock})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	var stderr bytes.Buffer
+	if _, err := pollBuildStatus(context.Background(), &stderr, client, pollOptions{
+		AppID:          "app_123",
+		BuildID:        "42",
+		TimeoutSeconds: 5,
+		Strategy:       timeoutStrategyPoll,
+		Interval:       10 * time.Millisecond,
+		StatusInterval: 30 * time.Second,
+	}); err != nil {
+		t.Fatalf("poll build status: %v", err)
+	}
+
+	if got := len(paths()); got != 8 {
+		t.Fatalf("expected polling to continue at its own rate (8 requests), got %d", got)
+	}
+	// Polls land every 10s; lines print at 10s, 40s and 70s.
+	if got := strings.Count(stderr.String(), "Still processing"); got != 3 {
+		t.Fatalf("expected 3 throttled status lines, got %d:\n%s", got, stderr.String())
+	}
+}
+
+func TestBuildWaitJSONProgress(t *testing.T) {
+	server, _ := newStatusSequenceServer(t, "queued", "processing", "available")
+
+	stdout, stderr, err := executeCLI(t, server.URL, "--json", "build", "wait", "app_123", "42", "--timeout-strategy", "poll", "--json-progress")
+	if err != nil {
+		t.Fatalf("build wait: %v\n%s", err, stderr)
+	}
+
+	var objects []map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(stdout))
+	for decoder.More() {
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil {
+			t.Fatalf("decode %q: %v", stdout, err)
+		}
+		objects = append(objects, object)
+	}
+	if len(objects) != 3 {
+		t.Fatalf("expected two progress objects and the result, got %q", stdout)
+	}
+	for i, status := range []string{"queued", "processing"} {
+		if objects[i]["type"] != "progress" || objects[i]["status"] != status || objects[i]["build_id"] != float64(42) {
+			t.Fatalf("progress %d: expected a %s progress object, got %v", i+1, status, objects[i])
+		}
+		if _, ok := objects[i]["elapsed_ms"]; !ok {
+			t.Fatalf("progress %d: expected elapsed_ms, got %v", i+1, objects[i])
+		}
+	}
+	if _, ok := objects[2]["type"]; ok {
+		t.Fatalf("expected the final result without a type, got %v", objects[2])
+	}
+	if lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[0], `{"type":"progress"`) {
+		t.Fatalf("expected one compact JSON object per line, result included, got %q", stdout)
+	}
+
+	server, _ = newStatusSequenceServer(t, "queued", "processing", "available")
+	stdout, _, err = executeCLI(t, server.URL, "--json", "build", "wait", "app_123", "42", "--timeout-strategy", "poll")
+	if err != nil {
+		t.Fatalf("build wait: %v", err)
+	}
+	if strings.Contains(stdout, "progress") {
+		t.Fatalf("expected no progress objects without --json-progress, got %q", stdout)
+	}
+}
+
+func TestBuildWaitJSONProgressUsesOutputFile(t *testing.T) {
+	server, _ := newStatusSequenceServer(t, "processing", "available")
+	path := filepath.Join(t.TempDir(), "wait.jsonl")
+	if err := os.WriteFile(path, []byte("stale\n"), 0o644); err != nil {
+		t.Fatalf("write stale output: %v", err)
+	}
+
+	stdout, stderr, err := executeCLI(t, server.URL, "--json-envelope", "--output-file", path, "build", "wait", "app_123", "42", "--timeout-strategy", "poll", "--json-progress")
+	if err != nil {
+		t.Fatalf("build wait: %v\n%s", err, stderr)
+	}
+	if stdout != "" {
+		t.Fatalf("expected nothing on stdout with --output-file, got %q", stdout)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a progress line and the result, got %q", data)
+	}
+	for i, line := range lines {
+		var envelope struct {
+			SchemaVersion int                    `json:"schema_version"`
+			Data          map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(line), &envelope); err != nil || envelope.SchemaVersion != jsonSchemaVersion {
+			t.Fatalf("line %d: expected an enveloped JSON object, got %q (%v)", i+1, line, err)
+		}
+		if _, ok := envelope.Data["type"]; ok != (i == 0) {
+			t.Fatalf("line %d: unexpected payload %v", i+1, envelope.Data)
+		}
+	}
+}
+
+func TestBuildWaitJSONProgressNeedsJSON(t *testing.T) {
+	_, _, err := executeCLI(t, "http://127.0.0.1:1", "build", "wait", "app_123", "42", "--json-progress")
+	if err == nil || !strings.Contains(err.Error(), "--json-progress needs --json") {
+		t.Fatalf("expected --json-progress error, got %v", err)
+	}
+}
+
+func TestBuildWaitRejectsNegativeStatusInterval(t *testing.T) {
+	_, _, err := executeCLI(t, "https://example.com", "build", "wait", "app_123", "42", "--status-interval", "-1s")
+	if err == nil || !strings.Contains(err.Error(), "--status-interval must be >= 0") {
+		t.Fatalf("expected --status-interval error, got %v", err)
+	}
+}
+
+func TestPollBuildStatusKeepsWaitingWhenRateLimited(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		call := calls
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		status := api.BuildStatusProcessing
+		switch call {
+		case 2:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "rate limited"})
+			return
+		case 3:
+			status = api.BuildStatusAvailable
+		}
+		_ = json.NewEncoder(w).Encode(api.BuildResponse{
+			Build:   api.Build{ID: 42, Status: status},
+			Appcast: api.Appcast{Status: "published"},
+		})
+	}))
+	defer server.Close()
+
+	// No client-side retries, so the 429 reaches the poll loop.
+	client, err := api.NewClient(server.URL, "test-key", server.Client(), api.WithRetryPolicy(api.RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	var stderr bytes.Buffer
+	start := time.Now()
+	resp, err := pollBuildStatus(context.Background(), &stderr, client, pollOptions{
+		AppID:          "app_123",
+		BuildID:        "42",
+		TimeoutSeconds: 30,
+		Strategy:       timeoutStrategyPoll,
+		Interval:       10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("poll build status: %v", err)
+	}
+	if !resp.Build.IsAvailable() {
+		t.Fatalf("expected available, got %s", resp.Build.Status)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("expected the next poll to honor Retry-After, took %s", elapsed)
+	}
+	if !strings.Contains(stderr.String(), "Rate limited, checking again in 1s") {
+		t.Fatalf("expected a rate limit status, got %q", stderr.String())
+	}
+}
+
+func TestPollBuildStatusHonorsContext(t *testing.T) {
+	server, _ := newStatusSequenceServer(t, "processing")
+	client, err := api.NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	for _, strategy := range []string{timeoutStrategyLongPoll, timeoutStrategyPoll} {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		_, err := pollBuildStatus(ctx, io.Discard, client, pollOptions{
+			AppID:    "app_123",
+			BuildID:  "42",
+			Strategy: strategy,
+			Interval: 10 * time.Millisecond,
+			JSON:     true,
+		})
+		cancel()
+		if err == nil {
+			t.Fatalf("%s: expected context error", strategy)
+		}
+	}
+}
+
+func TestBuildWaitRejectsUnknownTimeoutStrategy(t *testing.T) {
+	_, _, err := executeCLI(t, "https://example.com", "build", "wait", "app_123", "42", "--timeout-strategy", "sometimes")
+	if err == nil {
+		t.Fatal("expected error for unknown timeout strategy")
+	}
+}
+
+func newDeleteServer(t *testing.T) (*httptest.Server, *int) {
+	t.Helper()
+	deletes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/api/v1/apps/app_123/builds/42" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		deletes++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server, &deletes
+}
+
+func TestBuildDeleteConfirmed(t *testing.T) {
+	fakeTerminal(t)
+	server, deletes := newDeleteServer(t)
+
+	stdout, stderr, err := executeCLIWithInput(t, server.URL, "y\n", "build", "delete", "app_123", "42")
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if *deletes != 1 {
+		t.Fatalf("expected 1 delete request, got %d", *deletes)
+	}
+	if !strings.Contains(stderr, "Delete build 42 of app_123? [y/N]") {
+		t.Errorf("expected confirmation prompt on stderr, got %q", stderr)
+	}
+	if !strings.Contains(stdout, "✓") || !strings.Contains(stdout, "Build 42 deleted") {
+		t.Errorf("expected success output, got %q", stdout)
+	}
+}
+
+func TestBuildDeleteDeclined(t *testing.T) {
+	fakeTerminal(t)
+	server, deletes := newDeleteServer(t)
+
+	_, _, err := executeCLIWithInput(t, server.URL, "n\n", "build", "delete", "app_123", "42")
+	if err == nil {
+		t.Fatal("expected error when confirmation is declined")
+	}
+	if *deletes != 0 {
+		t.Fatalf("expected no delete request, got %d", *deletes)
+	}
+}
+
+func TestBuildDeleteYesSkipsPrompt(t *testing.T) {
+	server, deletes := newDeleteServer(t)
+
+	_, stderr, err := executeCLI(t, server.URL, "build", "delete", "app_123", "42", "--yes")
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if *deletes != 1 {
+		t.Fatalf("expected 1 delete request, got %d", *deletes)
+	}
+	if strings.Contains(stderr, "[y/N]") {
+		t.Errorf("expected no prompt with --yes, got %q", stderr)
+	}
+}
+
+func TestBuildDeleteNoInputSkipsPrompt(t *testing.T) {
+	fakeTerminal(t)
+	server, deletes := newDeleteServer(t)
+
+	_, stderr, err := executeCLIWithInput(t, server.URL, "y\n", "--no-input", "build", "delete", "app_123", "42")
+	if err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Fatalf("expected refusal mentioning --yes, got %v", err)
+	}
+	if strings.Contains(stderr, "[y/N]") {
+		t.Errorf("expected no prompt under --no-input, got %q", stderr)
+	}
+	if *deletes != 0 {
+		t.Fatalf("expected no delete request, got %d", *deletes)
+	}
+
+	if _, _, err := executeCLI(t, server.URL, "--no-input", "build", "delete", "app_123", "42", "--yes"); err != nil {
+		t.Fatalf("delete --yes: %v", err)
+	}
+	if *deletes != 1 {
+		t.Fatalf("expected --yes to delete under --no-input, got %d deletes", *deletes)
+	}
+}
+
+func TestBuildDeleteRefusesWithoutTerminal(t *testing.T) {
+	server, deletes := newDeleteServer(t)
+
+	_, _, err := executeCLIWithInput(t, server.URL, "y\n", "build", "delete", "app_123", "42")
+	if err == nil {
+		t.Fatal("expected refusal without a terminal")
+	}
+	if !strings.Contains(err.Error(), "--yes") {
+		t.Fatalf("expected error to mention --yes, got %v", err)
+	}
+	if *deletes != 0 {
+		t.Fatalf("expected no delete request, got %d", *deletes)
+	}
+}
+
+func TestBuildStatusAcceptsIDFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "positional", args: []string{"app_123", "42"}},
+		{name: "flags", args: []string{"--app-id", "app_123", "--build-id", "42"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, paths := newStatusSequenceServer(t, "available")
+
+			args := append([]string{"--json", "build", "status"}, tt.args...)
+			stdout, _, err := executeCLI(t, server.URL, args...)
+			if err != nil {
+				t.Fatalf("build status: %v", err)
+			}
+			var resp api.BuildResponse
+			if err := json.Unmarshal([]byte(stdout), &resp); err != nil {
+				t.Fatalf("invalid JSON output: %v\nraw: %s", err, stdout)
+			}
+			if resp.Build.ID != 42 {
+				t.Fatalf("expected build 42, got %d", resp.Build.ID)
+			}
+			if got := paths(); len(got) != 1 || got[0] != "/api/v1/apps/app_123/builds/42" {
+				t.Fatalf("unexpected requests %v", got)
+			}
+		})
+	}
+}
+
+func TestBuildStatusRejectsMixedIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "positional and flags", args: []string{"app_123", "42", "--app-id", "app_123"}},
+		{name: "one positional and one flag", args: []string{"app_123", "--build-id", "42"}},
+		{name: "app flag only", args: []string{"--app-id", "app_123"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, paths := newStatusSequenceServer(t, "available")
+
+			args := append([]string{"build", "status"}, tt.args...)
+			if _, _, err := executeCLI(t, server.URL, args...); err == nil {
+				t.Fatal("expected error for ambiguous IDs")
+			}
+			if got := paths(); len(got) != 0 {
+				t.Fatalf("expected no requests, got %v", got)
+			}
+		})
+	}
+}
+
+func TestPollBuildStatusWaitForStopsEarly(t *testing.T) {
+	server, paths := newStatusSequenceServer(t, "queued", "processing", "available")
+	client, err := api.NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := pollBuildStatus(context.Background(), io.Discard, client, pollOptions{
+		AppID:    "app_123",
+		BuildID:  "42",
+		WaitFor:  "processing",
+		Interval: 10 * time.Millisecond,
+		JSON:     true,
+	})
+	if err != nil {
+		t.Fatalf("poll build status: %v", err)
+	}
+	if resp.Build.Status != "processing" {
+		t.Fatalf("expected to stop at processing, got %s", resp.Build.Status)
+	}
+	if got := paths(); len(got) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(got))
+	}
+}
+
+func TestPollBuildStatusWaitsThroughQueued(t *testing.T) {
+	server, paths := newStatusSequenceServer(t, "queued", "queued", "available")
+	client, err := api.NewClient(server.URL, "test-key", server.Client())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := pollBuildStatus(context.Background(), io.Discard, client, pollOptions{
+		AppID:          "app_123",
+		BuildID:        "42",
+		TimeoutSeconds: 5,
+		Strategy:       timeoutStrategyPoll,
+		Interval:       10 * time.Millisecond,
+		JSON:           true,
+	})
+	if err != nil {
+		t.Fatalf("poll build status: %v", err)
+	}
+	if !resp.Build.IsAvailable() {
+		t.Fatalf("expected to wait until available, got %s", resp.Build.Status)
+	}
+	if got := paths(); len(got) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(got))
+	}
+}
+
+func TestPollDone(t *testing.T) {
+	tests := []struct {
+		status  string
+		appcast string
+		waitFor string
+		want    bool
+	}{
+		{status: "processing", waitFor: "", want: false},
+		{status: "queued", waitFor: "", want: false},
+		{status: "notarizing", waitFor: "", want: false},
+		{status: "available", waitFor: "", want: true},
+		{status: "queued", waitFor: "processing", want: false},
+		{status: "processing", waitFor: "processing", want: true},
+		{status: "failed", waitFor: "processing", want: true},
+		{status: "available", waitFor: "queued", want: true},
+		{status: "available", appcast: "notarizing", waitFor: "", want: false},
+		{status: "available", appcast: "notarizing", waitFor: "available", want: false},
+		{status: "available", appcast: "published", waitFor: "", want: true},
+		{status: "failed", appcast: "notarizing", waitFor: "", want: true},
+	}
+	for _, tt := range tests {
+		resp := api.BuildResponse{Build: api.Build{Status: api.BuildStatus(tt.status)}, Appcast: api.Appcast{Status: tt.appcast}}
+		if got := pollDone(resp, pollOptions{WaitFor: tt.waitFor}); got != tt.want {
+			t.Errorf("pollDone(%q, %q, %q) = %v, want %v", tt.status, tt.appcast, tt.waitFor, got, tt.want)
+		}
+	}
+}
+
+func TestBuildWaitRejectsUnknownWaitFor(t *testing.T) {
+	_, _, err := executeCLI(t, "https://example.com", "build", "wait", "app_123", "42", "--wait-for", "shipped")
+	if err == nil || !strings.Contains(err.Error(), "unknown status") {
+		t.Fatalf("expected unknown status error, got %v", err)
+	}
+}
+
+func newPromoteServer(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/apps/app_123/builds/42/promote" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body api.PromoteBuildRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Channel != "stable" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": "build is not on a promotable channel"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(api.BuildResponse{
+			Build:   api.Build{ID: 42, Status: "available"},
+			Appcast: api.Appcast{Status: "published", FeedURL: "https://example.com/stable.xml"},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestBuildPromote(t *testing.T) {
+	server := newPromoteServer(t, http.StatusOK)
+
+	stdout, _, err := executeCLI(t, server.URL, "build", "promote", "app_123", "42", "--to", "stable")
+	if err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	if !strings.Contains(stdout, "Build 42 processed") || !strings.Contains(stdout, "Feed updated: https://example.com/stable.xml") {
+		t.Fatalf("expected build and appcast output, got %q", stdout)
+	}
+}
+
+func TestBuildPromoteConflict(t *testing.T) {
+	server := newPromoteServer(t, http.StatusConflict)
+
+	_, _, err := executeCLI(t, server.URL, "build", "promote", "app_123", "42", "--to", "stable")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "build 42 can't be promoted to stable") || !strings.Contains(err.Error(), "not on a promotable channel") {
+		t.Fatalf("expected clear conflict message, got %q", err.Error())
+	}
+}
+
+func TestBuildPromoteRequiresChannel(t *testing.T) {
+	_, _, err := executeCLI(t, "https://example.com", "build", "promote", "app_123", "42")
+	if err == nil || !strings.Contains(err.Error(), "--to") {
+		t.Fatalf("expected missing channel error, got %v", err)
+	}
+}
+
+// newRebuildServer accepts rebuilds of build 42 with the given status code and
+// then reports processing once before the build becomes available.
+func newRebuildServer(t *testing.T, rebuildStatus int) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var (
+		mu    sync.Mutex
+		polls int
+		paths []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		status := "processing"
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/apps/app_123/builds/42/rebuild":
+			if rebuildStatus != http.StatusOK {
+				w.WriteHeader(rebuildStatus)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": "build is processing"})
+				return
+			}
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v1/apps/app_123/builds/42"):
+			mu.Lock()
+			polls++
+			if polls > 1 {
+				status = "available"
+			}
+			mu.Unlock()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		pollAfter := 10
+		_ = json.NewEncoder(w).Encode(api.BuildResponse{
+			Build:       api.Build{ID: 42, Status: api.BuildStatus(status)},
+			Appcast:     api.Appcast{Status: "published", FeedURL: "https://example.com/feed.xml"},
+			PollAfterMs: &pollAfter,
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), paths...)
+	}
+}
+
+func TestBuildRebuildReturnsImmediately(t *testing.T) {
+	server, paths := newRebuildServer(t, http.StatusOK)
+
+	stdout, _, err := executeCLI(t, server.URL, "--json", "build", "rebuild", "app_123", "42")
+	if err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	var resp api.BuildResponse
+	if err := json.Unmarshal([]byte(stdout), &resp); err != nil {
+		t.Fatalf("invalid JSON output: %v\nraw: %s", err, stdout)
+	}
+	if resp.Build.Status != "processing" {
+		t.Fatalf("expected processing status, got %s", resp.Build.Status)
+	}
+	for _, path := range paths() {
+		if strings.HasPrefix(path, "GET /api/v1/apps/") {
+			t.Fatalf("expected no polling without --wait, got %v", paths())
+		}
+	}
+}
+
+func TestBuildRebuildWaits(t *testing.T) {
+	server, _ := newRebuildServer(t, http.StatusOK)
+
+	stdout, _, err := executeCLI(t, server.URL, "--json", "build", "rebuild", "app_123", "42", "--wait", "--timeout-strategy", "poll")
+	if err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	var resp api.BuildResponse
+	if err := json.Unmarshal([]byte(stdout), &resp); err != nil {
+		t.Fatalf("invalid JSON output: %v\nraw: %s", err, stdout)
+	}
+	if resp.Build.Status != "available" {
+		t.Fatalf("expected available status after waiting, got %s", resp.Build.Status)
+	}
+}
+
+func TestBuildRebuildWaitForStatus(t *testing.T) {
+	server, paths := newRebuildServer(t, http.StatusOK)
+
+	stdout, _, err := executeCLI(t, server.URL, "--json", "build", "rebuild", "app_123", "42", "--wait", "--timeout-strategy", "poll", "--wait-for", "processing")
+	if err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	var resp api.BuildResponse
+	if err := json.Unmarshal([]byte(stdout), &resp); err != nil {
+		t.Fatalf("invalid JSON output: %v\nraw: %s", err, stdout)
+	}
+	if resp.Build.Status != "processing" {
+		t.Fatalf("expected --wait-for to stop at processing, got %s", resp.Build.Status)
+	}
+	if got := paths(); len(got) != 2 {
+		t.Fatalf("expected the rebuild and one poll, got %v", got)
+	}
+}
+
+func TestBuildRebuildConflict(t *testing.T) {
+	server, _ := newRebuildServer(t, http.StatusConflict)
+
+	_, _, err := executeCLI(t, server.URL, "build", "rebuild", "app_123", "42")
+	if err == nil || !strings.Contains(err.Error(), "build 42 is still processing") {
+		t.Fatalf("expected still-processing error, got %v", err)
+	}
+}
+
+func TestBuildWaitTimeoutBehavior(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "soft by default", wantErr: false},
+		{name: "fail on timeout", args: []string{"--fail-on-timeout"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newStatusSequenceServer(t, "processing")
+
+			args := append([]string{"--json", "build", "wait", "app_123", "42", "--timeout", "1", "--timeout-strategy", "poll"}, tt.args...)
+			stdout, _, err := executeCLI(t, server.URL, args...)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("expected soft timeout, got %v", err)
+				}
+				var resp api.BuildResponse
+				if err := json.Unmarshal([]byte(stdout), &resp); err != nil {
+					t.Fatalf("invalid JSON output: %v\nraw: %s", err, stdout)
+				}
+				if resp.Build.Status != "processing" {
+					t.Fatalf("expected last processing response, got %s", resp.Build.Status)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "build 42 is still processing") {
+				t.Fatalf("expected timeout error, got %v", err)
+			}
+		})
+	}
+}
+
+// writeTestZip writes name to dir as a zipped MyApp.app. A non-nil
+// infoPlist adds an Info.plist holding those string keys.
+func writeTestZip(t *testing.T, dir, name string, infoPlist map[string]string) string {
+	t.Helper()
+	zipPath := filepath.Join(dir, name)
+	out, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatalf("create zip: %v", err)
+	}
+	writer := zip.NewWriter(out)
+	entry, err := writer.Create("MyApp.app/Contents/MacOS/MyApp")
+	if err != nil {
+		t.Fatalf("create entry: %v", err)
+	}
+	if _, err := entry.Write([]byte("payload")); err != nil {
+		t.Fatalf("write entry: %v", err)
+	}
+	if infoPlist != nil {
+		entry, err := writer.Create("MyApp.app/Contents/Info.plist")
+		if err != nil {
+			t.Fatalf("create entry: %v", err)
+		}
+		var dict strings.Builder
+		for key, value := range infoPlist {
+			dict.WriteString("<key>" + key + "</key><string>" + value + "</string>")
+		}
+		plist := `<?xml version="1.0" encoding="UTF-8"?><plist version="1.0"><dict>` + dict.String() + `</dict></plist>`
+		if _, err := entry.Write([]byte(plist)); err != nil {
+			t.Fatalf("write entry: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("close file: %v", err)
+	}
+	return zipPath
+}
+
+func TestUploadBundleIDCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		bundleID   string
+		args       []string
+		wantErr    string
+		wantUpload bool
+	}{
+		{name: "matching", bundleID: "com.example.app", args: []string{"--check-bundle"}, wantUpload: true},
+		{name: "mismatch", bundleID: "com.example.other", args: []string{"--check-bundle"}, wantErr: "bundle ID mismatch"},
+		{name: "mismatch forced", bundleID: "com.example.other", args: []string{"--check-bundle", "--force-bundle"}, wantUpload: true},
+		{name: "missing plist", args: []string{"--check-bundle"}, wantUpload: true},
+		{name: "mismatch unchecked", bundleID: "com.example.other", wantUpload: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newUploadServer(t)
+			dir := t.TempDir()
+			zipPath := writeTestZip(t, dir, "MyApp.zip", nil)
+			if tt.bundleID != "" {
+				zipPath = writeTestZip(t, dir, "MyApp.zip", map[string]string{"CFBundleIdentifier": tt.bundleID})
+			}
+
+			args := append([]string{"build", "upload", "app_123", zipPath}, tt.args...)
+			_, _, err := executeCLI(t, server.URL, args...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("upload: %v", err)
+			}
+
+			uploaded := false
+			for _, path := range server.paths() {
+				if path == "/api/v1/apps/app_123/uploads" {
+					uploaded = true
+				}
+			}
+			if uploaded != tt.wantUpload {
+				t.Fatalf("upload started = %v, want %v (requests %v)", uploaded, tt.wantUpload, server.paths())
+			}
+		})
+	}
+}
+
+func TestUploadSendsMetadata(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/apps/app_123/uploads" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": "stop here"})
+	}))
+	defer server.Close()
+
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+	_, _, err := executeCLI(t, server.URL, "build", "upload", "app_123", zipPath,
+		"--metadata", "git_sha=abc123",
+		"--metadata", "ci_url=https://ci.example.com/run?id=1&a=b",
+		"--metadata", "git_sha=def456",
+	)
+	if err == nil || !strings.Contains(err.Error(), "stop here") {
+		t.Fatalf("expected create upload to be rejected, got %v", err)
+	}
+
+	build, _ := body["build"].(map[string]interface{})
+	metadata, ok := build["metadata"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected metadata object in request body, got %v", body)
+	}
+	if metadata["git_sha"] != "def456" {
+		t.Errorf("expected last git_sha to win, got %v", metadata["git_sha"])
+	}
+	if metadata["ci_url"] != "https://ci.example.com/run?id=1&a=b" {
+		t.Errorf("expected ci_url to keep '=' in value, got %v", metadata["ci_url"])
+	}
+}
+
+func TestBuildStatusOnlyExitCodes(t *testing.T) {
+	tests := []struct {
+		status   string
+		wantCode int
+	}{
+		{status: "available", wantCode: 0},
+		{status: "failed", wantCode: exitStatusFailed},
+		{status: "processing", wantCode: exitStatusPending},
+		{status: "queued", wantCode: exitStatusPending},
+	}
+
+	for _, tt := range tests {
+		for _, command := range []string{"status", "wait"} {
+			t.Run(command+"/"+tt.status, func(t *testing.T) {
+				server, _ := newStatusSequenceServer(t, tt.status)
+
+				args := []string{"build", command, "app_123", "42", "--status-only"}
+				if command == "wait" {
+					args = append(args, "--timeout", "1", "--wait-for", tt.status)
+				}
+				stdout, stderr, err := executeCLI(t, server.URL, args...)
+				if stdout != "" || stderr != "" {
+					t.Fatalf("expected no output, got stdout %q stderr %q", stdout, stderr)
+				}
+
+				code := 0
+				if err != nil {
+					var exitErr *ExitError
+					if !errors.As(err, &exitErr) {
+						t.Fatalf("expected ExitError, got %v", err)
+					}
+					code = exitErr.Code
+				}
+				if code != tt.wantCode {
+					t.Fatalf("expected exit code %d, got %d", tt.wantCode, code)
+				}
+			})
+		}
+	}
+}
+
+func TestBuildStatusOnlyRejectsJSON(t *testing.T) {
+	_, _, err := executeCLI(t, "https://example.com", "--json", "build", "status", "app_123", "42", "--status-only")
+	if err == nil || !strings.Contains(err.Error(), "--status-only") {
+		t.Fatalf("expected --status-only/--json conflict, got %v", err)
+	}
+}
+
+func TestShipPrintsSummaryFooter(t *testing.T) {
+	server := newUploadServer(t)
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+	info, err := os.Stat(zipPath)
+	if err != nil {
+		t.Fatalf("stat zip: %v", err)
+	}
+
+	_, stderr, err := executeCLI(t, server.URL, "ship", "app_123", zipPath, "--wait")
+	if err != nil {
+		t.Fatalf("ship: %v", err)
+	}
+	for _, want := range []string{"Build     7", "Status    available", fmt.Sprintf("Uploaded  %d bytes", info.Size()), "Feed      https://example.com/appcast.xml", "Time      "} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("expected summary line %q, got:\n%s", want, stderr)
+		}
+	}
+}
+
+func TestShipSummarySuppressedForJSON(t *testing.T) {
+	server := newUploadServer(t)
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+	_, stderr, err := executeCLI(t, server.URL, "--json", "ship", "app_123", zipPath, "--wait")
+	if err != nil {
+		t.Fatalf("ship: %v", err)
+	}
+	if strings.Contains(stderr, "Uploaded") {
+		t.Fatalf("expected no summary with --json, got %q", stderr)
+	}
+}
+
+func newBuildListServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/apps/app_123/builds" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		next := "page2"
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(api.BuildListResponse{
+			Builds: []api.Build{
+				{ID: 42, Status: "available", Version: strPtr("1.2.0"), BuildNumber: strPtr("5")},
+				{ID: 41, Status: "failed", Version: strPtr("1.1.0"), BuildNumber: strPtr("4")},
+			},
+			NextCursor: &next,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestBuildListStreamsTable(t *testing.T) {
+	server := newBuildListServer(t)
+
+	stdout, stderr, err := executeCLI(t, server.URL, "build", "list", "app_123")
+	if err != nil {
+		t.Fatalf("build list: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got %q", stdout)
+	}
+	if !strings.HasPrefix(lines[0], "ID") || !strings.Contains(lines[1], "1.2.0") || !strings.Contains(lines[2], "failed") {
+		t.Fatalf("unexpected table:\n%s", stdout)
+	}
+	if strings.Index(lines[1], "1.2.0") != strings.Index(lines[0], "VERSION") {
+		t.Fatalf("expected aligned columns:\n%s", stdout)
+	}
+	if !strings.Contains(stderr, "--cursor page2") {
+		t.Fatalf("expected next page hint, got %q", stderr)
+	}
+}
+
+func TestBuildListJSON(t *testing.T) {
+	server := newBuildListServer(t)
+
+	stdout, _, err := executeCLI(t, server.URL, "--json", "build", "list", "app_123")
+	if err != nil {
+		t.Fatalf("build list: %v", err)
+	}
+	var resp api.BuildListResponse
+	if err := json.Unmarshal([]byte(stdout), &resp); err != nil {
+		t.Fatalf("invalid JSON output: %v\nraw: %s", err, stdout)
+	}
+	if len(resp.Builds) != 2 || resp.NextCursor == nil || *resp.NextCursor != "page2" {
+		t.Fatalf("unexpected list response: %+v", resp)
+	}
+}
+
+func TestBuildListTimeFilters(t *testing.T) {
+	original := timeNow
+	timeNow = func() time.Time { return time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { timeNow = original })
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantSince string
+		wantUntil string
+	}{
+		{name: "absolute", args: []string{"--since", "2024-05-01T00:00:00Z", "--until", "2024-05-02T08:00:00+02:00"}, wantSince: "2024-05-01T00:00:00Z", wantUntil: "2024-05-02T06:00:00Z"},
+		{name: "relative", args: []string{"--since", "7d", "--until", "24h"}, wantSince: "2024-05-03T12:00:00Z", wantUntil: "2024-05-09T12:00:00Z"},
+		{name: "open ended", args: []string{"--since", "90m"}, wantSince: "2024-05-10T10:30:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query url.Values
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/v1/apps/app_123/builds" {
+					query = r.URL.Query()
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"builds":[]}`))
+			}))
+			defer server.Close()
+
+			args := append([]string{"build", "list", "app_123"}, tt.args...)
+			if _, _, err := executeCLI(t, server.URL, args...); err != nil {
+				t.Fatalf("build list: %v", err)
+			}
+			if got := query.Get("since"); got != tt.wantSince {
+				t.Errorf("since = %q, want %q", got, tt.wantSince)
+			}
+			if got := query.Get("until"); got != tt.wantUntil {
+				t.Errorf("until = %q, want %q", got, tt.wantUntil)
+			}
+		})
+	}
+}
+
+func TestBuildListSinceBuild(t *testing.T) {
+	// The server ignores after_id, so this also covers the client-side
+	// filter.
+	var afterID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/apps/app_123/builds" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		afterID = r.URL.Query().Get("after_id")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(api.BuildListResponse{Builds: []api.Build{
+			{ID: 42, Status: "available"},
+			{ID: 41, Status: "failed"},
+		}})
+	}))
+	defer server.Close()
+
+	stdout, _, err := executeCLI(t, server.URL, "--json", "build", "list", "app_123", "--since-build", "41")
+	if err != nil {
+		t.Fatalf("build list: %v", err)
+	}
+	if afterID != "41" {
+		t.Fatalf("expected after_id=41 in the query, got %q", afterID)
+	}
+	var resp api.BuildListResponse
+	if err := json.Unmarshal([]byte(stdout), &resp); err != nil {
+		t.Fatalf("invalid JSON output: %v\nraw: %s", err, stdout)
+	}
+	if len(resp.Builds) != 1 || resp.Builds[0].ID != 42 {
+		t.Fatalf("expected only build 42, got %+v", resp.Builds)
+	}
+
+	stdout, _, err = executeCLI(t, server.URL, "build", "list", "app_123", "--since-build", "41")
+	if err != nil {
+		t.Fatalf("build list: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[1], "42 ") {
+		t.Fatalf("expected header and build 42, got %q", stdout)
+	}
+
+	// Nothing new is an empty list, not null.
+	stdout, _, err = executeCLI(t, server.URL, "--json", "build", "list", "app_123", "--since-build", "42")
+	if err != nil {
+		t.Fatalf("build list: %v", err)
+	}
+	if !strings.Contains(stdout, `"builds": []`) {
+		t.Fatalf("expected an empty builds list, got %s", stdout)
+	}
+}
+
+func TestBuildListRejectsNegativeSinceBuild(t *testing.T) {
+	_, _, err := executeCLI(t, "https://example.com", "build", "list", "app_123", "--since-build", "-1")
+	if err == nil || !strings.Contains(err.Error(), "--since-build must be >= 0") {
+		t.Fatalf("expected --since-build error, got %v", err)
+	}
+}
+
+func TestBuildListRejectsInvalidTimeRange(t *testing.T) {
+	for _, args := range [][]string{
+		{"--since", "24h", "--until", "7d"},
+		{"--since", "yesterday"},
+	} {
+		cmdArgs := append([]string{"build", "list", "app_123"}, args...)
+		if _, _, err := executeCLI(t, "https://example.com", cmdArgs...); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
+
+func TestParseByteRate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "2048", want: 2048},
+		{value: "500KB", want: 500 * 1024},
+		{value: "1.5m", want: 1536 * 1024},
+		{value: "2MiB/s", want: 2 << 20},
+		{value: "1G", want: 1 << 30},
+		{value: "fast", wantErr: true},
+		{value: "0", wantErr: true},
+		{value: "-1KB", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseByteRate(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseByteRate(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Fatalf("parseByteRate(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestUploadRejectsInvalidMaxUploadRate(t *testing.T) {
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+	_, _, err := executeCLI(t, "https://example.com", "build", "upload", "app_123", zipPath, "--max-upload-rate", "fast")
+	if err == nil || !strings.Contains(err.Error(), "invalid --max-upload-rate") {
+		t.Fatalf("expected rate error, got %v", err)
+	}
+}
+
+func TestUploadAppIDFromPlist(t *testing.T) {
+	tests := []struct {
+		name     string
+		appID    string
+		wantPath string
+	}{
+		{name: "from plist", wantPath: "/api/v1/apps/com.example.app/uploads"},
+		{name: "explicit app ID wins", appID: "app_123", wantPath: "/api/v1/apps/app_123/uploads"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu    sync.Mutex
+				paths []string
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				paths = append(paths, r.URL.Path)
+				mu.Unlock()
+				w.WriteHeader(http.StatusNotFound)
+			}))
+			defer server.Close()
+
+			zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", map[string]string{"CFBundleIdentifier": "com.example.app"})
+			args := []string{"build", "upload", "--app-id-from-plist"}
+			if tt.appID != "" {
+				args = append(args, tt.appID)
+			}
+			_, _, _ = executeCLI(t, server.URL, append(args, zipPath)...)
+
+			mu.Lock()
+			defer mu.Unlock()
+			found := false
+			for _, path := range paths {
+				found = found || path == tt.wantPath
+			}
+			if !found {
+				t.Fatalf("expected a request to %s, got %v", tt.wantPath, paths)
+			}
+		})
+	}
+}
+
+func TestUploadAppIDFromPlistMissingPlist(t *testing.T) {
+	zipPath := filepath.Join(t.TempDir(), "MyApp.zip")
+	out, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatalf("create zip: %v", err)
+	}
+	writer := zip.NewWriter(out)
+	if _, err := writer.Create("README.txt"); err != nil {
+		t.Fatalf("create entry: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	out.Close()
+
+	_, _, err = executeCLI(t, "https://example.com", "build", "upload", "--app-id-from-plist", zipPath)
+	if err == nil || !strings.Contains(err.Error(), "--app-id-from-plist") || !strings.Contains(err.Error(), "no app bundle Info.plist") {
+		t.Fatalf("expected a missing plist error, got %v", err)
+	}
+}
+
+func TestUploadRequiresAppIDWithoutPlistFlag(t *testing.T) {
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+	if _, _, err := executeCLI(t, "https://example.com", "build", "upload", zipPath); err == nil {
+		t.Fatal("expected an argument error")
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    int
+		wantErr bool
+	}{
+		{raw: "120", want: 120},
+		{raw: "2m", want: 120},
+		{raw: "2m30s", want: 150},
+		{raw: "1500ms", want: 2},
+		{raw: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeout(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseTimeout(%q): expected error", tt.raw)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseTimeout(%q) = %d, %v; want %d", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestTimeoutFlagEnforcesCapAfterConversion(t *testing.T) {
+	_, _, err := executeCLI(t, "https://example.com", "build", "wait", "app_123", "42", "--timeout", "5m1s")
+	if err == nil || !strings.Contains(err.Error(), "timeout must be <= 300") {
+		t.Fatalf("expected cap error, got %v", err)
+	}
+	_, _, err = executeCLI(t, "https://example.com", "build", "wait", "app_123", "42", "--timeout", "a while")
+	if err == nil || !strings.Contains(err.Error(), "invalid timeout") {
+		t.Fatalf("expected parse error, got %v", err)
+	}
+}
+
+func TestTimeoutDurationIsSentAsSeconds(t *testing.T) {
+	var gotTimeout string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/wait") {
+			gotTimeout = r.URL.Query().Get("timeout")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(api.BuildResponse{Build: api.Build{ID: 42, Status: api.BuildStatusAvailable}})
+	}))
+	defer server.Close()
+
+	if _, _, err := executeCLI(t, server.URL, "--json", "build", "wait", "app_123", "42", "--timeout", "2m"); err != nil {
+		t.Fatalf("build wait: %v", err)
+	}
+	if gotTimeout != "120" {
+		t.Fatalf("expected timeout=120, got %q", gotTimeout)
+	}
+}
+
+// uploadCounts tallies the create requests, storage PUTs and deleted builds
+// server has seen.
+func uploadCounts(server *uploadServer) (creates, puts int, deleted []string) {
+	for _, req := range server.requests() {
+		switch {
+		case req.Method == http.MethodPost && strings.HasSuffix(req.Path, "/uploads"):
+			creates++
+		case req.Method == http.MethodPut && strings.HasPrefix(req.Path, "/storage/"):
+			puts++
+		case req.Method == http.MethodDelete:
+			deleted = append(deleted, path.Base(req.Path))
+		}
+	}
+	return creates, puts, deleted
+}
+
+func TestUploadReissuesURLNearExpiry(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	original := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = original })
+
+	server := newUploadServer(t, withExpiringUploadURLs(now.Add(10*time.Second), now.Add(15*time.Minute)))
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+	stdout, stderr, err := executeCLI(t, server.URL, "build", "upload", "app_123", zipPath, "--force-bundle")
+	if err != nil {
+		t.Fatalf("upload: %v\n%s", err, stderr)
+	}
+	if creates, uploads, deleted := uploadCounts(server); creates != 2 || uploads != 1 || len(deleted) != 1 || deleted[0] != "1" {
+		t.Fatalf("expected 2 creates, 1 upload and the expired build deleted, got %d, %d and %q", creates, uploads, deleted)
+	}
+	if !strings.Contains(stderr, "Upload URL expired, requesting a new one") {
+		t.Fatalf("expected a re-issue status, got %q", stderr)
+	}
+	if !strings.Contains(stdout, "Upload complete") {
+		t.Fatalf("expected completion, got %q", stdout)
+	}
+}
+
+func TestUploadReissuesURLThatExpiredDuringUpload(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	original := timeNow
+	calls := 0
+	timeNow = func() time.Time {
+		calls++
+		// The second reading comes after the slow upload failed.
+		if calls > 1 {
+			return now.Add(2 * time.Minute)
+		}
+		return now
+	}
+	t.Cleanup(func() { timeNow = original })
+
+	server := newUploadServer(t, withExpiringUploadURLs(now.Add(time.Minute), now.Add(15*time.Minute)), withFailingStorage(http.StatusForbidden, ""))
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+	if _, stderr, err := executeCLI(t, server.URL, "build", "upload", "app_123", zipPath, "--force-bundle"); err != nil {
+		t.Fatalf("upload: %v\n%s", err, stderr)
+	}
+	if creates, uploads, deleted := uploadCounts(server); creates != 2 || uploads != 2 || len(deleted) != 1 || deleted[0] != "1" {
+		t.Fatalf("expected 2 creates, 2 uploads and the expired build deleted, got %d, %d and %q", creates, uploads, deleted)
+	}
+}
+
+func TestUploadFailureWithLiveURLIsNotRetried(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	original := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = original })
+
+	server := newUploadServer(t, withExpiringUploadURLs(now.Add(15*time.Minute)), withFailingStorage(http.StatusForbidden, ""))
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+	_, _, err := executeCLI(t, server.URL, "build", "upload", "app_123", zipPath, "--force-bundle")
+	if err == nil || !strings.Contains(err.Error(), "status 403") {
+		t.Fatalf("expected the 403 to surface, got %v", err)
+	}
+	if creates, uploads, deleted := uploadCounts(server); creates != 1 || uploads != 1 || len(deleted) != 0 {
+		t.Fatalf("expected no re-issue, got %d creates, %d uploads and %q deleted", creates, uploads, deleted)
+	}
+}
+
+func TestUploadChannelPrecedence(t *testing.T) {
+	withChannel := writeTestZip(t, t.TempDir(), "MyApp.zip", map[string]string{"CFBundleIdentifier": "com.example.app", "TWChannel": "beta"})
+	withoutChannel := writeTestZip(t, t.TempDir(), "MyApp.zip", map[string]string{"CFBundleIdentifier": "com.example.app"})
+
+	tests := []struct {
+		name    string
+		req     uploadRequest
+		want    string
+		wantErr string
+	}{
+		{name: "flag only", req: uploadRequest{FilePath: withChannel, Channel: "stable"}, want: "stable"},
+		{name: "auto off", req: uploadRequest{FilePath: withChannel}, want: ""},
+		{name: "auto", req: uploadRequest{FilePath: withChannel, AutoChannel: true}, want: "beta"},
+		{name: "flag overrides auto", req: uploadRequest{FilePath: withChannel, Channel: "stable", AutoChannel: true}, want: "stable"},
+		{name: "missing key", req: uploadRequest{FilePath: withoutChannel, AutoChannel: true}, wantErr: "has no TWChannel key"},
+	}
+	for _, tt := range tests {
+		got, err := uploadChannel(tt.req)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestUploadAutoChannelSendsPlistChannel(t *testing.T) {
+	server := newUploadServer(t)
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", map[string]string{"CFBundleIdentifier": "com.example.app", "TWChannel": "beta"})
+	if _, stderr, err := executeCLI(t, server.URL, "build", "upload", "app_123", zipPath, "--auto-channel"); err != nil {
+		t.Fatalf("upload: %v\n%s", err, stderr)
+	}
+	if creates := server.createdUploads(t, "app_123"); len(creates) != 1 || creates[0].Channel != "beta" {
+		t.Fatalf("expected channel beta from Info.plist, got %+v", creates)
+	}
+}
+
+func TestUploadDeclaresFileSize(t *testing.T) {
+	server := newUploadServer(t)
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+	info, err := os.Stat(zipPath)
+	if err != nil {
+		t.Fatalf("stat zip: %v", err)
+	}
+	if _, stderr, err := executeCLI(t, server.URL, "build", "upload", "app_123", zipPath, "--force-bundle"); err != nil {
+		t.Fatalf("upload: %v\n%s", err, stderr)
+	}
+	if creates := server.createdUploads(t, "app_123"); len(creates) != 1 || creates[0].Size != info.Size() {
+		t.Fatalf("expected size %d in the create request, got %+v", info.Size(), creates)
+	}
+}
+
+func TestUploadReportsOversizeRejection(t *testing.T) {
+	var puts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPut {
+			puts++
+		}
+		switch r.URL.Path {
+		case "/api/v1/apps/app_123/uploads":
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": "build exceeds the 2 GB limit"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+	_, _, err := executeCLI(t, server.URL, "build", "upload", "app_123", zipPath, "--force-bundle")
+	if err == nil {
+		t.Fatal("expected an error for an oversize upload")
+	}
+	for _, want := range []string{"MyApp.zip", "larger than the server accepts", "2 GB limit"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in error, got %v", want, err)
+		}
+	}
+	if puts != 0 {
+		t.Fatalf("expected no file upload after the rejection, got %d", puts)
+	}
+}
+
+func TestUploadCommitSkipsExistingBuild(t *testing.T) {
+	server := newUploadServer(t, withBuilds([]api.Build{{ID: 5, Status: api.BuildStatusAvailable, CustomMetadata: map[string]string{"commit": "abc123"}}}))
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+	stdout, stderr, err := executeCLI(t, server.URL, "--json", "build", "upload", "app_123", zipPath, "--commit", "abc123")
+	if err != nil {
+		t.Fatalf("upload: %v\n%s", err, stderr)
+	}
+	if creates := server.createdUploads(t, "app_123"); len(creates) != 0 {
+		t.Fatalf("expected no upload, got %d create requests", len(creates))
+	}
+	lookups := server.requestsTo(http.MethodGet, "/api/v1/apps/app_123/builds")
+	if len(lookups) != 1 || lookups[0].Query.Get("metadata[commit]") != "abc123" {
+		t.Fatalf("expected one build lookup filtered by commit, got %+v", lookups)
+	}
+	var resp api.BuildResponse
+	if err := json.Unmarshal([]byte(stdout), &resp); err != nil || resp.Build.ID != 5 {
+		t.Fatalf("expected the existing build in the output, got %v: %s", err, stdout)
+	}
+}
+
+func TestShipSummaryReportsSkippedCommit(t *testing.T) {
+	server := newUploadServer(t, withBuilds([]api.Build{{ID: 5, Status: api.BuildStatusAvailable, CustomMetadata: map[string]string{"commit": "abc123"}}}))
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+	_, stderr, err := executeCLI(t, server.URL, "ship", "app_123", zipPath, "--commit", "abc123")
+	if err != nil {
+		t.Fatalf("ship: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stderr, "Upload  skipped, build 5 already exists for commit abc123") || strings.Contains(stderr, "Uploaded") {
+		t.Fatalf("expected the summary to report the skip without a byte count, got:\n%s", stderr)
+	}
+}
+
+func TestUploadCommitUploadsWhenNoBuildExists(t *testing.T) {
+	server := newUploadServer(t, withBuilds([]api.Build{{ID: 4, Status: api.BuildStatusFailed, CustomMetadata: map[string]string{"commit": "abc123"}}}))
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+	_, stderr, err := executeCLI(t, server.URL, "build", "upload", "app_123", zipPath, "--commit", "abc123", "--metadata", "ci=1")
+	if err != nil {
+		t.Fatalf("upload: %v\n%s", err, stderr)
+	}
+	creates := server.createdUploads(t, "app_123")
+	if len(creates) != 1 {
+		t.Fatalf("expected one upload, got %d", len(creates))
+	}
+	if metadata := creates[0].Metadata; metadata["commit"] != "abc123" || metadata["ci"] != "1" {
+		t.Fatalf("expected the commit alongside other metadata, got %v", metadata)
+	}
+}
+
+func TestBuildStatusFetchesOnceWithoutWait(t *testing.T) {
+	server, paths := newStatusSequenceServer(t, "processing", "available")
+
+	stdout, _, err := executeCLI(t, server.URL, "--json", "build", "status", "app_123", "42")
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	var resp api.BuildResponse
+	if err := json.Unmarshal([]byte(stdout), &resp); err != nil {
+		t.Fatalf("invalid JSON output: %v\nraw: %s", err, stdout)
+	}
+	if resp.Build.Status != "processing" {
+		t.Fatalf("expected processing status, got %s", resp.Build.Status)
+	}
+	if got := paths(); len(got) != 1 {
+		t.Fatalf("expected a single fetch, got %v", got)
+	}
+}
+
+func TestBuildStatusWaitPollsUntilTerminal(t *testing.T) {
+	server, paths := newStatusSequenceServer(t, "processing", "processing", "available")
+
+	stdout, _, err := executeCLI(t, server.URL, "--json", "build", "status", "app_123", "42", "--wait", "--timeout-strategy", "poll")
+	if err != nil {
+		t.Fatalf("status --wait: %v", err)
+	}
+	var resp api.BuildResponse
+	if err := json.Unmarshal([]byte(stdout), &resp); err != nil {
+		t.Fatalf("invalid JSON output: %v\nraw: %s", err, stdout)
+	}
+	if resp.Build.Status != "available" {
+		t.Fatalf("expected available status after waiting, got %s", resp.Build.Status)
+	}
+	if got := paths(); len(got) != 3 {
+		t.Fatalf("expected three polls, got %v", got)
+	}
+}
+
+func TestBuildStatusRejectsWaitFlagsWithoutWait(t *testing.T) {
+	_, _, err := executeCLI(t, "https://example.com", "build", "status", "app_123", "42", "--timeout", "60")
+	if err == nil || !strings.Contains(err.Error(), "--timeout only applies with --wait") {
+		t.Fatalf("expected --wait error, got %v", err)
+	}
+}
+
+func TestWaitFlagsRequireWait(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := writeTestZip(t, dir, "build.zip", nil)
+	statePath := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(statePath, []byte(`{"app_id":"app_123","file":"build.zip","upload":{"build_id":42}}`), 0o600); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "rebuild", args: []string{"build", "rebuild", "app_123", "42", "--on-change", "true"}},
+		{name: "complete", args: []string{"build", "complete", "--from-state", statePath, "--status-interval", "30s"}},
+		{name: "upload", args: []string{"build", "upload", "app_123", zipPath, "--wait-for", "available"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := executeCLI(t, "http://127.0.0.1:1", tt.args...)
+			if err == nil || !strings.Contains(err.Error(), "only applies with --wait") {
+				t.Fatalf("expected --wait error, got %v", err)
+			}
+		})
+	}
+}
+
+// newAppcastSequenceServer serves build 42 as available with the appcast
+// moving through statuses, one per request.
+func newAppcastSequenceServer(t *testing.T, statuses ...string) (*httptest.Server, func() int) {
+	t.Helper()
+
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/apps/app_123/builds/42" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		mu.Lock()
+		status := statuses[len(statuses)-1]
+		if calls < len(statuses) {
+			status = statuses[calls]
+		}
+		calls++
+		mu.Unlock()
+
+		pollAfter := 10
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(api.BuildResponse{
+			Build:       api.Build{ID: 42, Status: api.BuildStatusAvailable},
+			Appcast:     api.Appcast{Status: status, FeedURL: "https://example.com/feed.xml"},
+			PollAfterMs: &pollAfter,
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+func TestBuildStatusFailedAppcastExitsNonZero(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(api.BuildResponse{
+			Build:   api.Build{ID: 42, Status: api.BuildStatusAvailable},
+			Appcast: api.Appcast{Status: api.AppcastStatusFailed, Message: "EdDSA signature missing"},
+		})
+	}))
+	defer server.Close()
+
+	stdout, _, err := executeCLI(t, server.URL, "build", "status", "app_123", "42")
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("expected exit code 1, got %v", err)
+	}
+	for _, want := range []string{"Build 42 processed", "Appcast for build 42 failed", "EdDSA signature missing"} {
+		if !strings.Contains(stdout, want) {
+			t.Fatalf("expected %q in output, got %q", want, stdout)
+		}
+	}
+
+	// --no-appcast doesn't hide the failure.
+	stdout, _, _ = executeCLI(t, server.URL, "build", "status", "app_123", "42", "--no-appcast")
+	if !strings.Contains(stdout, "EdDSA signature missing") {
+		t.Fatalf("expected the failure despite --no-appcast, got %q", stdout)
+	}
+
+	_, _, err = executeCLI(t, server.URL, "build", "status", "app_123", "42", "--status-only")
+	if !errors.As(err, &exitErr) || exitErr.Code != exitStatusFailed {
+		t.Fatalf("expected --status-only to report failure, got %v", err)
+	}
+}
+
+func TestBuildWaitFailedAppcastExitsNonZero(t *testing.T) {
+	server, _ := newAppcastSequenceServer(t, "notarizing", api.AppcastStatusFailed)
+
+	_, _, err := executeCLI(t, server.URL, "--json", "build", "wait", "app_123", "42", "--timeout-strategy", "poll")
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("expected exit code 1, got %v", err)
+	}
+}
+
+func TestBuildWaitWaitsForNotarizingAppcast(t *testing.T) {
+	server, calls := newAppcastSequenceServer(t, "notarizing", "notarizing", "published")
+
+	stdout, stderr, err := executeCLI(t, server.URL, "build", "wait", "app_123", "42", "--timeout-strategy", "poll")
+	if err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if got := calls(); got != 3 {
+		t.Fatalf("expected to poll until the appcast was published, got %d requests", got)
+	}
+	if !strings.Contains(stderr, "Appcast still notarizing") {
+		t.Fatalf("expected appcast progress, got %q", stderr)
+	}
+	if !strings.Contains(stdout, "Feed updated") {
+		t.Fatalf("expected published feed in output, got %q", stdout)
+	}
+}
+
+func TestBuildWaitPendingAppcastStatusFlag(t *testing.T) {
+	server, calls := newAppcastSequenceServer(t, "signing", "published")
+
+	_, _, err := executeCLI(t, server.URL, "--json", "--pending-appcast-status", "signing", "build", "wait", "app_123", "42", "--timeout-strategy", "poll")
+	if err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if got := calls(); got != 2 {
+		t.Fatalf("expected the configured appcast status to keep the wait going, got %d requests", got)
+	}
+
+	// The status only applies to the run that passed it.
+	server, calls = newAppcastSequenceServer(t, "signing", "published")
+	if _, _, err := executeCLI(t, server.URL, "--json", "build", "wait", "app_123", "42", "--timeout-strategy", "poll"); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if got := calls(); got != 1 {
+		t.Fatalf("expected signing to end a wait without the flag, got %d requests", got)
+	}
+}
+
+func TestBuildStatusRawPrintsServerBody(t *testing.T) {
+	payload := `{"build":{"id":42,"status":"available","unknown_field":true},"appcast":{}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/apps/app_123/builds/42" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	stdout, _, err := executeCLI(t, server.URL, "build", "status", "app_123", "42", "--raw")
+	if err != nil {
+		t.Fatalf("status --raw: %v", err)
+	}
+	if stdout != payload {
+		t.Fatalf("expected raw body %q, got %q", payload, stdout)
+	}
+}
+
+func TestUploadReportsStorageRetries(t *testing.T) {
+	server := newUploadServer(t, withFailingStorage(http.StatusServiceUnavailable, "1"))
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+	_, stderr, err := executeCLI(t, server.URL, "build", "upload", "app_123", zipPath, "--force-bundle")
+	if err != nil {
+		t.Fatalf("upload: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stderr, "Uploading to edge network… (retry 1 in 1s)") {
+		t.Fatalf("expected a retry indicator, got %q", stderr)
+	}
+}
+
+func TestUploadRetryIndicatorHiddenInJSON(t *testing.T) {
+	server := newUploadServer(t, withFailingStorage(http.StatusServiceUnavailable, "1"))
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+	_, stderr, err := executeCLI(t, server.URL, "--json", "build", "upload", "app_123", zipPath, "--force-bundle")
+	if err != nil {
+		t.Fatalf("upload: %v\n%s", err, stderr)
+	}
+	if strings.Contains(stderr, "retry") {
+		t.Fatalf("expected no retry indicator with --json, got %q", stderr)
+	}
+}
+
+func TestValidateUploadFileRejectsIrregularFiles(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := writeTestZip(t, dir, "MyApp.zip", nil)
+	link := filepath.Join(dir, "Link.zip")
+	if err := os.Symlink(zipPath, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	if err := validateUploadFile(zipPath, false); err != nil {
+		t.Fatalf("expected a regular file to pass, got %v", err)
+	}
+	err := validateUploadFile(link, false)
+	if err == nil || !strings.Contains(err.Error(), "not a regular file (symlink)") || !strings.Contains(err.Error(), "--allow-irregular") {
+		t.Fatalf("expected a symlink error mentioning --allow-irregular, got %v", err)
+	}
+	if err := validateUploadFile(link, true); err != nil {
+		t.Fatalf("expected --allow-irregular to accept the symlink, got %v", err)
+	}
+}
+
+func TestValidateUploadFileAcceptsMixedCaseExtensions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"MyApp.zip", "MyApp.ZIP", "MyApp.Zip", "My.App.zIp"} {
+		path := writeTestZip(t, dir, name, nil)
+		if err := validateUploadFile(path, false); err != nil {
+			t.Errorf("%s: expected to pass, got %v", name, err)
+		}
+		if got := uploadContentType(path); got != "application/zip" {
+			t.Errorf("%s: expected application/zip, got %q", name, got)
+		}
+	}
+	for _, name := range []string{"MyApp.zip.bak", "MyApp.zipx", "MyApp"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		if err := validateUploadFile(path, false); err == nil || !strings.Contains(err.Error(), "only .zip archives") {
+			t.Errorf("%s: expected a .zip error, got %v", name, err)
+		}
+	}
+}
+
+func TestNormalizeZipContentType(t *testing.T) {
+	tests := map[string]string{
+		"application/zip":                             "application/zip",
+		"application/x-zip-compressed":                "application/zip",
+		"Application/X-Zip-Compressed":                "application/zip",
+		"application/x-zip":                           "application/zip",
+		"application/zip-compressed":                  "application/zip",
+		"multipart/x-zip":                             "application/zip",
+		"application/x-zip-compressed; charset=utf-8": "application/zip",
+		"":                 "application/zip",
+		"application/json": "application/json",
+	}
+	for in, want := range tests {
+		if got := normalizeZipContentType(in); got != want {
+			t.Errorf("normalizeZipContentType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCheckOpenedFileRejectsSwappedPath(t *testing.T) {
+	dir := t.TempDir()
+	opened := writeTestZip(t, dir, "Opened.zip", nil)
+	swapped := writeTestZip(t, dir, "Swapped.zip", nil)
+	info, err := os.Stat(opened)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+
+	if err := checkOpenedFile(opened, info); err != nil {
+		t.Fatalf("expected the opened file to pass, got %v", err)
+	}
+	// The path now names a different file than the handle.
+	if err := checkOpenedFile(swapped, info); err == nil || !strings.Contains(err.Error(), "changed while it was being opened") {
+		t.Fatalf("expected a swapped file to be rejected, got %v", err)
+	}
+}
+
+func TestUploadRejectsSymlinkedDirectory(t *testing.T) {
+	server := newUploadServer(t)
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "real"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeTestZip(t, filepath.Join(dir, "real"), "MyApp.zip", nil)
+	if err := os.Symlink(filepath.Join(dir, "real"), filepath.Join(dir, "dist")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	_, _, err = executeCLI(t, server.URL, "build", "upload", "app_123", filepath.Join("dist", "MyApp.zip"))
+	if err == nil || !strings.Contains(err.Error(), "which is a symlink") {
+		t.Fatalf("expected the symlinked directory to be rejected, got %v", err)
+	}
+}
+
+func TestUploadRejectsSymlinkUnlessAllowed(t *testing.T) {
+	server := newUploadServer(t)
+	dir := t.TempDir()
+	zipPath := writeTestZip(t, dir, "MyApp.zip", nil)
+	link := filepath.Join(dir, "Link.zip")
+	if err := os.Symlink(zipPath, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	_, _, err := executeCLI(t, server.URL, "build", "upload", "app_123", link, "--force-bundle")
+	if err == nil || !strings.Contains(err.Error(), "not a regular file") {
+		t.Fatalf("expected the symlink to be rejected, got %v", err)
+	}
+
+	stdout, stderr, err := executeCLI(t, server.URL, "build", "upload", "app_123", link, "--force-bundle", "--allow-irregular")
+	if err != nil {
+		t.Fatalf("upload with --allow-irregular: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Upload complete") {
+		t.Fatalf("expected completion, got %q", stdout)
+	}
+}
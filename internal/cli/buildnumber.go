@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/twinkle-apps/cli/internal/api"
+	"github.com/twinkle-apps/cli/internal/bundle"
+)
+
+// --check-build-number modes.
+const (
+	buildNumberCheckWarn = "warn"
+	buildNumberCheckFail = "fail"
+)
+
+func validateBuildNumberCheck(mode string) error {
+	switch mode {
+	case "", buildNumberCheckWarn, buildNumberCheckFail:
+		return nil
+	default:
+		return fmt.Errorf("--check-build-number must be %q or %q", buildNumberCheckWarn, buildNumberCheckFail)
+	}
+}
+
+// checkBuildNumber compares the archive's CFBundleVersion with the app's
+// latest build, so a build number the server would reject as too low is
+// caught before the upload. It warns or fails depending on
+// req.CheckBuildNumber, and passes when there is nothing to compare.
+func checkBuildNumber(ctx context.Context, stderr io.Writer, client *api.Client, req uploadRequest, verbose, jsonOut bool) error {
+	if req.CheckBuildNumber == "" {
+		return nil
+	}
+	skip := func(reason string) error {
+		if verbose && !jsonOut {
+			Statusf(stderr, "Skipping build number check: %s", reason)
+		}
+		return nil
+	}
+
+	info, err := bundle.ReadInfo(req.FilePath)
+	if err != nil {
+		return skip(err.Error())
+	}
+	if info.BundleVersion == "" {
+		return skip("Info.plist has no CFBundleVersion")
+	}
+
+	latest, err := client.ListBuilds(ctx, req.AppID, api.ListBuildsParams{Limit: 1})
+	if err != nil {
+		return fmt.Errorf("check build number: %w", err)
+	}
+	if len(latest.Builds) == 0 {
+		return skip("the app has no builds yet")
+	}
+	previous := buildNumberOf(latest.Builds[0])
+	if previous == nil || *previous == "" {
+		return skip(fmt.Sprintf("build %d has no build number", latest.Builds[0].ID))
+	}
+
+	cmp, err := compareBuildNumbers(info.BundleVersion, *previous)
+	if err != nil {
+		return skip(err.Error())
+	}
+	if cmp > 0 {
+		return nil
+	}
+
+	problem := fmt.Sprintf("build number %s in %s is not greater than %s (build %d)", info.BundleVersion, filepath.Base(req.FilePath), *previous, latest.Builds[0].ID)
+	if req.CheckBuildNumber == buildNumberCheckFail {
+		return errors.New(problem)
+	}
+	if !jsonOut {
+		Statusf(stderr, "Warning: %s", problem)
+	}
+	return nil
+}
+
+// compareBuildNumbers compares dot-separated numeric build numbers such as
+// "42" or "1.2.10" component by component, treating missing components as
+// zero. It returns -1, 0 or 1.
+func compareBuildNumbers(a, b string) (int, error) {
+	left, err := parseBuildNumber(a)
+	if err != nil {
+		return 0, err
+	}
+	right, err := parseBuildNumber(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < max(len(left), len(right)); i++ {
+		var l, r int
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		switch {
+		case l < r:
+			return -1, nil
+		case l > r:
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func parseBuildNumber(value string) ([]int, error) {
+	parts := strings.Split(strings.TrimSpace(value), ".")
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("build number %q isn't numeric", value)
+		}
+		numbers[i] = n
+	}
+	return numbers, nil
+}
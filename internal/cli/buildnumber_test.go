@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+func TestUploadCheckBuildNumber(t *testing.T) {
+	previous := []api.Build{{ID: 41, Status: "available", BuildNumber: strPtr("42")}}
+
+	tests := []struct {
+		name        string
+		builds      []api.Build
+		buildNumber string
+		flags       []string
+		wantErr     string
+		wantWarning bool
+		wantUpload  bool
+	}{
+		{name: "greater", builds: previous, buildNumber: "43", flags: []string{"--check-build-number", "fail"}, wantUpload: true},
+		{name: "too low fails", builds: previous, buildNumber: "42", flags: []string{"--check-build-number", "fail"}, wantErr: "build number 42 in MyApp.zip is not greater than 42 (build 41)"},
+		{name: "too low warns", builds: previous, buildNumber: "41", flags: []string{"--check-build-number", "warn"}, wantWarning: true, wantUpload: true},
+		{name: "no previous build", builds: nil, buildNumber: "1", flags: []string{"--check-build-number=fail"}, wantUpload: true},
+		{name: "dotted numbers", builds: []api.Build{{ID: 41, BuildNumber: strPtr("1.2.9")}}, buildNumber: "1.2.10", flags: []string{"--check-build-number=fail"}, wantUpload: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newUploadServer(t, withBuilds(tt.builds))
+			zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", map[string]string{"CFBundleVersion": tt.buildNumber})
+
+			args := append([]string{"build", "upload", "app_123", zipPath}, tt.flags...)
+			_, stderr, err := executeCLI(t, server.URL, args...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("upload: %v\n%s", err, stderr)
+			}
+			if got := strings.Contains(stderr, "Warning: build number"); got != tt.wantWarning {
+				t.Fatalf("warning shown = %v, want %v: %q", got, tt.wantWarning, stderr)
+			}
+			if got := containsPath(server.paths(), "/api/v1/apps/app_123/uploads"); got != tt.wantUpload {
+				t.Fatalf("uploaded = %v, want %v", got, tt.wantUpload)
+			}
+			for _, req := range server.requestsTo(http.MethodGet, "/api/v1/apps/app_123/builds") {
+				if req.Query.Get("limit") != "1" {
+					t.Fatalf("expected the latest build only, got limit=%q", req.Query.Get("limit"))
+				}
+			}
+		})
+	}
+}
+
+func TestUploadCheckBuildNumberRejectsUnknownMode(t *testing.T) {
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+	_, _, err := executeCLI(t, "https://example.com", "build", "upload", "app_123", zipPath, "--check-build-number=maybe")
+	if err == nil || !strings.Contains(err.Error(), "--check-build-number must be") {
+		t.Fatalf("expected a mode error, got %v", err)
+	}
+}
+
+func TestUploadCheckBuildNumberNeedsMode(t *testing.T) {
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+	_, _, err := executeCLI(t, "https://example.com", "build", "upload", "app_123", zipPath, "--check-build-number")
+	if err == nil || !strings.Contains(err.Error(), "flag needs an argument") {
+		t.Fatalf("expected a missing value error, got %v", err)
+	}
+}
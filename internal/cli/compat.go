@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+// Supported server API versions: at least minServerAPIVersion, and any
+// minor version of maxServerAPIMajor or older.
+const (
+	minServerAPIVersion = "1.0"
+	maxServerAPIMajor   = 1
+)
+
+// versionCheckTimeout bounds the health request so an unresponsive server
+// doesn't delay the actual command.
+const versionCheckTimeout = 5 * time.Second
+
+// checkServerVersion fails when the server's API version is outside the
+// range this CLI supports, for --strict-version. It asks the health endpoint
+// before the command runs, so nothing is sent to an unsupported server.
+// Servers that don't report a version are not checked.
+func checkServerVersion(ctx context.Context, client *api.Client) error {
+	ctx, cancel := context.WithTimeout(ctx, versionCheckTimeout)
+	defer cancel()
+
+	version, err := client.ServerVersion(ctx)
+	if err != nil || version == "" {
+		return nil
+	}
+	if problem := serverVersionProblem(version); problem != "" {
+		return fmt.Errorf("%s", problem)
+	}
+	return nil
+}
+
+// warnServerVersion returns a version observer that warns on w when the
+// server's API version is outside the supported range. It checks the
+// version the command's own requests report instead of asking up front.
+func warnServerVersion(w io.Writer) func(string) {
+	return func(version string) {
+		if problem := serverVersionProblem(version); problem != "" {
+			Statusf(w, "Warning: %s", problem)
+		}
+	}
+}
+
+// serverVersionProblem describes why version is unsupported, or returns "".
+func serverVersionProblem(version string) string {
+	switch {
+	case compareVersions(version, minServerAPIVersion) < 0:
+		return fmt.Sprintf("server API version %s is older than this CLI supports (minimum %s)", version, minServerAPIVersion)
+	case majorVersion(version) > maxServerAPIMajor:
+		return fmt.Sprintf("server API version %s is newer than this CLI supports (maximum %d.x); upgrade twinkle", version, maxServerAPIMajor)
+	default:
+		return ""
+	}
+}
+
+// compareVersions compares dotted numeric versions component by component,
+// returning -1, 0 or 1. Missing components count as zero and non-numeric
+// suffixes (like "-beta") are ignored.
+func compareVersions(version, bound string) int {
+	versionParts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	boundParts := strings.Split(strings.TrimPrefix(bound, "v"), ".")
+	for i := 0; i < len(versionParts) || i < len(boundParts); i++ {
+		have, want := 0, 0
+		if i < len(versionParts) {
+			have = leadingNumber(versionParts[i])
+		}
+		if i < len(boundParts) {
+			want = leadingNumber(boundParts[i])
+		}
+		switch {
+		case have < want:
+			return -1
+		case have > want:
+			return 1
+		}
+	}
+	return 0
+}
+
+// majorVersion returns the first component of a dotted version.
+func majorVersion(version string) int {
+	major, _, _ := strings.Cut(strings.TrimPrefix(version, "v"), ".")
+	return leadingNumber(major)
+}
+
+func leadingNumber(part string) int {
+	end := 0
+	for end < len(part) && part[end] >= '0' && part[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(part[:end])
+	return n
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+// newVersionedServer serves build 42 and reports apiVersion on every response.
+func newVersionedServer(t *testing.T, apiVersion string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-API-Version", apiVersion)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/v1/health" {
+			_ = json.NewEncoder(w).Encode(api.HealthResponse{Status: "ok", APIVersion: apiVersion})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(api.BuildResponse{
+			Build:   api.Build{ID: 42, Status: "available", UpdatedAt: api.APITime{Time: time.Now()}},
+			Appcast: api.Appcast{Status: "published"},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestServerVersionCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		wantWarn string
+	}{
+		{name: "compatible", version: "1.3"},
+		{name: "too old", version: "0.9", wantWarn: "older than this CLI supports"},
+		{name: "too new", version: "2.0", wantWarn: "newer than this CLI supports"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newVersionedServer(t, tt.version)
+
+			_, stderr, err := executeCLI(t, server.URL, "--json", "build", "status", "app_123", "42")
+			if err != nil {
+				t.Fatalf("expected warning only, got error: %v", err)
+			}
+			if tt.wantWarn == "" {
+				if strings.Contains(stderr, "Warning") {
+					t.Fatalf("expected no warning, got %q", stderr)
+				}
+			} else if !strings.Contains(stderr, tt.wantWarn) {
+				t.Fatalf("expected warning %q, got %q", tt.wantWarn, stderr)
+			}
+
+			_, _, err = executeCLI(t, server.URL, "--strict-version", "--json", "build", "status", "app_123", "42")
+			if tt.wantWarn == "" && err != nil {
+				t.Fatalf("expected strict check to pass, got %v", err)
+			}
+			if tt.wantWarn != "" && (err == nil || !strings.Contains(err.Error(), tt.wantWarn)) {
+				t.Fatalf("expected strict error %q, got %v", tt.wantWarn, err)
+			}
+		})
+	}
+}
+
+func TestServerVersionCheckUsesResponseHeaderWithoutStrict(t *testing.T) {
+	var healthChecks int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/health" {
+			atomic.AddInt32(&healthChecks, 1)
+		}
+		w.Header().Set("X-API-Version", "2.0")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(api.BuildResponse{Build: api.Build{ID: 42, Status: "available"}})
+	}))
+	t.Cleanup(server.Close)
+
+	_, stderr, err := executeCLI(t, server.URL, "--json", "build", "status", "app_123", "42")
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if !strings.Contains(stderr, "newer than this CLI supports") {
+		t.Fatalf("expected a warning from the status response, got %q", stderr)
+	}
+	if got := atomic.LoadInt32(&healthChecks); got != 0 {
+		t.Fatalf("expected no health request without --strict-version, got %d", got)
+	}
+}
+
+func TestServerVersionCheckSkipsUnreportedVersion(t *testing.T) {
+	server, _ := newStatusSequenceServer(t, "available")
+	if _, _, err := executeCLI(t, server.URL, "--strict-version", "--json", "build", "status", "app_123", "42"); err != nil {
+		t.Fatalf("expected no version check without a reported version, got %v", err)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		version string
+		bound   string
+		want    int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.9.3", "1", 1},
+		{"1.2.1", "1.2", 1},
+		{"1.2", "1.2.0", 0},
+		{"v1.2", "1.3", -1},
+		{"2.0-beta", "1", 1},
+		{"0.9", "1.0", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.version, tt.bound); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.version, tt.bound, got, tt.want)
+		}
+	}
+}
+
+func TestServerVersionProblemAcceptsMinorVersions(t *testing.T) {
+	for _, version := range []string{"1.0", "1.9.3", "v1.12"} {
+		if problem := serverVersionProblem(version); problem != "" {
+			t.Errorf("serverVersionProblem(%q) = %q, want none", version, problem)
+		}
+	}
+	if problem := serverVersionProblem("2.0"); !strings.Contains(problem, "maximum 1.x") {
+		t.Errorf("expected 2.0 to be too new, got %q", problem)
+	}
+}
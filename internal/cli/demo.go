@@ -77,9 +77,10 @@ func runStylesDemo(cmd *cobra.Command) error {
 	// Masked secrets
 	cmd.Println("Masked secrets:")
 	cmd.Printf("  Full key:   ABCD1234EFGH5678\n")
-	cmd.Printf("  Masked (4): %s\n", MaskSecret("ABCD1234EFGH5678", 4))
-	cmd.Printf("  Masked (8): %s\n", MaskSecret("ABCD1234EFGH5678", 8))
-	cmd.Printf("  Short key:  %s\n", MaskSecret("ABC", 4))
+	symbols := consoleFor(out).symbols
+	cmd.Printf("  Masked (4): %s\n", symbols.maskSecret("ABCD1234EFGH5678", 4))
+	cmd.Printf("  Masked (8): %s\n", symbols.maskSecret("ABCD1234EFGH5678", 8))
+	cmd.Printf("  Short key:  %s\n", symbols.maskSecret("ABC", 4))
 	cmd.Println()
 
 	// Timing
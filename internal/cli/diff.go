@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+// buildDiff compares the release-relevant fields of two builds of an app.
+type buildDiff struct {
+	AppID     string           `json:"app_id"`
+	From      int              `json:"from"`
+	To        int              `json:"to"`
+	Identical bool             `json:"identical"`
+	Fields    []buildFieldDiff `json:"fields"`
+}
+
+// buildFieldDiff holds one compared field. From and To are the raw values,
+// nil when the build doesn't report the field.
+type buildFieldDiff struct {
+	Field   string      `json:"field"`
+	From    interface{} `json:"from"`
+	To      interface{} `json:"to"`
+	Changed bool        `json:"changed"`
+
+	label    string
+	fromText string
+	toText   string
+}
+
+func newBuildDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <app-id> <build-id> <build-id>",
+		Short: "Compare two builds field by field",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appID := args[0]
+
+			appCtx, err := getAppContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			from, err := appCtx.Client.GetBuild(cmd.Context(), appID, args[1])
+			if err != nil {
+				return fmt.Errorf("get build %s: %w", args[1], err)
+			}
+			to, err := appCtx.Client.GetBuild(cmd.Context(), appID, args[2])
+			if err != nil {
+				return fmt.Errorf("get build %s: %w", args[2], err)
+			}
+
+			return renderOutputWithOptions(cmd, appCtx.outputOptions(), diffBuilds(appID, from.Build, to.Build))
+		},
+	}
+
+	return cmd
+}
+
+// diffBuilds compares version, build number, size, minimum system version and
+// signature of two builds.
+func diffBuilds(appID string, from, to api.Build) buildDiff {
+	fromMeta := buildMetadataOrEmpty(from)
+	toMeta := buildMetadataOrEmpty(to)
+
+	fields := []buildFieldDiff{
+		stringFieldDiff("version", "Version", from.Version, to.Version),
+		stringFieldDiff("build_number", "Build Number", buildNumberOf(from), buildNumberOf(to)),
+		sizeFieldDiff(fromMeta.BuildSize, toMeta.BuildSize),
+		stringFieldDiff("minimum_system_version", "Min System", fromMeta.MinimumSystemVersion, toMeta.MinimumSystemVersion),
+		stringFieldDiff("signature", "Signature", fromMeta.Signature, toMeta.Signature),
+	}
+
+	diff := buildDiff{AppID: appID, From: from.ID, To: to.ID, Identical: true, Fields: fields}
+	for _, field := range fields {
+		if field.Changed {
+			diff.Identical = false
+		}
+	}
+	return diff
+}
+
+func buildMetadataOrEmpty(build api.Build) api.BuildMetadata {
+	if build.Metadata == nil {
+		return api.BuildMetadata{}
+	}
+	return *build.Metadata
+}
+
+// buildNumberOf prefers the build's own build number and falls back to the
+// one read from the bundle.
+func buildNumberOf(build api.Build) *string {
+	if build.BuildNumber != nil {
+		return build.BuildNumber
+	}
+	if build.Metadata != nil {
+		return build.Metadata.BuildNumber
+	}
+	return nil
+}
+
+func stringFieldDiff(field, label string, from, to *string) buildFieldDiff {
+	diff := buildFieldDiff{Field: field, label: label}
+	if from != nil {
+		diff.From = *from
+		diff.fromText = *from
+	}
+	if to != nil {
+		diff.To = *to
+		diff.toText = *to
+	}
+	diff.Changed = (from == nil) != (to == nil) || diff.fromText != diff.toText
+	return diff
+}
+
+func sizeFieldDiff(from, to *int) buildFieldDiff {
+	diff := buildFieldDiff{Field: "size", label: "Build Size"}
+	if from != nil {
+		diff.From = *from
+		diff.fromText = formatBytes(*from)
+	}
+	if to != nil {
+		diff.To = *to
+		diff.toText = formatBytes(*to)
+	}
+	diff.Changed = (from == nil) != (to == nil) || (from != nil && *from != *to)
+	return diff
+}
+
+func init() {
+	registerPrinter(func(cmd *cobra.Command, diff buildDiff, _ outputOptions) { printBuildDiff(cmd, diff) })
+}
+
+func printBuildDiff(cmd *cobra.Command, diff buildDiff) {
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Build %d vs %d\n", diff.From, diff.To)
+
+	width := 0
+	for _, field := range diff.Fields {
+		if len(field.label) > width {
+			width = len(field.label)
+		}
+	}
+	line := func(marker, label, value string) string {
+		if value == "" {
+			value = "(none)"
+		}
+		return fmt.Sprintf("%s %-*s  %s", marker, width, label, value)
+	}
+
+	c := consoleFor(out)
+	for _, field := range diff.Fields {
+		if !field.Changed {
+			fmt.Fprintln(out, c.dim.Render(line(" ", field.label, field.fromText)))
+			continue
+		}
+		fmt.Fprintln(out, c.error.Render(line("-", field.label, field.fromText)))
+		fmt.Fprintln(out, c.success.Render(line("+", field.label, field.toText)))
+	}
+
+	if diff.Identical {
+		Successf(out, "Builds %d and %d are identical", diff.From, diff.To)
+	}
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+func newBuildDiffServer(t *testing.T, builds map[string]api.Build) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/v1/apps/app_123/builds/")
+		build, ok := builds[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(api.BuildResponse{Build: build})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func diffTestBuild(id int, version, buildNumber string, size int) api.Build {
+	minimum := "13.0"
+	signature := "sig-abc"
+	return api.Build{
+		ID:          id,
+		Status:      "available",
+		Version:     &version,
+		BuildNumber: &buildNumber,
+		Metadata: &api.BuildMetadata{
+			BuildSize:            &size,
+			MinimumSystemVersion: &minimum,
+			Signature:            &signature,
+		},
+	}
+}
+
+func TestBuildDiffShowsChangedFields(t *testing.T) {
+	newer := diffTestBuild(42, "1.1.0", "110", 2048)
+	minimum := "14.0"
+	newer.Metadata.MinimumSystemVersion = &minimum
+	server := newBuildDiffServer(t, map[string]api.Build{
+		"41": diffTestBuild(41, "1.0.0", "100", 1024),
+		"42": newer,
+	})
+
+	stdout, _, err := executeCLI(t, server.URL, "build", "diff", "app_123", "41", "42")
+	if err != nil {
+		t.Fatalf("build diff: %v", err)
+	}
+	for _, want := range []string{
+		"Build 41 vs 42",
+		"- Version       1.0.0",
+		"+ Version       1.1.0",
+		"- Build Number  100",
+		"+ Build Number  110",
+		"- Build Size    1.00 KB",
+		"+ Build Size    2.00 KB",
+		"- Min System    13.0",
+		"+ Min System    14.0",
+		"  Signature     sig-abc",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Fatalf("expected %q in diff output:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "identical") {
+		t.Fatalf("expected no identical note:\n%s", stdout)
+	}
+}
+
+func TestBuildDiffJSON(t *testing.T) {
+	server := newBuildDiffServer(t, map[string]api.Build{
+		"41": diffTestBuild(41, "1.0.0", "100", 1024),
+		"42": diffTestBuild(42, "1.1.0", "100", 1024),
+	})
+
+	stdout, _, err := executeCLI(t, server.URL, "--json", "build", "diff", "app_123", "41", "42")
+	if err != nil {
+		t.Fatalf("build diff: %v", err)
+	}
+
+	var got struct {
+		From      int  `json:"from"`
+		To        int  `json:"to"`
+		Identical bool `json:"identical"`
+		Fields    []struct {
+			Field   string      `json:"field"`
+			From    interface{} `json:"from"`
+			To      interface{} `json:"to"`
+			Changed bool        `json:"changed"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("decode diff: %v\n%s", err, stdout)
+	}
+	if got.From != 41 || got.To != 42 || got.Identical || len(got.Fields) != 5 {
+		t.Fatalf("unexpected diff %+v", got)
+	}
+	for _, field := range got.Fields {
+		wantChanged := field.Field == "version"
+		if field.Changed != wantChanged {
+			t.Fatalf("field %s changed = %v, want %v", field.Field, field.Changed, wantChanged)
+		}
+	}
+	if got.Fields[0].From != "1.0.0" || got.Fields[0].To != "1.1.0" {
+		t.Fatalf("unexpected version values %+v", got.Fields[0])
+	}
+	if got.Fields[2].Field != "size" || got.Fields[2].From != float64(1024) {
+		t.Fatalf("expected raw size in JSON, got %+v", got.Fields[2])
+	}
+}
+
+func TestBuildDiffIdentical(t *testing.T) {
+	server := newBuildDiffServer(t, map[string]api.Build{
+		"41": diffTestBuild(41, "1.0.0", "100", 1024),
+		"42": diffTestBuild(42, "1.0.0", "100", 1024),
+	})
+
+	stdout, _, err := executeCLI(t, server.URL, "build", "diff", "app_123", "41", "42")
+	if err != nil {
+		t.Fatalf("build diff: %v", err)
+	}
+	if !strings.Contains(stdout, "Builds 41 and 42 are identical") {
+		t.Fatalf("expected identical note:\n%s", stdout)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		if strings.HasPrefix(line, "-") || strings.HasPrefix(line, "+") {
+			t.Fatalf("expected no changed lines, got %q", line)
+		}
+	}
+}
+
+func TestDiffBuildsMissingFields(t *testing.T) {
+	version := "1.0.0"
+	diff := diffBuilds("app_123", api.Build{ID: 1, Version: &version}, api.Build{ID: 2})
+	if diff.Identical || !diff.Fields[0].Changed || diff.Fields[0].To != nil {
+		t.Fatalf("expected a removed version to count as a change, got %+v", diff.Fields[0])
+	}
+	for _, field := range diff.Fields[1:] {
+		if field.Changed {
+			t.Fatalf("expected fields missing on both builds to be unchanged, got %+v", field)
+		}
+	}
+}
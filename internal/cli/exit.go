@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+// ExitError asks main to exit with Code. A nil Err means the command has
+// already said everything it needs to and nothing more should be printed.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// maskedSecretSuffix is how many trailing characters of a secret stay
+// visible in error output, enough to tell keys apart.
+const maskedSecretSuffix = 4
+
+// authorizationPattern matches credentials in Authorization headers and
+// bearer tokens, capturing the credential itself in group 2.
+var authorizationPattern = regexp.MustCompile(`(?i)(authorization["']?\s*[:=]\s*["']?(?:bearer\s+)?|bearer\s+)([^\s"',]+)`)
+
+// sanitizedError masks secrets in an error's message while keeping the
+// original error available to errors.Is and errors.As.
+type sanitizedError struct {
+	err     error
+	secrets []string
+}
+
+// sanitizeError returns err with the given secrets (such as the API key)
+// and any Authorization values masked in its message.
+func sanitizeError(err error, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+	return &sanitizedError{err: err, secrets: secrets}
+}
+
+func (e *sanitizedError) Error() string {
+	return e.mask(e.err.Error(), unicodeSymbols)
+}
+
+func (e *sanitizedError) Unwrap() error {
+	return e.err
+}
+
+func (e *sanitizedError) mask(text string, symbols symbolSet) string {
+	for _, secret := range e.secrets {
+		if len(secret) <= maskedSecretSuffix {
+			continue
+		}
+		text = strings.ReplaceAll(text, secret, symbols.maskSecret(secret, maskedSecretSuffix))
+	}
+	return authorizationPattern.ReplaceAllStringFunc(text, func(match string) string {
+		groups := authorizationPattern.FindStringSubmatch(match)
+		return groups[1] + symbols.maskSecret(groups[2], maskedSecretSuffix)
+	})
+}
+
+// printError reports err on w. In text mode, details on an API error are
+// listed as indented lines instead of the raw JSON payload; JSON mode keeps
+// the payload in the message for log scrapers. A request that was retried
+// says how many attempts it took, and an API error's request ID comes last,
+// for support requests.
+func printError(w io.Writer, err error, jsonOut bool) {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) && exitErr.Err == nil {
+		return
+	}
+
+	// A sanitizedError's own message is already masked; mask the raw text
+	// once instead, with the symbols this writer uses.
+	msg := err.Error()
+	mask := func(text string) string { return text }
+	var sanitized *sanitizedError
+	if errors.As(err, &sanitized) {
+		symbols := consoleFor(w).symbols
+		mask = func(text string) string { return sanitized.mask(text, symbols) }
+		msg = sanitized.err.Error()
+	}
+
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) {
+		fmt.Fprintln(w, "Error:", mask(msg))
+		return
+	}
+	if apiErr.RequestID != "" {
+		defer printRequestID(w, apiErr.RequestID, jsonOut)
+	}
+	if apiErr.HelpURL != "" {
+		defer printHelpURL(w, mask(apiErr.HelpURL), jsonOut)
+	}
+	if apiErr.Attempts > 1 {
+		defer printAttempts(w, apiErr.Attempts, jsonOut)
+	}
+	if jsonOut || len(apiErr.Details) == 0 {
+		fmt.Fprintln(w, "Error:", mask(msg))
+		return
+	}
+
+	fmt.Fprintln(w, "Error:", mask(strings.Replace(msg, apiErr.Error(), apiErr.Summary(), 1)))
+	// The help URL gets its own line; keep it out of the details listing.
+	details := make(map[string]interface{}, len(apiErr.Details))
+	for key, value := range apiErr.Details {
+		if key != "help_url" {
+			details[key] = value
+		}
+	}
+	lines := make([]string, 0)
+	collectProcessingErrors(details, "", &lines)
+	for _, line := range lines {
+		ErrorDetail(w, mask(line))
+	}
+}
+
+func printHelpURL(w io.Writer, url string, jsonOut bool) {
+	if jsonOut {
+		fmt.Fprintln(w, "See:", url)
+		return
+	}
+	fmt.Fprintln(w, consoleFor(w).dim.Render("  See: "+url))
+}
+
+func printAttempts(w io.Writer, attempts int, jsonOut bool) {
+	if jsonOut {
+		fmt.Fprintln(w, "Attempts:", attempts)
+		return
+	}
+	ErrorDetail(w, fmt.Sprintf("Gave up after %d attempts", attempts))
+}
+
+func printRequestID(w io.Writer, id string, jsonOut bool) {
+	if jsonOut {
+		fmt.Fprintln(w, "Request ID:", id)
+		return
+	}
+	ErrorDetail(w, "Request ID: "+id)
+}
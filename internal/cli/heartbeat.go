@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// newHeartbeatTicker returns a channel that delivers the current time every
+// interval and a function that stops it. Tests replace it to tick on demand.
+var newHeartbeatTicker = func(interval time.Duration) (<-chan time.Time, func()) {
+	ticker := time.NewTicker(interval)
+	return ticker.C, ticker.Stop
+}
+
+// startHeartbeat prints a dim keepalive line to w every interval, so CI
+// runners that kill silent jobs see output during a long wait. It prints
+// even under --json, since w is stderr. Until stop is called, all other
+// output to w must go through the returned writer, which serializes writes
+// with the heartbeat's. An interval of zero or less does nothing.
+func startHeartbeat(w io.Writer, interval time.Duration) (io.Writer, func()) {
+	if interval <= 0 {
+		return w, func() {}
+	}
+	locked := &lockedWriter{w: w}
+	ticks, stopTicker := newHeartbeatTicker(interval)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	start := timeNow()
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-quit:
+				return
+			case tick := <-ticks:
+				Statusf(locked, "Still waiting (%s)", tick.Sub(start).Round(time.Second))
+			}
+		}
+	}()
+	return locked, func() {
+		stopTicker()
+		close(quit)
+		<-done
+	}
+}
+
+// lockedWriter serializes writes to w.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+func TestBuildWaitHeartbeatPrintsAtInterval(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		now      = time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+		interval time.Duration
+		ticks    = make(chan time.Time)
+	)
+	originalNow, originalTicker := timeNow, newHeartbeatTicker
+	timeNow = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	newHeartbeatTicker = func(d time.Duration) (<-chan time.Time, func()) {
+		interval = d
+		return ticks, func() {}
+	}
+	t.Cleanup(func() { timeNow, newHeartbeatTicker = originalNow, originalTicker })
+
+	// The first status request stays silent for three heartbeats.
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/apps/app_123/builds/42" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		status := api.BuildStatusAvailable
+		if requests == 1 {
+			status = api.BuildStatusProcessing
+			for i := 0; i < 3; i++ {
+				mu.Lock()
+				now = now.Add(45 * time.Second)
+				mu.Unlock()
+				ticks <- now
+			}
+		}
+		pollAfter := 10
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(api.BuildResponse{Build: api.Build{ID: 42, Status: status}, PollAfterMs: &pollAfter})
+	}))
+	defer server.Close()
+
+	stdout, stderr, err := executeCLI(t, server.URL, "--json", "build", "wait", "app_123", "42", "--timeout-strategy", "poll", "--heartbeat", "45s")
+	if err != nil {
+		t.Fatalf("build wait: %v\n%s", err, stderr)
+	}
+	if interval != 45*time.Second {
+		t.Fatalf("expected a 45s heartbeat, got %s", interval)
+	}
+
+	var beats []string
+	for _, line := range strings.Split(stderr, "\n") {
+		if strings.Contains(line, "Still waiting") {
+			beats = append(beats, line)
+		}
+	}
+	want := []string{"(45s)", "(1m30s)", "(2m15s)"}
+	if len(beats) != len(want) {
+		t.Fatalf("expected %d heartbeats, got %q", len(want), stderr)
+	}
+	for i, line := range beats {
+		if !strings.HasSuffix(line, want[i]) {
+			t.Fatalf("heartbeat %d: expected %s elapsed, got %q", i+1, want[i], line)
+		}
+	}
+
+	var resp api.BuildResponse
+	if err := json.Unmarshal([]byte(stdout), &resp); err != nil {
+		t.Fatalf("expected only JSON on stdout, got %q: %v", stdout, err)
+	}
+}
+
+func TestBuildWaitRejectsNegativeHeartbeat(t *testing.T) {
+	_, _, err := executeCLI(t, "http://127.0.0.1:1", "build", "wait", "app_123", "42", "--heartbeat", "-1s")
+	if err == nil || !strings.Contains(err.Error(), "--heartbeat must be >= 0") {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+}
+
+func TestRebuildAndCompleteStartHeartbeat(t *testing.T) {
+	var intervals []time.Duration
+	originalTicker := newHeartbeatTicker
+	newHeartbeatTicker = func(d time.Duration) (<-chan time.Time, func()) {
+		intervals = append(intervals, d)
+		return make(chan time.Time), func() {}
+	}
+	t.Cleanup(func() { newHeartbeatTicker = originalTicker })
+
+	rebuildServer, _ := newRebuildServer(t, http.StatusOK)
+	if _, stderr, err := executeCLI(t, rebuildServer.URL, "--json", "build", "rebuild", "app_123", "42", "--wait", "--timeout-strategy", "poll", "--heartbeat", "1m"); err != nil {
+		t.Fatalf("rebuild: %v\n%s", err, stderr)
+	}
+
+	uploadServer := newUploadServer(t)
+	dir := t.TempDir()
+	zipPath := writeTestZip(t, dir, "MyApp.zip", nil)
+	statePath := filepath.Join(dir, "upload.json")
+	if _, _, err := executeCLI(t, uploadServer.URL, "build", "upload", "app_123", zipPath, "--save-state", statePath); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if _, stderr, err := executeCLI(t, uploadServer.URL, "--json", "build", "complete", "--from-state", statePath, "--wait", "--heartbeat", "2m"); err != nil {
+		t.Fatalf("complete: %v\n%s", err, stderr)
+	}
+
+	if len(intervals) != 2 || intervals[0] != time.Minute || intervals[1] != 2*time.Minute {
+		t.Fatalf("expected 1m and 2m heartbeats, got %v", intervals)
+	}
+}
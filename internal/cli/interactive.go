@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+// promptUploadRequest fills in the app, file and version of req by asking on
+// the terminal, then confirms before anything is uploaded.
+func promptUploadRequest(cmd *cobra.Command, client *api.Client, req uploadRequest) (uploadRequest, error) {
+	p, err := newPrompter(cmd)
+	if errors.Is(err, errInputDisabled) {
+		return req, errors.New("--interactive can't be combined with --no-input: pass <app-id> <file> instead")
+	}
+	if errors.Is(err, errNotInteractive) {
+		return req, errors.New("--interactive needs a terminal: pass <app-id> <file> instead")
+	}
+	if err != nil {
+		return req, err
+	}
+	stderr := cmd.ErrOrStderr()
+
+	apps, err := client.ListApps(cmd.Context(), api.ListAppsParams{})
+	if err != nil {
+		Statusf(stderr, "Warning: couldn't list apps: %v", err)
+	}
+	if len(apps) > 0 {
+		fmt.Fprintln(stderr, "Apps:")
+		for i, app := range apps {
+			fmt.Fprintf(stderr, "  %d. %s (%s)\n", i+1, app.Name, app.ID)
+		}
+	}
+
+	answer, err := p.line("App ID, name or number: ")
+	if err != nil {
+		return req, err
+	}
+	if req.AppID, err = resolveAppChoice(apps, answer); err != nil {
+		return req, err
+	}
+
+	if req.FilePath, err = p.line("Build archive (.zip): "); err != nil {
+		return req, err
+	}
+	if err := validateUploadFile(req.FilePath, req.AllowIrregular); err != nil {
+		return req, err
+	}
+
+	versionPrompt := "Version (optional, Enter to skip): "
+	if req.Version != "" {
+		versionPrompt = fmt.Sprintf("Version (Enter for %s): ", req.Version)
+	}
+	version, err := p.line(versionPrompt)
+	if err != nil {
+		return req, err
+	}
+	if version != "" {
+		req.Version = version
+	}
+
+	question := fmt.Sprintf("Upload %s to %s", req.FilePath, req.AppID)
+	if req.Version != "" {
+		question += " as version " + req.Version
+	}
+	ok, err := p.confirm(question + "?")
+	if err != nil {
+		return req, err
+	}
+	if !ok {
+		return req, errors.New("upload cancelled")
+	}
+	return req, nil
+}
+
+// resolveAppChoice maps an answer to an app ID. It accepts a list number, an
+// exact ID, or a unique prefix of an app's ID or name. When no apps could be
+// listed, the answer is used as the ID unchanged.
+func resolveAppChoice(apps []api.App, answer string) (string, error) {
+	if answer == "" {
+		return "", errors.New("an app ID is required")
+	}
+	if len(apps) == 0 {
+		return answer, nil
+	}
+	if n, err := strconv.Atoi(answer); err == nil {
+		if n < 1 || n > len(apps) {
+			return "", fmt.Errorf("choose a number between 1 and %d", len(apps))
+		}
+		return apps[n-1].ID, nil
+	}
+
+	var matches []string
+	lower := strings.ToLower(answer)
+	for _, app := range apps {
+		if app.ID == answer {
+			return app.ID, nil
+		}
+		if strings.HasPrefix(app.ID, answer) || strings.HasPrefix(strings.ToLower(app.Name), lower) {
+			matches = append(matches, app.ID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no app matches %q", answer)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%q matches several apps: %s", answer, strings.Join(matches, ", "))
+	}
+}
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+func TestShipInteractiveUploadsChosenApp(t *testing.T) {
+	fakeTerminal(t)
+	server := newUploadServer(t)
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+	input := strings.Join([]string{"1", zipPath, "1.2.0", "y"}, "\n") + "\n"
+	_, stderr, err := executeCLIWithInput(t, server.URL, input, "ship", "--interactive")
+	if err != nil {
+		t.Fatalf("ship --interactive: %v", err)
+	}
+	if !strings.Contains(stderr, "1. MyApp (app_123)") {
+		t.Fatalf("expected app list on stderr, got %q", stderr)
+	}
+	if !strings.Contains(stderr, "Upload "+zipPath+" to app_123 as version 1.2.0? [y/N]") {
+		t.Fatalf("expected confirmation prompt, got %q", stderr)
+	}
+	if !containsPath(server.paths(), "/api/v1/apps/app_123/uploads/7/complete") {
+		t.Fatalf("expected upload to complete, got paths %v", server.paths())
+	}
+}
+
+func TestShipInteractiveKeepsVersionFlag(t *testing.T) {
+	fakeTerminal(t)
+	server := newUploadServer(t)
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+	input := strings.Join([]string{"1", zipPath, "", "n"}, "\n") + "\n"
+	_, stderr, _ := executeCLIWithInput(t, server.URL, input, "ship", "-i", "--version", "2.0.0")
+	if !strings.Contains(stderr, "Version (Enter for 2.0.0): ") {
+		t.Fatalf("expected --version as the prompt's default, got %q", stderr)
+	}
+	if !strings.Contains(stderr, "as version 2.0.0?") {
+		t.Fatalf("expected Enter to keep --version, got %q", stderr)
+	}
+}
+
+func TestShipUsageShowsOptionalArguments(t *testing.T) {
+	if use := newShipCmd().Use; use != "ship [<app-id> <file>]" {
+		t.Fatalf("expected both arguments marked optional for --interactive, got %q", use)
+	}
+}
+
+func TestShipInteractiveDeclined(t *testing.T) {
+	fakeTerminal(t)
+	server := newUploadServer(t)
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+	input := strings.Join([]string{"myhelper", zipPath, "", "n"}, "\n") + "\n"
+	_, stderr, err := executeCLIWithInput(t, server.URL, input, "ship", "-i")
+	if err == nil || !strings.Contains(err.Error(), "upload cancelled") {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	if !strings.Contains(stderr, "to app_456?") {
+		t.Fatalf("expected name prefix to resolve to app_456, got %q", stderr)
+	}
+	if containsPath(server.paths(), "/api/v1/apps/app_456/uploads") {
+		t.Fatalf("expected no upload after declining, got paths %v", server.paths())
+	}
+}
+
+func TestShipInteractiveRequiresTerminal(t *testing.T) {
+	server := newUploadServer(t)
+
+	_, _, err := executeCLIWithInput(t, server.URL, "1\n", "ship", "--interactive")
+	if err == nil || !strings.Contains(err.Error(), "needs a terminal") {
+		t.Fatalf("expected terminal error, got %v", err)
+	}
+	if containsPath(server.paths(), "/api/v1/apps") {
+		t.Fatalf("expected no requests, got %v", server.paths())
+	}
+}
+
+func TestShipInteractiveRejectsNoInput(t *testing.T) {
+	fakeTerminal(t)
+	server := newUploadServer(t)
+
+	_, stderr, err := executeCLIWithInput(t, server.URL, "1\n", "--no-input", "ship", "--interactive")
+	if err == nil || !strings.Contains(err.Error(), "--no-input") {
+		t.Fatalf("expected a --no-input error, got %v", err)
+	}
+	if strings.Contains(stderr, "App ID") {
+		t.Fatalf("expected no prompt, got %q", stderr)
+	}
+	if containsPath(server.paths(), "/api/v1/apps") {
+		t.Fatalf("expected no requests, got %v", server.paths())
+	}
+}
+
+func TestShipInteractiveRejectsBadFile(t *testing.T) {
+	fakeTerminal(t)
+	server := newUploadServer(t)
+	missing := filepath.Join(t.TempDir(), "missing.zip")
+
+	_, _, err := executeCLIWithInput(t, server.URL, "app_123\n"+missing+"\n", "ship", "--interactive")
+	if err == nil || !strings.Contains(err.Error(), "file not accessible") {
+		t.Fatalf("expected file error, got %v", err)
+	}
+}
+
+func TestResolveAppChoice(t *testing.T) {
+	apps := []api.App{
+		{ID: "app_123", Name: "MyApp"},
+		{ID: "app_124", Name: "Other"},
+	}
+	tests := []struct {
+		answer  string
+		want    string
+		wantErr string
+	}{
+		{answer: "2", want: "app_124"},
+		{answer: "app_123", want: "app_123"},
+		{answer: "my", want: "app_123"},
+		{answer: "app_12", wantErr: "matches several apps"},
+		{answer: "9", wantErr: "between 1 and 2"},
+		{answer: "nope", wantErr: "no app matches"},
+		{answer: "", wantErr: "required"},
+	}
+	for _, tt := range tests {
+		got, err := resolveAppChoice(apps, tt.answer)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("resolveAppChoice(%q) error = %v, want %q", tt.answer, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Fatalf("resolveAppChoice(%q) = %q, %v; want %q", tt.answer, got, err, tt.want)
+		}
+	}
+
+	if got, err := resolveAppChoice(nil, "app_999"); err != nil || got != "app_999" {
+		t.Fatalf("expected unlisted answer to pass through, got %q, %v", got, err)
+	}
+}
+
+func containsPath(paths []string, want string) bool {
+	for _, path := range paths {
+		if path == want {
+			return true
+		}
+	}
+	return false
+}
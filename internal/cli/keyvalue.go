@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// parseKeyValues turns repeated key=value flags into a map. Later values win
+// when a key repeats. kind names the flag in errors.
+func parseKeyValues(kind string, values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]string, len(values))
+	for _, value := range values {
+		key, val, err := splitKeyValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", kind, value, err)
+		}
+		result[key] = val
+	}
+	return result, nil
+}
+
+func splitKeyValue(value string) (string, string, error) {
+	key, val, ok := strings.Cut(value, "=")
+	key = strings.TrimSpace(key)
+	if !ok {
+		return "", "", fmt.Errorf("expected key=value")
+	}
+	if key == "" {
+		return "", "", fmt.Errorf("key is empty")
+	}
+	return key, val, nil
+}
+
+// readKeyValueFile reads key/value pairs from path. A file whose content
+// starts with "{" is read as a JSON object of strings; anything else is read
+// as key=value lines, skipping blank lines and lines starting with #. Unlike
+// flags, whitespace around line values is trimmed.
+func readKeyValueFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("{")) {
+		var raw map[string]interface{}
+		if err := json.Unmarshal(trimmed, &raw); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		result := make(map[string]string, len(raw))
+		for key, value := range raw {
+			text, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("parse %s: value of %q must be a string", path, key)
+			}
+			if strings.TrimSpace(key) == "" {
+				return nil, fmt.Errorf("parse %s: key is empty", path)
+			}
+			result[key] = text
+		}
+		return result, nil
+	}
+
+	result := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, err := splitKeyValue(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		result[key] = strings.TrimSpace(val)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return result, nil
+}
+
+// keyValuesFromFlags merges the pairs in file, if set, with inline
+// key=value flags. Inline values override the file's.
+func keyValuesFromFlags(kind, file string, inline []string) (map[string]string, error) {
+	flags, err := parseKeyValues(kind, inline)
+	if err != nil {
+		return nil, err
+	}
+	if file == "" {
+		return flags, nil
+	}
+	merged, err := readKeyValueFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read %s file: %w", kind, err)
+	}
+	for key, value := range flags {
+		merged[key] = value
+	}
+	if len(merged) == 0 {
+		return nil, nil
+	}
+	return merged, nil
+}
+
+// withKeyValue returns a copy of values with key set to value.
+func withKeyValue(values map[string]string, key, value string) map[string]string {
+	merged := make(map[string]string, len(values)+1)
+	for k, v := range values {
+		merged[k] = v
+	}
+	merged[key] = value
+	return merged
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+func writeKeyValueFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "values")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	return path
+}
+
+func TestParseKeyValuesRejectsEmptyKey(t *testing.T) {
+	for _, value := range []string{"=value", "  =value", "novalue"} {
+		if _, err := parseKeyValues("metadata", []string{value}); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestReadKeyValueFileFormats(t *testing.T) {
+	want := map[string]string{"git_sha": "abc123", "ci_url": "https://ci.example.com/run?id=1&a=b"}
+
+	lines := writeKeyValueFile(t, "# build info\ngit_sha=abc123\n\n  ci_url = https://ci.example.com/run?id=1&a=b\n")
+	got, err := readKeyValueFile(lines)
+	if err != nil {
+		t.Fatalf("read key=value file: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %#v, got %#v", want, got)
+	}
+
+	object := writeKeyValueFile(t, `{"git_sha": "abc123", "ci_url": "https://ci.example.com/run?id=1&a=b"}`)
+	got, err = readKeyValueFile(object)
+	if err != nil {
+		t.Fatalf("read JSON file: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %#v, got %#v", want, got)
+	}
+}
+
+func TestReadKeyValueFileRejectsMalformedFiles(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: "git_sha=abc\nnot a pair\n", want: ":2: expected key=value"},
+		{content: "=abc\n", want: ":1: key is empty"},
+		{content: `{"git_sha": `, want: "parse "},
+		{content: `{"build": 42}`, want: `value of "build" must be a string`},
+	}
+	for _, tt := range tests {
+		_, err := readKeyValueFile(writeKeyValueFile(t, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("content %q: expected error containing %q, got %v", tt.content, tt.want, err)
+		}
+	}
+}
+
+func TestKeyValuesFromFlagsInlineOverridesFile(t *testing.T) {
+	path := writeKeyValueFile(t, "git_sha=from-file\nchannel=beta\n")
+
+	got, err := keyValuesFromFlags("metadata", path, []string{"git_sha=inline", "ci=1"})
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	want := map[string]string{"git_sha": "inline", "channel": "beta", "ci": "1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %#v, got %#v", want, got)
+	}
+
+	if _, err := keyValuesFromFlags("metadata", filepath.Join(t.TempDir(), "missing"), nil); err == nil || !strings.Contains(err.Error(), "read metadata file") {
+		t.Fatalf("expected missing file error, got %v", err)
+	}
+}
+
+func TestHeaderFlagsAreSentWithAPIRequests(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/builds/7") {
+			got = r.Header.Clone()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(api.BuildResponse{Build: api.Build{ID: 7, Status: api.BuildStatusAvailable}})
+	}))
+	defer server.Close()
+
+	path := writeKeyValueFile(t, `{"X-Team": "desktop", "X-Env": "staging"}`)
+	if _, _, err := executeCLI(t, server.URL, "--headers-file", path, "--header", "X-Env=prod", "build", "status", "app_123", "7"); err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if got.Get("X-Team") != "desktop" || got.Get("X-Env") != "prod" {
+		t.Fatalf("expected file and inline headers, got %v", got)
+	}
+}
@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+// shipManifest is the document accepted by `ship --manifest`.
+//
+//	builds:
+//	  - app_id: app_123
+//	    file: ./MyApp.zip
+//	    version: 1.2.0
+type shipManifest struct {
+	Builds []manifestEntry `yaml:"builds"`
+}
+
+type manifestEntry struct {
+	AppID   string `yaml:"app_id"`
+	File    string `yaml:"file"`
+	Version string `yaml:"version"`
+}
+
+// manifestResult is the per-entry outcome of a manifest ship.
+type manifestResult struct {
+	AppID   string `json:"app_id"`
+	File    string `json:"file"`
+	Version string `json:"version,omitempty"`
+	BuildID int    `json:"build_id,omitempty"`
+	Status  string `json:"status"`
+	// AppcastStatus is the build's appcast state, when the entry waited
+	// for it or the build already existed.
+	AppcastStatus string `json:"appcast_status,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+type manifestResults []manifestResult
+
+// manifestStatusCanceled marks entries stopped by --fail-fast.
+const manifestStatusCanceled = "canceled"
+
+// failed reports whether the entry errored, its build failed, or its build
+// is available but the appcast couldn't be published.
+func (r manifestResult) failed() bool {
+	return r.Error != "" || r.Status == string(api.BuildStatusFailed) || r.AppcastStatus == api.AppcastStatusFailed
+}
+
+func loadShipManifest(manifestPath string, allowIrregular bool) (shipManifest, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return shipManifest{}, fmt.Errorf("read manifest: %w", err)
+	}
+	var manifest shipManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return shipManifest{}, fmt.Errorf("parse manifest: %w", err)
+	}
+	if len(manifest.Builds) == 0 {
+		return shipManifest{}, errors.New("manifest has no builds")
+	}
+
+	// Relative file paths are resolved against the manifest's directory so
+	// the manifest can be checked in next to the artifacts.
+	baseDir := filepath.Dir(manifestPath)
+	for i := range manifest.Builds {
+		entry := &manifest.Builds[i]
+		entry.AppID = strings.TrimSpace(entry.AppID)
+		if entry.AppID == "" {
+			return shipManifest{}, fmt.Errorf("manifest entry %d: app_id is required", i+1)
+		}
+		if strings.TrimSpace(entry.File) != "" && !filepath.IsAbs(entry.File) {
+			entry.File = filepath.Join(baseDir, entry.File)
+		}
+		if err := validateUploadFile(entry.File, allowIrregular); err != nil {
+			return shipManifest{}, fmt.Errorf("manifest entry %d: %w", i+1, err)
+		}
+	}
+	return manifest, nil
+}
+
+// runManifestShip uploads every manifest entry using template for the shared
+// wait settings. With failFast, the first failure cancels the uploads still
+// running and skips the ones not yet started.
+func runManifestShip(cmd *cobra.Command, appCtx *AppContext, manifestPath string, concurrency int, failFast bool, template uploadRequest) error {
+	manifest, err := loadShipManifest(manifestPath, template.AllowIrregular)
+	if err != nil {
+		return err
+	}
+	// Per-entry failures are reported in the results; usage text would only
+	// corrupt the JSON on stdout.
+	cmd.SilenceUsage = true
+
+	stderr := cmd.ErrOrStderr()
+	start := time.Now()
+	jsonOut := appCtx.JSON
+
+	if !jsonOut {
+		Statusf(stderr, "Shipping %d builds…", len(manifest.Builds))
+	}
+
+	// Entries upload silently, so one heartbeat covers the whole run.
+	stderr, stopHeartbeat := startHeartbeat(stderr, template.Waiting.heartbeat)
+	defer stopHeartbeat()
+	template.Waiting.heartbeat = 0
+
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		sem      = make(chan struct{}, concurrency)
+		results  = make(manifestResults, len(manifest.Builds))
+		stopping bool // guarded by mu; set once --fail-fast cancels ctx
+	)
+	for i, entry := range manifest.Builds {
+		wg.Add(1)
+		go func(i int, entry manifestEntry) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			req := template
+			req.AppID = entry.AppID
+			req.FilePath = entry.File
+			req.Version = entry.Version
+
+			var result manifestResult
+			if ctx.Err() == nil {
+				result = shipManifestEntry(ctx, appCtx.Client, req)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case stopping && (result.Status == "" || result.failed()):
+				// Not started, or cut short by the first failure.
+				result = manifestResult{AppID: req.AppID, File: req.FilePath, Version: req.Version, Status: manifestStatusCanceled}
+			case result.failed() && failFast:
+				stopping = true
+				cancel()
+			}
+			results[i] = result
+
+			if !jsonOut {
+				switch {
+				case result.Status == manifestStatusCanceled:
+					Statusf(stderr, "%s: %s canceled", result.AppID, filepath.Base(result.File))
+				case result.failed():
+					Errorf(stderr, "%s: %s", result.AppID, filepath.Base(result.File))
+				default:
+					Successf(stderr, "%s: %s", result.AppID, filepath.Base(result.File))
+				}
+			}
+		}(i, entry)
+	}
+	wg.Wait()
+
+	if err := renderOutputWithOptions(cmd, appCtx.outputOptions(), results); err != nil {
+		return err
+	}
+	if !jsonOut {
+		Done(stderr, time.Since(start))
+	}
+
+	failed, canceled := 0, 0
+	for _, result := range results {
+		switch {
+		case result.Status == manifestStatusCanceled:
+			canceled++
+		case result.failed():
+			failed++
+		}
+	}
+	if canceled > 0 {
+		return fmt.Errorf("%d of %d builds failed, %d canceled by --fail-fast", failed, len(results), canceled)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d builds failed", failed, len(results))
+	}
+	return nil
+}
+
+// shipManifestEntry runs the single-upload flow quietly and folds the
+// outcome into a manifestResult.
+func shipManifestEntry(ctx context.Context, client *api.Client, req uploadRequest) manifestResult {
+	result := manifestResult{
+		AppID:   req.AppID,
+		File:    req.FilePath,
+		Version: req.Version,
+	}
+
+	uploaded, err := runUpload(ctx, io.Discard, client, req, 0, true)
+	if err != nil {
+		result.Status = "error"
+		result.Error = err.Error()
+		return result
+	}
+
+	switch {
+	case uploaded.Skipped:
+		result.BuildID = uploaded.Build.Build.ID
+		result.Status = "skipped"
+	case uploaded.Build != nil:
+		result.BuildID = uploaded.Complete.BuildID.Int()
+		result.Status = string(uploaded.Build.Build.Status)
+	default:
+		result.BuildID = uploaded.Complete.BuildID.Int()
+		result.Status = "uploaded"
+	}
+	if uploaded.Build != nil {
+		result.AppcastStatus = uploaded.Build.Appcast.Status
+		if uploaded.Build.Appcast.IsFailed() {
+			result.Error = "appcast failed"
+			if message := uploaded.Build.Appcast.Message; message != "" {
+				result.Error += ": " + message
+			}
+		}
+	}
+	return result
+}
+
+func init() {
+	registerPrinter(func(cmd *cobra.Command, results manifestResults, _ outputOptions) { printManifestResults(cmd, results) })
+}
+
+func printManifestResults(cmd *cobra.Command, results manifestResults) {
+	out := cmd.OutOrStdout()
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "APP\tFILE\tVERSION\tBUILD\tSTATUS")
+	for _, result := range results {
+		version := result.Version
+		if version == "" {
+			version = "-"
+		}
+		buildID := "-"
+		if result.BuildID != 0 {
+			buildID = fmt.Sprintf("%d", result.BuildID)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", result.AppID, filepath.Base(result.File), version, buildID, consoleFor(out).statusKeyword(result.Status))
+	}
+	_ = tw.Flush()
+
+	for _, result := range results {
+		if result.Error != "" {
+			ErrorDetail(out, fmt.Sprintf("%s: %s", result.AppID, result.Error))
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+func TestShipManifestAggregatesResults(t *testing.T) {
+	server := newUploadServer(t, withRoute("/api/v1/apps/app_bad/uploads", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": "version already exists"})
+	}))
+
+	dir := t.TempDir()
+	writeTestZip(t, dir, "Good.zip", nil)
+	writeTestZip(t, dir, "Bad.zip", nil)
+	manifestPath := filepath.Join(dir, "builds.yaml")
+	manifest := `builds:
+  - app_id: app_ok
+    file: Good.zip
+    version: 1.0.0
+  - app_id: app_bad
+    file: Bad.zip
+    version: 2.0.0
+`
+	if err := os.WriteFile(manifestPath, []byte(manifest), 0644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	stdout, _, err := executeCLI(t, server.URL, "--json", "ship", "--manifest", manifestPath)
+	if err == nil {
+		t.Fatal("expected error when a manifest entry fails")
+	}
+	if !strings.Contains(err.Error(), "1 of 2 builds failed") {
+		t.Fatalf("expected aggregate failure error, got %v", err)
+	}
+
+	var results []manifestResult
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("invalid JSON output: %v\nraw: %s", err, stdout)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].AppID != "app_ok" || results[0].Status != "uploaded" || results[0].BuildID != 7 {
+		t.Errorf("unexpected first result: %+v", results[0])
+	}
+	if results[1].AppID != "app_bad" || results[1].Status != "error" || !strings.Contains(results[1].Error, "version already exists") {
+		t.Errorf("unexpected second result: %+v", results[1])
+	}
+}
+
+func TestShipManifestFailsOnFailedAppcast(t *testing.T) {
+	server := newUploadServer(t, withRoute("/api/v1/apps/app_cast/builds/{build}/wait", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(api.BuildResponse{
+			Build:   api.Build{ID: 7, Status: api.BuildStatusAvailable},
+			Appcast: api.Appcast{Status: api.AppcastStatusFailed, Message: "feed upload rejected"},
+		})
+	}))
+
+	dir := t.TempDir()
+	writeTestZip(t, dir, "Good.zip", nil)
+	writeTestZip(t, dir, "Cast.zip", nil)
+	manifestPath := filepath.Join(dir, "builds.yaml")
+	manifest := "builds:\n  - app_id: app_ok\n    file: Good.zip\n  - app_id: app_cast\n    file: Cast.zip\n"
+	if err := os.WriteFile(manifestPath, []byte(manifest), 0644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	stdout, _, err := executeCLI(t, server.URL, "--json", "ship", "--manifest", manifestPath, "--wait")
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 builds failed") {
+		t.Fatalf("expected the failed appcast to fail the run, got %v", err)
+	}
+	var results []manifestResult
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("invalid JSON output: %v\nraw: %s", err, stdout)
+	}
+	if len(results) != 2 || results[0].failed() || results[0].AppcastStatus != "published" {
+		t.Fatalf("expected the first entry to succeed, got %+v", results)
+	}
+	if got := results[1]; got.Status != string(api.BuildStatusAvailable) || got.AppcastStatus != api.AppcastStatusFailed || got.Error != "appcast failed: feed upload rejected" {
+		t.Fatalf("expected the appcast failure recorded, got %+v", got)
+	}
+}
+
+func TestShipManifestRejectsPositionalArgs(t *testing.T) {
+	_, _, err := executeCLI(t, "https://example.com", "ship", "--manifest", "builds.yaml", "app_123", "MyApp.zip")
+	if err == nil {
+		t.Fatal("expected error when mixing --manifest with positional args")
+	}
+}
+
+func TestLoadShipManifestRequiresAppID(t *testing.T) {
+	dir := t.TempDir()
+	writeTestZip(t, dir, "MyApp.zip", nil)
+	manifestPath := filepath.Join(dir, "builds.yaml")
+	if err := os.WriteFile(manifestPath, []byte("builds:\n  - file: MyApp.zip\n"), 0644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	if _, err := loadShipManifest(manifestPath, false); err == nil || !strings.Contains(err.Error(), "app_id is required") {
+		t.Fatalf("expected app_id validation error, got %v", err)
+	}
+}
+
+func TestShipManifestFailFastCancelsInFlightUploads(t *testing.T) {
+	var (
+		started  = make(chan struct{}, 2)
+		canceled = make(chan struct{}, 2)
+		release  = make(chan struct{})
+	)
+	server := newUploadServer(t,
+		withRoute("/storage/{build}", func(w http.ResponseWriter, r *http.Request) {
+			// The body has been read, so the server notices the client going
+			// away.
+			started <- struct{}{}
+			select {
+			case <-r.Context().Done():
+				canceled <- struct{}{}
+			case <-release:
+			}
+		}),
+		withRoute("/api/v1/apps/app_bad/uploads", func(w http.ResponseWriter, r *http.Request) {
+			// Fail only once both other uploads are in flight.
+			for i := 0; i < 2; i++ {
+				select {
+				case <-started:
+				case <-time.After(5 * time.Second):
+				}
+			}
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": "version already exists"})
+		}),
+	)
+	defer close(release)
+
+	dir := t.TempDir()
+	writeTestZip(t, dir, "Slow.zip", nil)
+	writeTestZip(t, dir, "Slower.zip", nil)
+	writeTestZip(t, dir, "Bad.zip", nil)
+	manifestPath := filepath.Join(dir, "builds.yaml")
+	manifest := `builds:
+  - app_id: app_slow
+    file: Slow.zip
+  - app_id: app_bad
+    file: Bad.zip
+  - app_id: app_slower
+    file: Slower.zip
+`
+	if err := os.WriteFile(manifestPath, []byte(manifest), 0644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	start := time.Now()
+	stdout, _, err := executeCLI(t, server.URL, "--json", "ship", "--manifest", manifestPath, "--concurrency", "3", "--fail-fast")
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 builds failed, 2 canceled by --fail-fast") {
+		t.Fatalf("expected a fail-fast error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected fail-fast to return promptly, took %s", elapsed)
+	}
+
+	var results []manifestResult
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("invalid JSON output: %v\nraw: %s", err, stdout)
+	}
+	want := []string{manifestStatusCanceled, "error", manifestStatusCanceled}
+	for i, result := range results {
+		if result.Status != want[i] {
+			t.Errorf("result %d (%s): expected %s, got %+v", i, result.AppID, want[i], result)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-canceled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the in-flight uploads to be canceled")
+		}
+	}
+}
+
+func TestFailFastNeedsManifest(t *testing.T) {
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+	_, _, err := executeCLI(t, "https://example.com", "ship", "app_123", zipPath, "--fail-fast")
+	if err == nil || !strings.Contains(err.Error(), "--fail-fast only applies with --manifest") {
+		t.Fatalf("expected a --manifest error, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+// buildMetadataResult is the output of build metadata. Metadata is nil until
+// the build's bundle has been read.
+type buildMetadataResult struct {
+	AppID    string             `json:"app_id"`
+	BuildID  int                `json:"build_id"`
+	Metadata *api.BuildMetadata `json:"metadata"`
+}
+
+func newBuildMetadataCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "metadata <app-id> <build-id>",
+		Short: "Show the metadata read from a build's bundle",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appID := args[0]
+			buildID := args[1]
+
+			appCtx, err := getAppContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			resp, err := appCtx.Client.GetBuild(cmd.Context(), appID, buildID)
+			if err != nil {
+				return err
+			}
+
+			return renderOutputWithOptions(cmd, appCtx.outputOptions(), buildMetadataResult{
+				AppID:    appID,
+				BuildID:  resp.Build.ID,
+				Metadata: resp.Build.Metadata,
+			})
+		},
+	}
+
+	return cmd
+}
+
+func init() {
+	registerPrinter(printBuildMetadata)
+}
+
+func printBuildMetadata(cmd *cobra.Command, result buildMetadataResult, opts outputOptions) {
+	out := cmd.OutOrStdout()
+	if result.Metadata == nil {
+		Statusf(out, "Build %d has no metadata yet", result.BuildID)
+		return
+	}
+
+	meta := result.Metadata
+	fmt.Fprintf(out, "Build %d metadata\n", result.BuildID)
+	rows := []detailRow{
+		{Key: "Version", Value: metadataValue(meta.BuildVersion)},
+		{Key: "Build Number", Value: metadataValue(meta.BuildNumber)},
+		{Key: "Build Size", Value: "n/a"},
+		{Key: "Minimum System", Value: metadataValue(meta.MinimumSystemVersion)},
+		{Key: "Signature", Value: metadataValue(meta.Signature)},
+		{Key: "Icon URL", Value: metadataValue(meta.IconURL)},
+	}
+	if meta.BuildSize != nil {
+		rows[2].Value = formatBytes(*meta.BuildSize)
+	}
+	printDetails(out, rows, opts.Pretty)
+	printReleaseNotes(out, meta.ReleaseNotes)
+}
+
+func metadataValue(value *string) string {
+	if value == nil || *value == "" {
+		return "n/a"
+	}
+	return *value
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+func TestBuildMetadataPrintsFields(t *testing.T) {
+	build := diffTestBuild(41, "1.0.0", "100", 2048)
+	icon := "https://cdn.example.com/icon.png"
+	version := "1.0.0"
+	build.Metadata.IconURL = &icon
+	build.Metadata.BuildVersion = &version
+	server := newBuildDiffServer(t, map[string]api.Build{"41": build})
+
+	stdout, _, err := executeCLI(t, server.URL, "build", "metadata", "app_123", "41")
+	if err != nil {
+		t.Fatalf("build metadata: %v", err)
+	}
+	for _, want := range []string{
+		"Build 41 metadata",
+		"  Version: 1.0.0\n",
+		"  Build Number: n/a\n",
+		"  Build Size: 2.00 KB\n",
+		"  Minimum System: 13.0\n",
+		"  Signature: sig-abc\n",
+		"  Icon URL: " + icon + "\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Fatalf("expected %q in output:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestBuildMetadataJSON(t *testing.T) {
+	server := newBuildDiffServer(t, map[string]api.Build{"41": diffTestBuild(41, "1.0.0", "100", 2048)})
+
+	stdout, _, err := executeCLI(t, server.URL, "--json", "build", "metadata", "app_123", "41")
+	if err != nil {
+		t.Fatalf("build metadata: %v", err)
+	}
+	var result struct {
+		BuildID  int                `json:"build_id"`
+		Metadata *api.BuildMetadata `json:"metadata"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("decode output: %v\n%s", err, stdout)
+	}
+	if result.BuildID != 41 || result.Metadata == nil || *result.Metadata.BuildSize != 2048 {
+		t.Fatalf("unexpected metadata output: %s", stdout)
+	}
+}
+
+func TestBuildMetadataReportsMissingMetadata(t *testing.T) {
+	server := newBuildDiffServer(t, map[string]api.Build{"41": {ID: 41, Status: api.BuildStatusProcessing}})
+
+	stdout, _, err := executeCLI(t, server.URL, "build", "metadata", "app_123", "41")
+	if err != nil {
+		t.Fatalf("build metadata: %v", err)
+	}
+	if !strings.Contains(stdout, "Build 41 has no metadata yet") {
+		t.Fatalf("expected missing metadata note, got %q", stdout)
+	}
+
+	stdout, _, err = executeCLI(t, server.URL, "--json", "build", "metadata", "app_123", "41")
+	if err != nil {
+		t.Fatalf("build metadata --json: %v", err)
+	}
+	if !strings.Contains(stdout, `"metadata": null`) {
+		t.Fatalf("expected null metadata in JSON, got %s", stdout)
+	}
+}
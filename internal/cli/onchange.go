@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+// runShellCommand runs command through the platform shell with env added to
+// the environment, sending its output to out. Tests replace it.
+var runShellCommand = func(ctx context.Context, command string, env []string, out io.Writer) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	// Don't wait on output from children the command left running.
+	cmd.WaitDelay = time.Second
+	return cmd.Run()
+}
+
+// onChangeTimeout caps each --on-change run. Tests shorten it.
+var onChangeTimeout = 30 * time.Second
+
+// onChangeHook returns a pollOptions.OnChange that runs command with the new
+// status in TWINKLE_BUILD_STATUS. The command's output goes to stderr, so
+// stdout stays parseable, and a failing command only warns. A run is killed
+// after onChangeTimeout or when the wait's deadline passes, whichever comes
+// first, so a hung hook can't hold up the wait.
+func onChangeHook(stderr io.Writer, command, appID string) func(ctx context.Context, previous api.BuildStatus, resp api.BuildResponse) {
+	return func(ctx context.Context, previous api.BuildStatus, resp api.BuildResponse) {
+		ctx, cancel := context.WithTimeout(ctx, onChangeTimeout)
+		defer cancel()
+		env := []string{
+			"TWINKLE_APP_ID=" + appID,
+			"TWINKLE_BUILD_ID=" + strconv.Itoa(resp.Build.ID),
+			"TWINKLE_BUILD_STATUS=" + string(resp.Build.Status),
+			"TWINKLE_PREVIOUS_STATUS=" + string(previous),
+		}
+		if err := runShellCommand(ctx, command, env, stderr); err != nil {
+			if ctx.Err() != nil {
+				err = fmt.Errorf("stopped after it ran too long (%w)", ctx.Err())
+			}
+			Statusf(stderr, "Warning: --on-change command failed for status %s: %v", resp.Build.Status, err)
+		}
+	}
+}
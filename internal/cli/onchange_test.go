@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeShell replaces runShellCommand, recording each run's environment and
+// failing with err.
+func fakeShell(t *testing.T, err error) func() [][]string {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		runs [][]string
+	)
+	original := runShellCommand
+	runShellCommand = func(_ context.Context, command string, env []string, _ io.Writer) error {
+		mu.Lock()
+		defer mu.Unlock()
+		runs = append(runs, append([]string{command}, env...))
+		return err
+	}
+	t.Cleanup(func() { runShellCommand = original })
+	return func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return runs
+	}
+}
+
+func TestBuildWaitOnChangeRunsForEachTransition(t *testing.T) {
+	runs := fakeShell(t, nil)
+	server, _ := newStatusSequenceServer(t, "queued", "processing", "processing", "available")
+
+	_, stderr, err := executeCLI(t, server.URL, "build", "wait", "app_123", "42", "--timeout-strategy", "poll", "--on-change", "./notify.sh")
+	if err != nil {
+		t.Fatalf("build wait: %v\n%s", err, stderr)
+	}
+
+	var transitions []string
+	for _, run := range runs() {
+		if run[0] != "./notify.sh" {
+			t.Fatalf("expected the --on-change command, got %q", run[0])
+		}
+		transitions = append(transitions, envValue(run[1:], "TWINKLE_PREVIOUS_STATUS")+"->"+envValue(run[1:], "TWINKLE_BUILD_STATUS"))
+		if envValue(run[1:], "TWINKLE_BUILD_ID") != "42" || envValue(run[1:], "TWINKLE_APP_ID") != "app_123" {
+			t.Fatalf("expected build and app IDs in the environment, got %v", run[1:])
+		}
+	}
+	want := []string{"->queued", "queued->processing", "processing->available"}
+	if !reflect.DeepEqual(transitions, want) {
+		t.Fatalf("expected transitions %v, got %v", want, transitions)
+	}
+}
+
+func TestBuildWaitOnChangeFailureOnlyWarns(t *testing.T) {
+	fakeShell(t, errors.New("exit status 1"))
+	server, _ := newStatusSequenceServer(t, "processing", "available")
+
+	stdout, stderr, err := executeCLI(t, server.URL, "build", "wait", "app_123", "42", "--timeout-strategy", "poll", "--on-change", "false")
+	if err != nil {
+		t.Fatalf("expected the wait to succeed despite the hook, got %v", err)
+	}
+	if !strings.Contains(stderr, "Warning: --on-change command failed for status processing: exit status 1") {
+		t.Fatalf("expected a warning, got %q", stderr)
+	}
+	if !strings.Contains(stdout, "Build 42 processed") {
+		t.Fatalf("expected the wait to finish, got %q", stdout)
+	}
+}
+
+func TestBuildWaitOnChangeIsBounded(t *testing.T) {
+	originalShell, originalTimeout := runShellCommand, onChangeTimeout
+	t.Cleanup(func() { runShellCommand, onChangeTimeout = originalShell, originalTimeout })
+	onChangeTimeout = 50 * time.Millisecond
+	var deadlines []time.Time
+	runShellCommand = func(ctx context.Context, _ string, _ []string, _ io.Writer) error {
+		deadline, _ := ctx.Deadline()
+		deadlines = append(deadlines, deadline)
+		<-ctx.Done()
+		return errors.New("signal: killed")
+	}
+	server, _ := newStatusSequenceServer(t, "processing", "available")
+
+	start := time.Now()
+	_, stderr, err := executeCLI(t, server.URL, "build", "wait", "app_123", "42", "--timeout-strategy", "poll", "--on-change", "sleep 600")
+	if err != nil {
+		t.Fatalf("build wait: %v\n%s", err, stderr)
+	}
+	if len(deadlines) != 2 || time.Since(start) > 5*time.Second {
+		t.Fatalf("expected both hooks to be cut short, got %d runs in %s", len(deadlines), time.Since(start))
+	}
+	if !strings.Contains(stderr, "--on-change command failed for status processing: stopped after it ran too long") {
+		t.Fatalf("expected a timeout warning, got %q", stderr)
+	}
+
+	// With --timeout, the wait's deadline bounds the hook too.
+	onChangeTimeout = time.Hour
+	runShellCommand = func(ctx context.Context, _ string, _ []string, _ io.Writer) error {
+		deadline, _ := ctx.Deadline()
+		deadlines = append(deadlines, deadline)
+		return nil
+	}
+	deadlines = nil
+	server, _ = newStatusSequenceServer(t, "available")
+	start = time.Now()
+	if _, stderr, err := executeCLI(t, server.URL, "build", "wait", "app_123", "42", "--timeout-strategy", "poll", "--timeout", "30", "--on-change", "./notify.sh"); err != nil {
+		t.Fatalf("build wait: %v\n%s", err, stderr)
+	}
+	if len(deadlines) != 1 || deadlines[0].IsZero() || deadlines[0].After(start.Add(31*time.Second)) {
+		t.Fatalf("expected the hook to end with the 30s wait, got deadlines %v", deadlines)
+	}
+}
+
+func envValue(env []string, key string) string {
+	for _, entry := range env {
+		if value, ok := strings.CutPrefix(entry, key+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+// openBrowser opens target with the platform's default handler. Tests replace it.
+// On Windows it avoids cmd.exe, which would interpret metacharacters such as
+// & in the URL.
+var openBrowser = func(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	return cmd.Start()
+}
+
+func newBuildOpenCmd() *cobra.Command {
+	var printURL bool
+
+	cmd := &cobra.Command{
+		Use:   "open <app-id> <build-id>",
+		Short: "Open a build's appcast feed in the browser",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appID := args[0]
+			buildID := args[1]
+
+			appCtx, err := getAppContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			if !printURL && (!isTerminal(cmd.OutOrStdout()) || os.Getenv("CI") != "") {
+				return errors.New("refusing to open a browser without a terminal: pass --print-url to print the URL instead")
+			}
+
+			resp, err := appCtx.Client.GetBuild(cmd.Context(), appID, buildID)
+			if err != nil {
+				return err
+			}
+			target, err := buildOpenURL(resp)
+			if err != nil {
+				return err
+			}
+
+			if printURL {
+				fmt.Fprintln(cmd.OutOrStdout(), target)
+				return nil
+			}
+			if err := openBrowser(target); err != nil {
+				return fmt.Errorf("open %s: %w", target, err)
+			}
+			Statusf(cmd.ErrOrStderr(), "Opened %s", target)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&printURL, "print-url", false, "Print the URL instead of opening it")
+
+	return cmd
+}
+
+// buildOpenURL picks the page to open for a build: the appcast feed, or the
+// appcast's web page when there's no feed yet. The URL comes from the server
+// and is handed to the system's URL handler, so only http and https are
+// accepted.
+func buildOpenURL(resp api.BuildResponse) (string, error) {
+	raw := ""
+	switch {
+	case resp.Appcast.FeedURL != "":
+		raw = resp.Appcast.FeedURL
+	case resp.Appcast.URL != nil && *resp.Appcast.URL != "":
+		raw = *resp.Appcast.URL
+	default:
+		return "", fmt.Errorf("build %d has no feed or appcast URL yet", resp.Build.ID)
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || (!strings.EqualFold(parsed.Scheme, "http") && !strings.EqualFold(parsed.Scheme, "https")) || parsed.Host == "" {
+		return "", fmt.Errorf("build %d has a feed URL that isn't http or https: %q", resp.Build.ID, raw)
+	}
+	return raw, nil
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+// fakeOpener records URLs instead of launching a browser.
+func fakeOpener(t *testing.T) *[]string {
+	t.Helper()
+	var opened []string
+	original := openBrowser
+	openBrowser = func(url string) error {
+		opened = append(opened, url)
+		return nil
+	}
+	t.Cleanup(func() { openBrowser = original })
+	return &opened
+}
+
+func TestBuildOpenURL(t *testing.T) {
+	portal := "https://example.com/apps/app_123"
+	script := "javascript:alert(1)"
+	tests := []struct {
+		name    string
+		resp    api.BuildResponse
+		want    string
+		wantErr bool
+	}{
+		{name: "feed", resp: api.BuildResponse{Appcast: api.Appcast{FeedURL: "https://example.com/feed.xml", URL: &portal}}, want: "https://example.com/feed.xml"},
+		{name: "portal fallback", resp: api.BuildResponse{Appcast: api.Appcast{URL: &portal}}, want: portal},
+		{name: "none", resp: api.BuildResponse{Build: api.Build{ID: 42}}, wantErr: true},
+		{name: "file feed", resp: api.BuildResponse{Appcast: api.Appcast{FeedURL: "file:///etc/passwd"}}, wantErr: true},
+		{name: "script portal", resp: api.BuildResponse{Appcast: api.Appcast{URL: &script}}, wantErr: true},
+		{name: "no host", resp: api.BuildResponse{Appcast: api.Appcast{FeedURL: "https:feed.xml"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildOpenURL(tt.resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildOpenURL error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("buildOpenURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildOpenPrintURL(t *testing.T) {
+	opened := fakeOpener(t)
+	server, _ := newStatusSequenceServer(t, "available")
+
+	stdout, _, err := executeCLI(t, server.URL, "build", "open", "app_123", "42", "--print-url")
+	if err != nil {
+		t.Fatalf("build open: %v", err)
+	}
+	if stdout != "https://example.com/feed.xml\n" {
+		t.Fatalf("unexpected output %q", stdout)
+	}
+	if len(*opened) != 0 {
+		t.Fatalf("expected nothing opened, got %v", *opened)
+	}
+}
+
+func TestBuildOpenLaunchesBrowser(t *testing.T) {
+	fakeTerminal(t)
+	t.Setenv("CI", "")
+	opened := fakeOpener(t)
+	server, _ := newStatusSequenceServer(t, "available")
+
+	if _, _, err := executeCLI(t, server.URL, "build", "open", "app_123", "42"); err != nil {
+		t.Fatalf("build open: %v", err)
+	}
+	if len(*opened) != 1 || (*opened)[0] != "https://example.com/feed.xml" {
+		t.Fatalf("expected the feed to open, got %v", *opened)
+	}
+}
+
+func TestBuildOpenRefusesWithoutTerminal(t *testing.T) {
+	opened := fakeOpener(t)
+	server, paths := newStatusSequenceServer(t, "available")
+
+	_, _, err := executeCLI(t, server.URL, "build", "open", "app_123", "42")
+	if err == nil || !strings.Contains(err.Error(), "--print-url") {
+		t.Fatalf("expected a refusal, got %v", err)
+	}
+	if len(*opened) != 0 || len(paths()) != 0 {
+		t.Fatalf("expected no requests or browser, got %v and %v", paths(), *opened)
+	}
+}
+
+func TestBuildOpenRefusesInCI(t *testing.T) {
+	fakeTerminal(t)
+	t.Setenv("CI", "true")
+	opened := fakeOpener(t)
+	server, _ := newStatusSequenceServer(t, "available")
+
+	if _, _, err := executeCLI(t, server.URL, "build", "open", "app_123", "42"); err == nil {
+		t.Fatal("expected a refusal in CI")
+	}
+	if len(*opened) != 0 {
+		t.Fatalf("expected nothing opened, got %v", *opened)
+	}
+}
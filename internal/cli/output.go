@@ -1,30 +1,175 @@
 package cli
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
 	"github.com/spf13/cobra"
 
 	"github.com/twinkle-apps/cli/internal/api"
 )
 
-// Styles for terminal output
+// symbolSet holds the glyphs used by the output helpers
+type symbolSet struct {
+	Status  string
+	Success string
+	Error   string
+	Detail  string
+	Mask    string
+}
+
 var (
-	dimStyle         = lipgloss.NewStyle().Faint(true)
-	successStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))          // green
-	errorStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))           // red
-	errorDetailStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Faint(true) // dim red
+	unicodeSymbols = symbolSet{Status: "·", Success: "✓", Error: "✕", Detail: "↳", Mask: "●"}
+	asciiSymbols   = symbolSet{Status: "-", Success: "[OK]", Error: "[X]", Detail: "->", Mask: "*"}
+)
+
+// console is how one run renders its output helpers. The root command picks
+// one for each of the command's stdout and stderr from the flags and
+// environment and attaches it to that stream, so the helpers find it on the
+// writer they're given.
+type console struct {
+	symbols symbolSet
+
+	// renderer holds the stream's color profile; the styles render with it.
+	renderer    *lipgloss.Renderer
+	dim         lipgloss.Style
+	success     lipgloss.Style // green
+	error       lipgloss.Style // red
+	errorDetail lipgloss.Style // dim red
+}
+
+func newConsole(w io.Writer, symbols symbolSet, profile termenv.Profile) *console {
+	renderer := lipgloss.NewRenderer(w)
+	renderer.SetColorProfile(profile)
+	return &console{
+		symbols:     symbols,
+		renderer:    renderer,
+		dim:         renderer.NewStyle().Faint(true),
+		success:     renderer.NewStyle().Foreground(lipgloss.Color("10")),
+		error:       renderer.NewStyle().Foreground(lipgloss.Color("9")),
+		errorDetail: renderer.NewStyle().Foreground(lipgloss.Color("9")).Faint(true),
+	}
+}
+
+// plainConsole is used for writers that have no console attached, such as
+// output files and test buffers. It never colors.
+var plainConsole = newConsole(io.Discard, unicodeSymbols, termenv.Ascii)
+
+// consoleWriter is a stream with the console to render it with.
+type consoleWriter struct {
+	io.Writer
+	console *console
+}
+
+// withConsole attaches c to w, replacing any console already attached.
+func withConsole(w io.Writer, c *console) io.Writer {
+	return &consoleWriter{Writer: unwrapConsole(w), console: c}
+}
+
+// attachConsole attaches a console to w that colors according to the
+// --color mode as resolved against w itself.
+func attachConsole(w io.Writer, symbols symbolSet, colorMode string) (io.Writer, error) {
+	w = unwrapConsole(w)
+	profile, err := colorProfileFor(colorMode, w)
+	if err != nil {
+		return nil, err
+	}
+	return withConsole(w, newConsole(w, symbols, profile)), nil
+}
+
+// unwrapConsole returns the stream under any attached console.
+func unwrapConsole(w io.Writer) io.Writer {
+	if cw, ok := w.(*consoleWriter); ok {
+		return cw.Writer
+	}
+	return w
+}
+
+// consoleFor returns the console attached to w, looking through the
+// writers this package wraps streams in, or plainConsole.
+func consoleFor(w io.Writer) *console {
+	switch typed := w.(type) {
+	case *consoleWriter:
+		return typed.console
+	case *lockedWriter:
+		return consoleFor(typed.w)
+	}
+	return plainConsole
+}
+
+// selectSymbols picks the ASCII set when forced or when the environment
+// can't be trusted to render UTF-8.
+func selectSymbols(ascii bool, getenv func(string) string, goos string) symbolSet {
+	if ascii {
+		return asciiSymbols
+	}
+	for _, key := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value := getenv(key); value != "" {
+			if isUTF8Locale(value) {
+				return unicodeSymbols
+			}
+			return asciiSymbols
+		}
+	}
+	// Legacy Windows consoles default to a non-UTF-8 code page; Windows
+	// Terminal sets WT_SESSION and renders UTF-8 fine.
+	if goos == "windows" && getenv("WT_SESSION") == "" {
+		return asciiSymbols
+	}
+	return unicodeSymbols
+}
+
+// --color modes
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
 )
 
+// colorProfileFor resolves a --color mode against out. auto colors terminals
+// and honors NO_COLOR and CLICOLOR_FORCE; always colors even when piped, at
+// least with the basic ANSI palette.
+func colorProfileFor(mode string, out io.Writer) (termenv.Profile, error) {
+	switch mode {
+	case colorAuto:
+		return termenv.NewOutput(out).EnvColorProfile(), nil
+	case colorAlways:
+		profile := termenv.NewOutput(out, termenv.WithUnsafe()).ColorProfile()
+		if profile == termenv.Ascii {
+			profile = termenv.ANSI
+		}
+		return profile, nil
+	case colorNever:
+		return termenv.Ascii, nil
+	default:
+		return termenv.Ascii, fmt.Errorf("invalid --color %q: must be %s, %s or %s", mode, colorAuto, colorAlways, colorNever)
+	}
+}
+
+func isUTF8Locale(locale string) bool {
+	normalized := strings.ToLower(strings.ReplaceAll(locale, "-", ""))
+	return strings.Contains(normalized, "utf8")
+}
+
 // Status prints a dimmed status message with a · prefix (for in-progress operations)
 func Status(w io.Writer, msg string) {
-	fmt.Fprintf(w, "%s %s\n", dimStyle.Render("·"), dimStyle.Render(msg))
+	c := consoleFor(w)
+	fmt.Fprintf(w, "%s %s\n", c.dim.Render(c.symbols.Status), c.dim.Render(msg))
 }
 
 // Statusf prints a formatted dimmed status message
@@ -34,8 +179,9 @@ func Statusf(w io.Writer, format string, args ...interface{}) {
 
 // Success prints a green checkmark followed by a message
 func Success(w io.Writer, msg string) {
-	checkmark := successStyle.Render("✓")
-	fmt.Fprintf(w, "%s %s\n", checkmark, successStyle.Render(msg))
+	c := consoleFor(w)
+	checkmark := c.success.Render(c.symbols.Success)
+	fmt.Fprintf(w, "%s %s\n", checkmark, c.success.Render(msg))
 }
 
 // Successf prints a formatted success message with checkmark
@@ -45,7 +191,8 @@ func Successf(w io.Writer, format string, args ...interface{}) {
 
 // Error prints a red ✕ followed by a message
 func Error(w io.Writer, msg string) {
-	fmt.Fprintf(w, "%s %s\n", errorStyle.Render("✕"), errorStyle.Render(msg))
+	c := consoleFor(w)
+	fmt.Fprintf(w, "%s %s\n", c.error.Render(c.symbols.Error), c.error.Render(msg))
 }
 
 // Errorf prints a formatted error message with ✕
@@ -55,90 +202,324 @@ func Errorf(w io.Writer, format string, args ...interface{}) {
 
 // ErrorDetail prints an indented error detail line with a ↳ connector
 func ErrorDetail(w io.Writer, msg string) {
-	fmt.Fprintf(w, "  %s %s\n", errorStyle.Render("↳"), errorDetailStyle.Render(msg))
+	c := consoleFor(w)
+	fmt.Fprintf(w, "  %s %s\n", c.error.Render(c.symbols.Detail), c.errorDetail.Render(msg))
 }
 
 // MaskSecret masks all but the last `show` characters of a secret
 // Example: MaskSecret("ABCD1234EFGH", 4) returns "●●●●●●●●EFGH"
 func MaskSecret(secret string, show int) string {
+	return unicodeSymbols.maskSecret(secret, show)
+}
+
+// maskSecret is MaskSecret with this set's mask glyph.
+func (s symbolSet) maskSecret(secret string, show int) string {
 	if len(secret) <= show {
 		return secret
 	}
-	masked := strings.Repeat("●", len(secret)-show)
+	masked := strings.Repeat(s.Mask, len(secret)-show)
 	return masked + secret[len(secret)-show:]
 }
 
 // Done prints the completion time in a dimmed, indented format
 func Done(w io.Writer, elapsed time.Duration) {
-	fmt.Fprintln(w, dimStyle.Render(fmt.Sprintf("  Done in %.1fs", elapsed.Seconds())))
+	fmt.Fprintln(w, consoleFor(w).dim.Render(fmt.Sprintf("  Done in %.1fs", elapsed.Seconds())))
 }
 
 // VerboseStatus prints a status with timing information (for verbose mode)
 func VerboseStatus(w io.Writer, msg string, elapsed time.Duration) {
-	fmt.Fprintln(w, dimStyle.Render(fmt.Sprintf("· %s (%.1fs)", msg, elapsed.Seconds())))
+	c := consoleFor(w)
+	fmt.Fprintln(w, c.dim.Render(fmt.Sprintf("%s %s (%.1fs)", c.symbols.Status, msg, elapsed.Seconds())))
+}
+
+// outputOptions controls how command results are rendered.
+type outputOptions struct {
+	JSON    bool
+	Verbose bool
+	// Verbosity is the -v count; 2 and up adds timestamps and timings.
+	Verbosity int
+	// NoAppcast hides the appcast status block for successful builds.
+	NoAppcast bool
+	// OutputFile, when set to anything but "-", receives the rendered
+	// result instead of stdout.
+	OutputFile string
+	// AppendOutput appends each result to OutputFile as one record, with
+	// JSON compacted to a single line, instead of replacing the file.
+	AppendOutput bool
+	// Pretty draws verbose details as an aligned table on terminals.
+	Pretty bool
+	// Template, when set, replaces the built-in text rendering.
+	Template *template.Template
+	// JSONEnvelope wraps JSON output in a versioned envelope.
+	JSONEnvelope bool
+	// JSONCanonical sorts the keys of every JSON object, struct fields
+	// included, and leaves HTML characters unescaped.
+	JSONCanonical bool
+}
+
+// jsonSchemaVersion is reported in --json-envelope output. Bump it whenever
+// the shape of a JSON payload changes in a way consumers could notice.
+const jsonSchemaVersion = 1
+
+// jsonEnvelope wraps a payload with the schema version it was written for.
+type jsonEnvelope struct {
+	SchemaVersion int         `json:"schema_version"`
+	Data          interface{} `json:"data"`
 }
 
 func renderOutput(cmd *cobra.Command, jsonOut bool, verbose bool, payload interface{}) error {
-	if jsonOut {
+	return renderOutputWithOptions(cmd, outputOptions{JSON: jsonOut, Verbose: verbose}, payload)
+}
+
+func renderOutputWithOptions(cmd *cobra.Command, opts outputOptions, payload interface{}) error {
+	if opts.OutputFile != "" && opts.OutputFile != "-" {
+		return renderOutputToFile(opts, payload)
+	}
+
+	if opts.Template != nil {
+		return renderTemplate(cmd.OutOrStdout(), opts.Template, payload)
+	}
+
+	if opts.JSON {
+		if opts.JSONEnvelope {
+			payload = jsonEnvelope{SchemaVersion: jsonSchemaVersion, Data: payload}
+		}
 		encoder := json.NewEncoder(cmd.OutOrStdout())
-		encoder.SetIndent("", "  ")
+		if !opts.AppendOutput {
+			encoder.SetIndent("", "  ")
+		}
+		if opts.JSONCanonical {
+			canonical, err := canonicalJSON(payload)
+			if err != nil {
+				return err
+			}
+			payload = canonical
+			encoder.SetEscapeHTML(false)
+		}
 		return encoder.Encode(payload)
 	}
 
-	switch value := payload.(type) {
-	case api.BuildResponse:
-		printBuildResponse(cmd, value, verbose)
-	case api.BuildUploadCompleteResponse:
-		printUploadComplete(cmd, value, verbose)
-	default:
+	printer, ok := printers[reflect.TypeOf(payload)]
+	if !ok {
 		return fmt.Errorf("unsupported output type %T", payload)
 	}
+	printer(cmd, payload, opts)
+	return nil
+}
+
+// printers holds the text renderer for each payload type. JSON and template
+// output work on any payload; only text output needs a printer.
+var printers = map[reflect.Type]func(cmd *cobra.Command, payload interface{}, opts outputOptions){}
+
+// registerPrinter makes fn the text renderer for payloads of type T. Commands
+// register their payloads' printers from an init func next to the printer.
+func registerPrinter[T any](fn func(cmd *cobra.Command, value T, opts outputOptions)) {
+	printers[reflect.TypeFor[T]()] = func(cmd *cobra.Command, payload interface{}, opts outputOptions) {
+		fn(cmd, payload.(T), opts)
+	}
+}
+
+func init() {
+	registerPrinter(printBuildResponseWithOptions)
+	registerPrinter(printUploadCompleteWithOptions)
+	registerPrinter(func(cmd *cobra.Command, value api.BuildListResponse, _ outputOptions) { printBuildList(cmd, value) })
+}
+
+// canonicalJSON round-trips payload through a generic value so that every
+// object, not just maps, is encoded with sorted keys. Numbers keep their
+// original text.
+func canonicalJSON(payload interface{}) (interface{}, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// renderOutputToFile renders payload as it would appear on stdout and writes
+// it to opts.OutputFile, without terminal styling.
+func renderOutputToFile(opts outputOptions, payload interface{}) error {
+	var buf bytes.Buffer
+	capture := &cobra.Command{}
+	capture.SetOut(&buf)
+
+	fileOpts := opts
+	fileOpts.OutputFile = ""
+	if err := renderOutputWithOptions(capture, fileOpts, payload); err != nil {
+		return err
+	}
+	data := []byte(ansi.Strip(buf.String()))
+	if opts.AppendOutput {
+		return appendFile(opts.OutputFile, data)
+	}
+	return writeFileAtomic(opts.OutputFile, data, 0o644)
+}
+
+// jsonLineStream writes a series of results as compact JSON, one per line,
+// to where the command's output goes: stdout or OutputFile, in the JSON
+// envelope when one is set. Without AppendOutput the file is replaced on the
+// first write, so it holds just this stream.
+type jsonLineStream struct {
+	cmd  *cobra.Command
+	opts outputOptions
+
+	mu      sync.Mutex
+	started bool
+}
+
+func (s *jsonLineStream) write(payload interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	opts := s.opts
+	if !s.started && !opts.AppendOutput && opts.OutputFile != "" && opts.OutputFile != "-" {
+		if err := writeFileAtomic(opts.OutputFile, nil, 0o644); err != nil {
+			return err
+		}
+	}
+	s.started = true
+	opts.AppendOutput = true
+	return renderOutputWithOptions(s.cmd, opts, payload)
+}
+
+// writeRawOutput writes an undecoded response body to stdout or
+// opts.OutputFile, bypassing JSON and template rendering.
+func writeRawOutput(cmd *cobra.Command, opts outputOptions, body []byte) error {
+	switch {
+	case opts.OutputFile == "" || opts.OutputFile == "-":
+		_, err := cmd.OutOrStdout().Write(body)
+		return err
+	case opts.AppendOutput:
+		return appendFile(opts.OutputFile, body)
+	default:
+		return writeFileAtomic(opts.OutputFile, body, 0o644)
+	}
+}
+
+// appendFile appends data to path as one record ending in a newline,
+// creating the file and its parent directories as needed.
+func appendFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create output directory: %w", err)
+	}
+	if len(data) == 0 || data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("open output file: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("write output file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write output file: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temp file next to path and renames it into
+// place with mode perm, creating parent directories as needed.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create output directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create output file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write output file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write output file: %w", err)
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return fmt.Errorf("write output file: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("write output file: %w", err)
+	}
 	return nil
 }
 
 func printBuildResponse(cmd *cobra.Command, resp api.BuildResponse, verbose bool) {
+	printBuildResponseWithOptions(cmd, resp, outputOptions{Verbose: verbose})
+}
+
+func printBuildResponseWithOptions(cmd *cobra.Command, resp api.BuildResponse, opts outputOptions) {
 	out := cmd.OutOrStdout()
+	c := consoleFor(out)
+	verbose := opts.Verbose
 
-	switch resp.Build.Status {
-	case "available":
+	switch {
+	case resp.Build.IsAvailable():
 		Successf(out, "Build %d processed", resp.Build.ID)
-	case "failed":
+		if !verbose {
+			fmt.Fprintln(out, c.dim.Render("  "+formatBuildSummary(resp.Build, c.symbols)))
+		}
+	case resp.Build.IsFailed():
 		Errorf(out, "Build %d failed", resp.Build.ID)
 	default:
-		Statusf(out, "Build %d is %s", resp.Build.ID, resp.Build.Status)
+		fmt.Fprintf(out, "%s Build %d is %s\n", c.dim.Render(c.symbols.Status), resp.Build.ID, c.statusKeyword(string(resp.Build.Status)))
 	}
 
 	if verbose {
 		// Verbose mode: show all details
-		fmt.Fprintf(out, "  Version: %s\n", formatBuildValue(resp.Build.Status, resp.Build.Version))
-		fmt.Fprintf(out, "  Build Number: %s\n", formatBuildValue(resp.Build.Status, resp.Build.BuildNumber))
-		fmt.Fprintf(out, "  Updated: %s\n", resp.Build.UpdatedAt.Format(time.RFC3339))
+		rows := []detailRow{
+			{Key: "Version", Value: formatBuildValue(resp.Build, resp.Build.Version)},
+			{Key: "Build Number", Value: formatBuildValue(resp.Build, resp.Build.BuildNumber)},
+			{Key: "Updated", Value: resp.Build.UpdatedAt.Format(time.RFC3339)},
+		}
+		if opts.Verbosity >= 2 {
+			rows = append(rows, detailRow{Key: "Created", Value: resp.Build.InsertedAt.Format(time.RFC3339)})
+			if resp.Build.IsTerminal() && !resp.Build.InsertedAt.IsZero() && resp.Build.UpdatedAt.After(resp.Build.InsertedAt.Time) {
+				rows = append(rows, detailRow{Key: "Processing Time", Value: resp.Build.UpdatedAt.Sub(resp.Build.InsertedAt.Time).Round(time.Second).String()})
+			}
+		}
 
 		if resp.Build.Metadata != nil {
-			fmt.Fprintf(out, "  Metadata:\n")
+			rows = append(rows, detailRow{Key: "Metadata", Section: true})
 			if resp.Build.Metadata.BuildVersion != nil {
-				fmt.Fprintf(out, "    Build Version: %s\n", *resp.Build.Metadata.BuildVersion)
+				rows = append(rows, detailRow{Key: "Build Version", Value: *resp.Build.Metadata.BuildVersion, Level: 1})
 			}
 			if resp.Build.Metadata.BuildNumber != nil {
-				fmt.Fprintf(out, "    Build Number: %s\n", *resp.Build.Metadata.BuildNumber)
+				rows = append(rows, detailRow{Key: "Build Number", Value: *resp.Build.Metadata.BuildNumber, Level: 1})
 			}
 			if resp.Build.Metadata.BuildSize != nil {
-				fmt.Fprintf(out, "    Build Size: %s\n", formatBytes(*resp.Build.Metadata.BuildSize))
+				rows = append(rows, detailRow{Key: "Build Size", Value: formatBytes(*resp.Build.Metadata.BuildSize), Level: 1})
 			}
 			if resp.Build.Metadata.MinimumSystemVersion != nil {
-				fmt.Fprintf(out, "    Minimum System: %s\n", *resp.Build.Metadata.MinimumSystemVersion)
+				rows = append(rows, detailRow{Key: "Minimum System", Value: *resp.Build.Metadata.MinimumSystemVersion, Level: 1})
 			}
 			if resp.Build.Metadata.Signature != nil {
-				fmt.Fprintf(out, "    Signature: %s\n", *resp.Build.Metadata.Signature)
+				rows = append(rows, detailRow{Key: "Signature", Value: *resp.Build.Metadata.Signature, Level: 1})
 			}
 			if len(resp.Build.Metadata.ProcessingErrors) > 0 {
-				fmt.Fprintf(out, "    Processing Errors: %s\n", formatKeys(resp.Build.Metadata.ProcessingErrors))
+				rows = append(rows, detailRow{Key: "Processing Errors", Value: formatKeys(resp.Build.Metadata.ProcessingErrors), Level: 1})
 			}
 		}
+		printDetails(out, rows, opts.Pretty)
+		if resp.Build.Metadata != nil {
+			printReleaseNotes(out, resp.Build.Metadata.ReleaseNotes)
+		}
 	}
 
 	// Appcast info
-	if resp.Build.Status == "failed" {
+	if resp.Build.IsFailed() {
 		if resp.Build.Metadata != nil && len(resp.Build.Metadata.ProcessingErrors) > 0 {
 			for _, line := range formatProcessingErrors(resp.Build.Metadata.ProcessingErrors) {
 				ErrorDetail(out, line)
@@ -147,41 +528,135 @@ func printBuildResponse(cmd *cobra.Command, resp api.BuildResponse, verbose bool
 		return
 	}
 
+	// A failed appcast is an error, not just status, so it's always shown.
+	if opts.NoAppcast && !resp.Appcast.IsFailed() {
+		return
+	}
+
 	switch resp.Appcast.Status {
 	case "published":
 		Successf(out, "Feed updated: %s", resp.Appcast.FeedURL)
+	case api.AppcastStatusFailed:
+		Errorf(out, "Appcast for build %d failed", resp.Build.ID)
+		if resp.Appcast.Message != "" {
+			ErrorDetail(out, resp.Appcast.Message)
+		}
 	case "waiting_manual":
 		Status(out, "Awaiting manual publication")
 	default:
 		Statusf(out, "Appcast status: %s", resp.Appcast.Status)
 	}
 	if verbose {
-		fmt.Fprintf(out, "  Message: %s\n", resp.Appcast.Message)
-		fmt.Fprintf(out, "  Feed URL: %s\n", resp.Appcast.FeedURL)
+		rows := []detailRow{
+			{Key: "Message", Value: resp.Appcast.Message},
+			{Key: "Feed URL", Value: resp.Appcast.FeedURL},
+		}
 		if resp.Appcast.PublishedAt != nil {
-			fmt.Fprintf(out, "  Published At: %s\n", resp.Appcast.PublishedAt.Format(time.RFC3339))
+			rows = append(rows, detailRow{Key: "Published At", Value: resp.Appcast.PublishedAt.Format(time.RFC3339)})
 		}
 		if resp.Appcast.URL != nil {
-			fmt.Fprintf(out, "  URL: %s\n", *resp.Appcast.URL)
+			rows = append(rows, detailRow{Key: "URL", Value: *resp.Appcast.URL})
 		}
+		printDetails(out, rows, opts.Pretty)
 	}
 }
 
 func printUploadComplete(cmd *cobra.Command, resp api.BuildUploadCompleteResponse, verbose bool) {
+	printUploadCompleteWithOptions(cmd, resp, outputOptions{Verbose: verbose})
+}
+
+func printUploadCompleteWithOptions(cmd *cobra.Command, resp api.BuildUploadCompleteResponse, opts outputOptions) {
 	out := cmd.OutOrStdout()
 	Success(out, "Upload complete")
-	if verbose {
-		fmt.Fprintf(out, "  Build ID: %d\n", resp.BuildID.Int())
-		fmt.Fprintf(out, "  Status URL: %s\n", resp.StatusURL)
-		fmt.Fprintf(out, "  Wait URL: %s\n", resp.WaitURL)
+	if opts.Verbose {
+		printDetails(out, []detailRow{
+			{Key: "Build ID", Value: fmt.Sprintf("%d", resp.BuildID.Int())},
+			{Key: "Status URL", Value: resp.StatusURL},
+			{Key: "Wait URL", Value: resp.WaitURL},
+		}, opts.Pretty)
 	}
 }
 
+// detailRow is one key/value line of verbose output.
+type detailRow struct {
+	Key   string
+	Value string
+	// Level indents nested rows under a section heading.
+	Level int
+	// Section marks a heading row with no value of its own.
+	Section bool
+}
+
+// printDetails writes verbose key/value rows. With pretty set and a terminal
+// on the other end the rows are drawn as an aligned table; otherwise they
+// use the plain "  Key: value" lines scripts already parse.
+func printDetails(w io.Writer, rows []detailRow, pretty bool) {
+	if pretty && isTerminal(w) {
+		c := consoleFor(w)
+		tbl := table.New().
+			Border(lipgloss.RoundedBorder()).
+			BorderStyle(c.dim).
+			StyleFunc(func(row, col int) lipgloss.Style {
+				style := c.renderer.NewStyle().Padding(0, 1)
+				if col == 0 {
+					return style.Inherit(c.dim)
+				}
+				return style
+			})
+		for _, row := range rows {
+			tbl.Row(strings.Repeat("  ", row.Level)+row.Key, row.Value)
+		}
+		fmt.Fprintln(w, tbl.Render())
+		return
+	}
+
+	for _, row := range rows {
+		indent := strings.Repeat("  ", row.Level+1)
+		if row.Section {
+			fmt.Fprintf(w, "%s%s:\n", indent, row.Key)
+			continue
+		}
+		fmt.Fprintf(w, "%s%s: %s\n", indent, row.Key, row.Value)
+	}
+}
+
+// buildListRowFormat lays out build list columns at fixed widths so rows can
+// be printed as they arrive instead of after the whole list is known.
+const buildListRowFormat = "%-8s %-12s %-8s %-20s %s\n"
+
+func printBuildListHeader(w io.Writer) {
+	fmt.Fprintf(w, buildListRowFormat, "ID", "VERSION", "BUILD", "UPDATED", "STATUS")
+}
+
+func printBuildListRow(w io.Writer, build api.Build) {
+	updated := "-"
+	if !build.UpdatedAt.IsZero() {
+		updated = build.UpdatedAt.Format(time.RFC3339)
+	}
+	fmt.Fprintf(w, buildListRowFormat,
+		fmt.Sprintf("%d", build.ID),
+		formatBuildValue(build, build.Version),
+		formatBuildValue(build, build.BuildNumber),
+		updated,
+		consoleFor(w).statusKeyword(string(build.Status)),
+	)
+}
+
+func printBuildList(cmd *cobra.Command, resp api.BuildListResponse) {
+	out := cmd.OutOrStdout()
+	printBuildListHeader(out)
+	for _, build := range resp.Builds {
+		printBuildListRow(out, build)
+	}
+}
+
+// formatKeys joins the map's keys in sorted order so output is stable.
 func formatKeys(values map[string]interface{}) string {
 	keys := make([]string, 0, len(values))
 	for key := range values {
 		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 	return strings.Join(keys, ", ")
 }
 
@@ -229,26 +704,67 @@ func collectProcessingErrors(value interface{}, prefix string, lines *[]string)
 		for _, item := range typed {
 			collectProcessingErrors(item, prefix, lines)
 		}
-	case string:
-		if prefix != "" {
-			*lines = append(*lines, fmt.Sprintf("%s: %s", prefix, typed))
-		} else {
-			*lines = append(*lines, typed)
-		}
 	default:
+		leaf, ok := processingErrorLeaf(typed)
+		if !ok {
+			return
+		}
 		if prefix != "" {
-			*lines = append(*lines, fmt.Sprintf("%s: %s", prefix, fmt.Sprint(typed)))
+			*lines = append(*lines, fmt.Sprintf("%s: %s", prefix, leaf))
 		} else {
-			*lines = append(*lines, fmt.Sprint(typed))
+			*lines = append(*lines, leaf)
 		}
 	}
 }
 
-func formatBuildValue(status string, value *string) string {
+// processingErrorLeaf formats a scalar from a processing error tree. Numbers
+// decoded from JSON print without an exponent, so 20240510 doesn't come out
+// as 2.024051e+07. Nulls have nothing to report.
+func processingErrorLeaf(value interface{}) (string, bool) {
+	switch typed := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return typed, true
+	case bool:
+		return strconv.FormatBool(typed), true
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64), true
+	case json.Number:
+		return typed.String(), true
+	default:
+		return fmt.Sprint(typed), true
+	}
+}
+
+// statusKeyword colors a status word by outcome (green for success, red for
+// failure, dim while in progress) so it stands out in otherwise plain text.
+func (c *console) statusKeyword(status string) string {
+	switch status {
+	case string(api.BuildStatusAvailable), "uploaded":
+		return c.success.Render(status)
+	case string(api.BuildStatusFailed), "error":
+		return c.error.Render(status)
+	default:
+		return c.dim.Render(status)
+	}
+}
+
+// formatBuildSummary renders a one-line version/build/size summary,
+// e.g. "1.2.0 (5) · 1.00 MB"
+func formatBuildSummary(build api.Build, symbols symbolSet) string {
+	summary := fmt.Sprintf("%s (%s)", formatBuildValue(build, build.Version), formatBuildValue(build, build.BuildNumber))
+	if build.Metadata != nil && build.Metadata.BuildSize != nil {
+		summary += fmt.Sprintf(" %s %s", symbols.Status, formatBytes(*build.Metadata.BuildSize))
+	}
+	return summary
+}
+
+func formatBuildValue(build api.Build, value *string) string {
 	if value != nil && *value != "" {
 		return *value
 	}
-	if status == "processing" {
+	if build.IsProcessing() {
 		return "pending"
 	}
 	return "n/a"
@@ -3,10 +3,18 @@ package cli
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
 	"github.com/spf13/cobra"
 
 	"github.com/twinkle-apps/cli/internal/api"
@@ -343,3 +351,681 @@ func TestRenderOutputUnsupportedType(t *testing.T) {
 		t.Errorf("expected unsupported output type error, got: %v", err)
 	}
 }
+
+func TestPrintBuildResponseNoAppcast(t *testing.T) {
+	resp := api.BuildResponse{
+		Build: api.Build{
+			ID:         12,
+			Status:     "available",
+			InsertedAt: api.APITime{Time: time.Now()},
+			UpdatedAt:  api.APITime{Time: time.Now()},
+		},
+		Appcast: api.Appcast{
+			Status:  "waiting_manual",
+			Message: "waiting on manual update in web portal",
+			FeedURL: "https://example.com/feed.xml",
+		},
+	}
+
+	t.Run("default", func(t *testing.T) {
+		cmd, buf := newTestCmd()
+		printBuildResponseWithOptions(cmd, resp, outputOptions{})
+		if !strings.Contains(buf.String(), "Awaiting manual publication") {
+			t.Fatalf("expected appcast line, got %q", buf.String())
+		}
+	})
+
+	t.Run("no appcast", func(t *testing.T) {
+		cmd, buf := newTestCmd()
+		printBuildResponseWithOptions(cmd, resp, outputOptions{NoAppcast: true, Verbose: true})
+		output := buf.String()
+		if strings.Contains(output, "Awaiting manual publication") || strings.Contains(output, "Feed URL") {
+			t.Fatalf("expected appcast block to be omitted, got %q", output)
+		}
+		if !strings.Contains(output, "Build 12 processed") {
+			t.Fatalf("expected build result, got %q", output)
+		}
+	})
+}
+
+func TestSelectSymbols(t *testing.T) {
+	tests := []struct {
+		name  string
+		ascii bool
+		env   map[string]string
+		goos  string
+		want  symbolSet
+	}{
+		{name: "flag forces ascii", ascii: true, env: map[string]string{"LANG": "en_US.UTF-8"}, goos: "darwin", want: asciiSymbols},
+		{name: "utf8 locale", env: map[string]string{"LANG": "en_US.UTF-8"}, goos: "linux", want: unicodeSymbols},
+		{name: "utf8 lowercase", env: map[string]string{"LC_ALL": "C.utf8"}, goos: "linux", want: unicodeSymbols},
+		{name: "non utf8 locale", env: map[string]string{"LANG": "en_US.ISO-8859-1"}, goos: "linux", want: asciiSymbols},
+		{name: "LC_ALL wins over LANG", env: map[string]string{"LC_ALL": "C", "LANG": "en_US.UTF-8"}, goos: "linux", want: asciiSymbols},
+		{name: "unset locale on unix", goos: "linux", want: unicodeSymbols},
+		{name: "legacy windows console", goos: "windows", want: asciiSymbols},
+		{name: "windows terminal", env: map[string]string{"WT_SESSION": "1"}, goos: "windows", want: unicodeSymbols},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			if got := selectSymbols(tt.ascii, getenv, tt.goos); got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestASCIIFlagUsesASCIISymbols(t *testing.T) {
+	server, _ := newDeleteServer(t)
+	stdout, _, err := executeCLI(t, server.URL, "--ascii", "build", "delete", "app_123", "42", "--yes")
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if !strings.Contains(stdout, "[OK] Build 42 deleted") {
+		t.Fatalf("expected ASCII success marker, got %q", stdout)
+	}
+	if strings.Contains(stdout, "✓") {
+		t.Fatalf("expected no Unicode symbols, got %q", stdout)
+	}
+
+	// The choice belongs to that run; the next one starts fresh.
+	stdout, _, err = executeCLI(t, server.URL, "build", "delete", "app_123", "42", "--yes")
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if !strings.Contains(stdout, "✓ Build 42 deleted") {
+		t.Fatalf("expected Unicode symbols after an --ascii run, got %q", stdout)
+	}
+
+	var buf bytes.Buffer
+	out := withConsole(&buf, &console{symbols: asciiSymbols})
+	Error(out, "Build 1 failed")
+	ErrorDetail(out, "signing: missing certificate")
+	if output := buf.String(); !strings.Contains(output, "[X] Build 1 failed") || !strings.Contains(output, "-> signing") {
+		t.Fatalf("expected ASCII error markers, got %q", output)
+	}
+	if got := asciiSymbols.maskSecret("ABCD1234", 4); got != "****1234" {
+		t.Fatalf("expected ASCII mask, got %q", got)
+	}
+}
+
+func TestPrintBuildResponseAvailableShowsSummary(t *testing.T) {
+	resp := api.BuildResponse{
+		Build: api.Build{
+			ID:          42,
+			Status:      "available",
+			Version:     strPtr("1.2.0"),
+			BuildNumber: strPtr("5"),
+			InsertedAt:  api.APITime{Time: time.Now()},
+			UpdatedAt:   api.APITime{Time: time.Now()},
+			Metadata: &api.BuildMetadata{
+				BuildSize: intPtr(1048576),
+			},
+		},
+		Appcast: api.Appcast{Status: "published", FeedURL: "https://example.com/feed.xml"},
+	}
+
+	for _, symbols := range []symbolSet{unicodeSymbols, asciiSymbols} {
+		cmd, buf := newTestCmd()
+		cmd.SetOut(withConsole(buf, newConsole(buf, symbols, termenv.Ascii)))
+		printBuildResponse(cmd, resp, false)
+		if want := "1.2.0 (5) " + symbols.Status + " 1.00 MB"; !strings.Contains(buf.String(), want) {
+			t.Fatalf("expected summary line %q, got %q", want, buf.String())
+		}
+	}
+
+	cmd, buf := newTestCmd()
+	if err := renderOutput(cmd, true, false, resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(buf.String(), "1.2.0 (5)") {
+		t.Fatalf("expected no summary line in JSON output, got %q", buf.String())
+	}
+}
+
+func TestFormatBuildSummaryWithoutSize(t *testing.T) {
+	build := api.Build{Status: "available", Version: strPtr("2.0"), BuildNumber: strPtr("12")}
+	if got := formatBuildSummary(build, unicodeSymbols); got != "2.0 (12)" {
+		t.Fatalf("got %q, want %q", got, "2.0 (12)")
+	}
+}
+
+func TestRenderOutputWritesJSONToFile(t *testing.T) {
+	cmd, buf := newTestCmd()
+	path := filepath.Join(t.TempDir(), "nested", "dir", "result.json")
+
+	resp := api.BuildResponse{Build: api.Build{ID: 42, Status: "available"}}
+	if err := renderOutputWithOptions(cmd, outputOptions{JSON: true, OutputFile: path}, resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected nothing on stdout, got %q", buf.String())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read output file: %v", err)
+	}
+	var got api.BuildResponse
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid JSON in file: %v\nraw: %s", err, data)
+	}
+	if got.Build.ID != 42 {
+		t.Fatalf("expected build 42, got %d", got.Build.ID)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only the output file, found %d entries", len(entries))
+	}
+}
+
+func TestRenderOutputDashMeansStdout(t *testing.T) {
+	cmd, buf := newTestCmd()
+	resp := api.BuildResponse{Build: api.Build{ID: 7, Status: "available"}}
+	if err := renderOutputWithOptions(cmd, outputOptions{JSON: true, OutputFile: "-"}, resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"id": 7`) {
+		t.Fatalf("expected JSON on stdout, got %q", buf.String())
+	}
+}
+
+func TestRenderOutputTextFileHasNoANSI(t *testing.T) {
+	cmd, _ := newTestCmd()
+	path := filepath.Join(t.TempDir(), "result.txt")
+
+	resp := api.BuildResponse{
+		Build:   api.Build{ID: 42, Status: "failed", Metadata: &api.BuildMetadata{ProcessingErrors: map[string]interface{}{"version": "too low"}}},
+		Appcast: api.Appcast{Status: "waiting_manual"},
+	}
+	if err := renderOutputWithOptions(cmd, outputOptions{OutputFile: path}, resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read output file: %v", err)
+	}
+	if strings.Contains(string(data), "\x1b[") {
+		t.Fatalf("expected no ANSI sequences, got %q", data)
+	}
+	if !strings.Contains(string(data), "Build 42 failed") || !strings.Contains(string(data), "version: too low") {
+		t.Fatalf("expected rendered text, got %q", data)
+	}
+}
+
+func TestPrettyDetailsRequireTerminal(t *testing.T) {
+	resp := api.BuildUploadCompleteResponse{StatusURL: "https://example.com/status", WaitURL: "https://example.com/wait"}
+
+	tests := []struct {
+		name      string
+		pretty    bool
+		terminal  bool
+		wantTable bool
+	}{
+		{name: "pretty on terminal", pretty: true, terminal: true, wantTable: true},
+		{name: "pretty piped", pretty: true, terminal: false, wantTable: false},
+		{name: "plain on terminal", pretty: false, terminal: true, wantTable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := isTerminal
+			isTerminal = func(interface{}) bool { return tt.terminal }
+			t.Cleanup(func() { isTerminal = original })
+
+			cmd, buf := newTestCmd()
+			printUploadCompleteWithOptions(cmd, resp, outputOptions{Verbose: true, Pretty: tt.pretty})
+
+			output := buf.String()
+			hasTable := strings.Contains(output, "│") && strings.Contains(output, "╭")
+			if hasTable != tt.wantTable {
+				t.Fatalf("table rendered = %v, want %v:\n%s", hasTable, tt.wantTable, output)
+			}
+			if !tt.wantTable && !strings.Contains(output, "  Status URL: https://example.com/status\n") {
+				t.Fatalf("expected plain detail lines, got %q", output)
+			}
+		})
+	}
+}
+
+func TestPrettyBuildDetailsAlignValues(t *testing.T) {
+	original := isTerminal
+	isTerminal = func(interface{}) bool { return true }
+	t.Cleanup(func() { isTerminal = original })
+
+	cmd, buf := newTestCmd()
+	resp := api.BuildResponse{
+		Build: api.Build{
+			ID:          42,
+			Status:      "available",
+			Version:     strPtr("1.2.0"),
+			BuildNumber: strPtr("5"),
+			Metadata:    &api.BuildMetadata{MinimumSystemVersion: strPtr("13.0")},
+		},
+		Appcast: api.Appcast{Status: "published", FeedURL: "https://example.com/appcast.xml"},
+	}
+	printBuildResponseWithOptions(cmd, resp, outputOptions{Verbose: true, Pretty: true})
+
+	valueColumn := -1
+	for _, line := range strings.Split(ansi.Strip(buf.String()), "\n") {
+		if !strings.Contains(line, "Version") && !strings.Contains(line, "Minimum System") {
+			continue
+		}
+		separators := []rune(line)
+		col := -1
+		for i, r := range separators {
+			if r == '│' && i > 0 {
+				col = i
+				break
+			}
+		}
+		if col < 0 {
+			t.Fatalf("expected column separator in %q", line)
+		}
+		if valueColumn == -1 {
+			valueColumn = col
+		} else if col != valueColumn {
+			t.Fatalf("expected aligned columns, got %d and %d:\n%s", valueColumn, col, buf.String())
+		}
+	}
+	if valueColumn == -1 {
+		t.Fatalf("expected detail rows, got:\n%s", buf.String())
+	}
+}
+
+// ansiConsole colors with the basic ANSI palette whatever the stream is.
+var ansiConsole = newConsole(io.Discard, unicodeSymbols, termenv.ANSI)
+
+func TestStatusKeywordStylesEachStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   lipgloss.Style
+	}{
+		{status: "available", want: ansiConsole.success},
+		{status: "failed", want: ansiConsole.error},
+		{status: "processing", want: ansiConsole.dim},
+		{status: "queued", want: ansiConsole.dim},
+	}
+	for _, tt := range tests {
+		got := ansiConsole.statusKeyword(tt.status)
+		if !strings.Contains(got, "\x1b[") {
+			t.Errorf("expected %s to be styled, got %q", tt.status, got)
+		}
+		if got != tt.want.Render(tt.status) {
+			t.Errorf("unexpected style for %s: %q", tt.status, got)
+		}
+	}
+}
+
+func TestStatusKeywordPlainWithoutColor(t *testing.T) {
+	for _, status := range []string{"available", "failed", "processing"} {
+		if got := plainConsole.statusKeyword(status); got != status {
+			t.Errorf("expected plain %q, got %q", status, got)
+		}
+	}
+}
+
+func TestPrintBuildResponseStylesOnlyStatusKeyword(t *testing.T) {
+	cmd, buf := newTestCmd()
+	cmd.SetOut(withConsole(buf, ansiConsole))
+	resp := api.BuildResponse{Build: api.Build{ID: 42, Status: "processing"}, Appcast: api.Appcast{Status: "pending"}}
+	printBuildResponse(cmd, resp, false)
+
+	if !strings.Contains(buf.String(), " Build 42 is "+ansiConsole.dim.Render("processing")) {
+		t.Fatalf("expected neutral text with styled keyword, got %q", buf.String())
+	}
+}
+
+func TestNoColorFlagDisablesStyling(t *testing.T) {
+	t.Setenv("CLICOLOR_FORCE", "1")
+	server, _ := newStatusSequenceServer(t, "processing")
+
+	stdout, _, err := executeCLI(t, server.URL, "--no-color", "build", "status", "app_123", "42")
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if strings.Contains(stdout, "\x1b[") {
+		t.Fatalf("expected no ANSI sequences with --no-color, got %q", stdout)
+	}
+	if !strings.Contains(stdout, "Build 42 is processing") {
+		t.Fatalf("expected status line, got %q", stdout)
+	}
+}
+
+func TestJSONEnvelopeWrapsPayload(t *testing.T) {
+	server, _ := newStatusSequenceServer(t, "available")
+
+	stdout, _, err := executeCLI(t, server.URL, "--json-envelope", "build", "status", "app_123", "42")
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+
+	var got struct {
+		SchemaVersion int               `json:"schema_version"`
+		Data          api.BuildResponse `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("decode envelope: %v\n%s", err, stdout)
+	}
+	if got.SchemaVersion != jsonSchemaVersion {
+		t.Fatalf("expected schema version %d, got %d", jsonSchemaVersion, got.SchemaVersion)
+	}
+	if got.Data.Build.ID != 42 || !got.Data.Build.IsAvailable() {
+		t.Fatalf("expected the build under data, got %+v", got.Data.Build)
+	}
+
+	plain, _, err := executeCLI(t, server.URL, "--json", "build", "status", "app_123", "42")
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if strings.Contains(plain, "schema_version") {
+		t.Fatalf("expected plain --json output to be unchanged, got %s", plain)
+	}
+}
+
+type whoamiPayload struct {
+	Key string `json:"key"`
+}
+
+func TestRenderOutputDispatchesToRegisteredPrinters(t *testing.T) {
+	registerPrinter(func(cmd *cobra.Command, value whoamiPayload, opts outputOptions) {
+		fmt.Fprintf(cmd.OutOrStdout(), "key %s (verbose=%t)\n", value.Key, opts.Verbose)
+	})
+	t.Cleanup(func() { delete(printers, reflect.TypeFor[whoamiPayload]()) })
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	if err := renderOutputWithOptions(cmd, outputOptions{Verbose: true}, whoamiPayload{Key: "tk_123"}); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if out.String() != "key tk_123 (verbose=true)\n" {
+		t.Fatalf("expected the registered printer's output, got %q", out.String())
+	}
+
+	// JSON output doesn't need a printer.
+	out.Reset()
+	if err := renderOutput(cmd, true, false, whoamiPayload{Key: "tk_123"}); err != nil {
+		t.Fatalf("render JSON: %v", err)
+	}
+	if !strings.Contains(out.String(), `"key": "tk_123"`) {
+		t.Fatalf("expected JSON output, got %q", out.String())
+	}
+
+	type unregistered struct{}
+	if err := renderOutput(cmd, false, false, unregistered{}); err == nil || !strings.Contains(err.Error(), "unsupported output type") {
+		t.Fatalf("expected an error for an unregistered type, got %v", err)
+	}
+	// A pointer is a different type from the value it points to.
+	if err := renderOutput(cmd, false, false, &whoamiPayload{}); err == nil {
+		t.Fatal("expected an error for a pointer payload")
+	}
+}
+
+func TestFormatProcessingErrorsMixedLeaves(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		want    []string
+	}{
+		{
+			name:    "numbers",
+			fixture: `{"version": {"min": 2022, "got": 20240510, "ratio": 0.5, "offset": -3}}`,
+			want:    []string{"version.got: 20240510", "version.min: 2022", "version.offset: -3", "version.ratio: 0.5"},
+		},
+		{
+			name:    "booleans and nulls",
+			fixture: `{"signing": {"notarized": false, "stapled": true, "team": null}}`,
+			want:    []string{"signing.notarized: false", "signing.stapled: true"},
+		},
+		{
+			name:    "arrays of mixed leaves",
+			fixture: `{"checks": [1, true, "bad icon", {"size": 1024}]}`,
+			want:    []string{"checks: 1", "checks: true", "checks: bad icon", "checks.size: 1024"},
+		},
+		{
+			name:    "step and message take precedence",
+			fixture: `{"sign": {"step": "codesign", "message": "identity not found", "code": 65, "retryable": false}}`,
+			want:    []string{"codesign: identity not found"},
+		},
+		{
+			name:    "message without a string step",
+			fixture: `{"sign": {"step": 3, "message": "timed out"}}`,
+			want:    []string{"timed out"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var values map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.fixture), &values); err != nil {
+				t.Fatalf("decode fixture: %v", err)
+			}
+			if got := formatProcessingErrors(values); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("formatProcessingErrors() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONCanonicalIsByteStable(t *testing.T) {
+	size := 1024
+	resp := api.BuildResponse{
+		Build: api.Build{
+			ID:     42,
+			Status: api.BuildStatusFailed,
+			Metadata: &api.BuildMetadata{
+				BuildSize: &size,
+				ProcessingErrors: map[string]interface{}{
+					"zeta":  "missing signature",
+					"alpha": map[string]interface{}{"y": "bad", "b": "worse"},
+					"mid":   []interface{}{"<script>", 3.5},
+				},
+			},
+			CustomMetadata: map[string]string{"git_sha": "abc", "ci_url": "https://ci.example.com/1?a=1&b=2"},
+		},
+	}
+
+	render := func() string {
+		var out bytes.Buffer
+		cmd := &cobra.Command{}
+		cmd.SetOut(&out)
+		if err := renderOutputWithOptions(cmd, outputOptions{JSON: true, JSONCanonical: true}, resp); err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		return out.String()
+	}
+
+	first := render()
+	for i := 0; i < 20; i++ {
+		if got := render(); got != first {
+			t.Fatalf("expected identical output across runs:\n%s\nvs\n%s", first, got)
+		}
+	}
+
+	// Struct fields are sorted too, not just map keys.
+	if strings.Index(first, `"custom_metadata"`) > strings.Index(first, `"id"`) {
+		t.Fatalf("expected struct fields in key order, got %s", first)
+	}
+	if strings.Index(first, `"b": "worse"`) > strings.Index(first, `"y": "bad"`) {
+		t.Fatalf("expected sorted nested map keys, got %s", first)
+	}
+	for _, want := range []string{`"<script>"`, `a=1&b=2`, `3.5`} {
+		if !strings.Contains(first, want) {
+			t.Fatalf("expected %s verbatim in canonical output, got %s", want, first)
+		}
+	}
+}
+
+func TestVerbosityLevels(t *testing.T) {
+	server, _ := newStatusSequenceServer(t, "available")
+
+	tests := []struct {
+		flag          string
+		stdoutWant    []string
+		stdoutNotWant []string
+		stderrWant    []string
+		stderrNotWant []string
+	}{
+		{
+			flag:          "-v",
+			stdoutWant:    []string{"Version", "Updated"},
+			stdoutNotWant: []string{"Created"},
+			stderrNotWant: []string{"> GET"},
+		},
+		{
+			flag:          "-vv",
+			stdoutWant:    []string{"Created"},
+			stderrWant:    []string{"> GET " + server.URL + "/api/v1/apps/app_123/builds/42", "< 200 OK", "< Content-Type: application/json"},
+			stderrNotWant: []string{"Authorization", `"feed_url"`},
+		},
+		{
+			flag:       "-vvv",
+			stdoutWant: []string{"Created"},
+			stderrWant: []string{"> Authorization: [redacted]", `"feed_url":"https://example.com/feed.xml"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.flag, func(t *testing.T) {
+			stdout, stderr, err := executeCLI(t, server.URL, tt.flag, "build", "status", "app_123", "42")
+			if err != nil {
+				t.Fatalf("build status: %v", err)
+			}
+			for _, want := range tt.stdoutWant {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("expected %q in stdout:\n%s", want, stdout)
+				}
+			}
+			for _, notWant := range tt.stdoutNotWant {
+				if strings.Contains(stdout, notWant) {
+					t.Errorf("expected no %q in stdout:\n%s", notWant, stdout)
+				}
+			}
+			for _, want := range tt.stderrWant {
+				if !strings.Contains(stderr, want) {
+					t.Errorf("expected %q in stderr:\n%s", want, stderr)
+				}
+			}
+			for _, notWant := range tt.stderrNotWant {
+				if strings.Contains(stderr, notWant) {
+					t.Errorf("expected no %q in stderr:\n%s", notWant, stderr)
+				}
+			}
+		})
+	}
+}
+
+func TestVerboseProcessingErrorKeysAreSorted(t *testing.T) {
+	errs := map[string]interface{}{
+		"version":  "build number too low",
+		"signing":  "missing certificate",
+		"archive":  "corrupt",
+		"metadata": "missing icon",
+	}
+	if got := formatKeys(errs); got != "archive, metadata, signing, version" {
+		t.Fatalf("expected sorted keys, got %q", got)
+	}
+
+	resp := api.BuildResponse{Build: api.Build{ID: 5, Status: "processing", Metadata: &api.BuildMetadata{ProcessingErrors: errs}}}
+	var first string
+	for i := 0; i < 20; i++ {
+		cmd, buf := newTestCmd()
+		if err := renderOutput(cmd, false, true, resp); err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		if i == 0 {
+			first = buf.String()
+		} else if buf.String() != first {
+			t.Fatalf("verbose output changed between runs:\n%s\nvs\n%s", first, buf.String())
+		}
+	}
+	if !strings.Contains(first, "Processing Errors: archive, metadata, signing, version") {
+		t.Fatalf("expected sorted processing errors, got:\n%s", first)
+	}
+}
+
+func TestColorFlagModes(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantANSI bool
+	}{
+		{args: []string{"--color", "auto"}, wantANSI: false}, // stdout isn't a terminal under go test
+		{args: []string{"--color", "always"}, wantANSI: true},
+		{args: []string{"--color", "never"}, wantANSI: false},
+		{args: []string{"--no-color"}, wantANSI: false},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, "="), func(t *testing.T) {
+			t.Setenv("NO_COLOR", "")
+			t.Setenv("CLICOLOR_FORCE", "")
+			server, _ := newStatusSequenceServer(t, "available")
+			stdout, _, err := executeCLI(t, server.URL, append(tt.args, "build", "status", "app_123", "42")...)
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if got := strings.Contains(stdout, "\x1b["); got != tt.wantANSI {
+				t.Fatalf("expected ANSI=%v, got %q", tt.wantANSI, stdout)
+			}
+
+			// The profile belongs to that run's streams; other writers
+			// stay plain.
+			var buf bytes.Buffer
+			Success(&buf, "done")
+			if strings.Contains(buf.String(), "\x1b[") {
+				t.Fatalf("expected no ANSI outside the run, got %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestColorFlagRejectsUnknownMode(t *testing.T) {
+	_, _, err := executeCLI(t, "https://example.com", "--color", "sometimes", "version")
+	if err == nil || !strings.Contains(err.Error(), "invalid --color") {
+		t.Fatalf("expected invalid mode error, got %v", err)
+	}
+	_, _, err = executeCLI(t, "https://example.com", "--color", "always", "--no-color", "version")
+	if err == nil {
+		t.Fatal("expected --color and --no-color to conflict")
+	}
+}
+
+func TestAppendOutputAccumulatesJSONLines(t *testing.T) {
+	server, _ := newStatusSequenceServer(t, "processing", "available")
+	path := filepath.Join(t.TempDir(), "status.jsonl")
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := executeCLI(t, server.URL, "--json", "--output-file", path, "--append-output", "build", "status", "app_123", "42"); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read output file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two records, got %d:\n%s", len(lines), data)
+	}
+	for i, want := range []api.BuildStatus{"processing", "available"} {
+		var resp api.BuildResponse
+		if err := json.Unmarshal([]byte(lines[i]), &resp); err != nil {
+			t.Fatalf("line %d is not JSON: %v\nraw: %s", i+1, err, lines[i])
+		}
+		if resp.Build.Status != want {
+			t.Fatalf("line %d: expected %s, got %s", i+1, want, resp.Build.Status)
+		}
+	}
+}
+
+func TestAppendOutputNeedsOutputFile(t *testing.T) {
+	_, _, err := executeCLI(t, "https://example.com", "--append-output", "build", "status", "app_123", "42")
+	if err == nil || !strings.Contains(err.Error(), "--append-output needs --output-file") {
+		t.Fatalf("expected --output-file error, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// outputPresets are the built-in templates selectable with --preset. Like
+// --template, they render the payload's JSON form; payloads a preset doesn't
+// know fall back to compact JSON.
+var outputPresets = map[string]string{
+	// ci prints one key=value line per build, easy to grep or eval.
+	"ci": `
+{{- if .build -}}
+build={{.build.id}} status={{.build.status}}{{with .build.version}} version={{.}}{{end}}{{with .appcast}}{{with .feed_url}} feed={{.}}{{end}}{{end}}
+{{- else if .upload_state -}}
+build={{.build_id}} upload_state={{.upload_state}}
+{{- else if .builds -}}
+{{range .builds}}build={{.id}} status={{.status}}{{with .version}} version={{.}}{{end}}
+{{end}}
+{{- else -}}
+{{json .}}
+{{- end -}}`,
+
+	// slack prints an incoming-webhook payload.
+	"slack": `
+{{- define "text" -}}
+{{- if .build -}}
+{{- if eq .build.status "available" -}}
+:white_check_mark: Build {{.build.id}}{{with .build.version}} ({{.}}){{end}} is available{{with .appcast}}{{with .feed_url}}: {{.}}{{end}}{{end}}
+{{- else if eq .build.status "failed" -}}
+:x: Build {{.build.id}}{{with .build.version}} ({{.}}){{end}} failed{{range processingErrors .build}}{{"\n"}}• {{.}}{{end}}
+{{- else -}}
+:hourglass_flowing_sand: Build {{.build.id}}{{with .build.version}} ({{.}}){{end}} is {{.build.status}}
+{{- end -}}
+{{- else if .upload_state -}}
+:package: Build {{.build_id}} uploaded ({{.upload_state}})
+{{- else -}}
+{{json .}}
+{{- end -}}
+{{- end -}}
+{"text":{{json (include "text" .)}}}`,
+
+	// github-actions prints workflow commands, so results show up as
+	// annotations on the run.
+	"github-actions": `
+{{- if .build -}}
+{{- $id := .build.id -}}
+{{- if eq .build.status "failed" -}}
+{{- $errs := processingErrors .build -}}
+{{- range $errs}}::error title=Build {{$id}} failed::{{ghaData .}}
+{{end -}}
+{{- if not $errs}}::error title=Build {{$id}} failed::Build {{$id}} failed{{end -}}
+{{- else if eq .build.status "available" -}}
+::notice title=Build {{$id}} available::Build {{$id}}{{with .build.version}} ({{ghaData .}}){{end}} is available{{with .appcast}}{{with .feed_url}}: {{ghaData .}}{{end}}{{end}}
+{{- else -}}
+::notice title=Build {{$id}} {{ghaProperty .build.status}}::Build {{$id}} is {{ghaData .build.status}}
+{{- end -}}
+{{- else if .upload_state -}}
+::notice title=Upload complete::Build {{.build_id}} uploaded ({{ghaData .upload_state}})
+{{- else -}}
+{{json .}}
+{{- end -}}`,
+}
+
+// presetFuncs are available to the built-in presets on top of templateFuncs.
+var presetFuncs = template.FuncMap{
+	"processingErrors": func(build interface{}) []string {
+		fields, _ := build.(map[string]interface{})
+		metadata, _ := fields["metadata"].(map[string]interface{})
+		errs, _ := metadata["processing_errors"].(map[string]interface{})
+		if len(errs) == 0 {
+			return nil
+		}
+		return formatProcessingErrors(errs)
+	},
+	// GitHub Actions workflow commands are line based; these escape values
+	// the way the runner expects.
+	"ghaData":     ghaEscaper(false),
+	"ghaProperty": ghaEscaper(true),
+}
+
+func ghaEscaper(property bool) func(interface{}) string {
+	pairs := []string{"%", "%25", "\r", "%0D", "\n", "%0A"}
+	if property {
+		pairs = append(pairs, ":", "%3A", ",", "%2C")
+	}
+	replacer := strings.NewReplacer(pairs...)
+	return func(value interface{}) string {
+		return replacer.Replace(fmt.Sprint(value))
+	}
+}
+
+// presetTemplate builds the --preset template called name.
+func presetTemplate(name string) (*template.Template, error) {
+	text, ok := outputPresets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q: must be one of %s", name, strings.Join(presetNames(), ", "))
+	}
+	tmpl := template.New(name).Funcs(templateFuncs).Funcs(presetFuncs)
+	tmpl.Funcs(template.FuncMap{
+		"include": func(name string, data interface{}) (string, error) {
+			var buf bytes.Buffer
+			err := tmpl.ExecuteTemplate(&buf, name, data)
+			return buf.String(), err
+		},
+	})
+	return tmpl.Parse(text)
+}
+
+func presetNames() []string {
+	names := make([]string, 0, len(outputPresets))
+	for name := range outputPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
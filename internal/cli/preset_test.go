@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+func renderPreset(t *testing.T, name string, payload interface{}) string {
+	t.Helper()
+	tmpl, err := presetTemplate(name)
+	if err != nil {
+		t.Fatalf("preset %s: %v", name, err)
+	}
+	var buf bytes.Buffer
+	if err := renderTemplate(&buf, tmpl, payload); err != nil {
+		t.Fatalf("render %s: %v", name, err)
+	}
+	return buf.String()
+}
+
+func TestGitHubActionsPresetAnnotatesFailedBuild(t *testing.T) {
+	version := "1.2.0"
+	resp := api.BuildResponse{Build: api.Build{
+		ID:      42,
+		Status:  api.BuildStatusFailed,
+		Version: &version,
+		Metadata: &api.BuildMetadata{ProcessingErrors: map[string]interface{}{
+			"signing": "missing certificate\nfor Developer ID",
+			"version": map[string]interface{}{"step": "validate", "message": "build number 100% taken"},
+		}},
+	}}
+
+	got := renderPreset(t, "github-actions", resp)
+	want := "::error title=Build 42 failed::signing: missing certificate%0Afor Developer ID\n" +
+		"::error title=Build 42 failed::validate: build number 100%25 taken\n"
+	if got != want {
+		t.Fatalf("unexpected annotations:\n%q\nwant\n%q", got, want)
+	}
+
+	resp.Build.Metadata = nil
+	if got := renderPreset(t, "github-actions", resp); got != "::error title=Build 42 failed::Build 42 failed\n" {
+		t.Fatalf("unexpected annotation without processing errors: %q", got)
+	}
+}
+
+func TestGitHubActionsPresetNoticesAvailableBuild(t *testing.T) {
+	version := "1.2.0"
+	resp := api.BuildResponse{
+		Build:   api.Build{ID: 42, Status: api.BuildStatusAvailable, Version: &version},
+		Appcast: api.Appcast{FeedURL: "https://example.com/appcast.xml"},
+	}
+	want := "::notice title=Build 42 available::Build 42 (1.2.0) is available: https://example.com/appcast.xml\n"
+	if got := renderPreset(t, "github-actions", resp); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestCIAndSlackPresets(t *testing.T) {
+	version := "1.2.0"
+	resp := api.BuildResponse{Build: api.Build{
+		ID:       42,
+		Status:   api.BuildStatusFailed,
+		Version:  &version,
+		Metadata: &api.BuildMetadata{ProcessingErrors: map[string]interface{}{"signing": "missing certificate"}},
+	}}
+
+	if got := renderPreset(t, "ci", resp); got != "build=42 status=failed version=1.2.0\n" {
+		t.Fatalf("unexpected ci output %q", got)
+	}
+
+	var slack struct {
+		Text string `json:"text"`
+	}
+	out := renderPreset(t, "slack", resp)
+	if err := json.Unmarshal([]byte(out), &slack); err != nil {
+		t.Fatalf("slack preset isn't a JSON payload: %v\n%s", err, out)
+	}
+	if slack.Text != ":x: Build 42 (1.2.0) failed\n• signing: missing certificate" {
+		t.Fatalf("unexpected slack text %q", slack.Text)
+	}
+}
+
+func TestPresetFlagValidation(t *testing.T) {
+	_, _, err := executeCLI(t, "https://example.com", "--preset", "teams", "build", "status", "app_123", "42")
+	if err == nil || !strings.Contains(err.Error(), `unknown preset "teams"`) {
+		t.Fatalf("expected unknown preset error, got %v", err)
+	}
+	_, _, err = executeCLI(t, "https://example.com", "--preset", "ci", "--json", "build", "status", "app_123", "42")
+	if err == nil || !strings.Contains(err.Error(), "--preset can't be combined") {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+}
+
+func TestPresetFlagRendersCommandOutput(t *testing.T) {
+	server, _ := newStatusSequenceServer(t, "available")
+
+	stdout, _, err := executeCLI(t, server.URL, "--preset", "ci", "build", "status", "app_123", "42")
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if !strings.HasPrefix(stdout, "build=42 status=available") || !strings.Contains(stdout, "feed=https://example.com/feed.xml") {
+		t.Fatalf("unexpected preset output %q", stdout)
+	}
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+// errNotInteractive is returned by prompts when stdin is not a terminal, so
+// unattended runs fail fast instead of blocking on input.
+var errNotInteractive = errors.New("stdin is not a terminal")
+
+// errInputDisabled is returned by prompts under --no-input. It matches
+// errNotInteractive, so callers fall back the same way.
+var errInputDisabled = fmt.Errorf("%w: prompts are disabled by --no-input", errNotInteractive)
+
+// isTerminal reports whether stream (stdin or an output writer) is an
+// interactive terminal. Tests override it.
+var isTerminal = func(stream interface{}) bool {
+	if w, ok := stream.(io.Writer); ok {
+		stream = unwrapConsole(w)
+	}
+	file, ok := stream.(*os.File)
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(file.Fd()) || isatty.IsCygwinTerminal(file.Fd())
+}
+
+// prompter reads answers to a series of prompts from stdin. Questions go to
+// stderr so stdout stays clean for the command's result.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// newPrompter returns a prompter for cmd, or errNotInteractive when stdin is
+// not a terminal or --no-input is set.
+func newPrompter(cmd *cobra.Command) (*prompter, error) {
+	if noInput, _ := cmd.Flags().GetBool("no-input"); noInput {
+		return nil, errInputDisabled
+	}
+	in := cmd.InOrStdin()
+	if !isTerminal(in) {
+		return nil, errNotInteractive
+	}
+	return &prompter{in: bufio.NewReader(in), out: cmd.ErrOrStderr()}, nil
+}
+
+// line writes prompt and reads a single trimmed line.
+func (p *prompter) line(prompt string) (string, error) {
+	fmt.Fprint(p.out, prompt)
+	line, err := p.in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("read input: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// confirm asks a yes/no question, defaulting to no.
+func (p *prompter) confirm(question string) (bool, error) {
+	answer, err := p.line(question + " [y/N] ")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// confirm asks a yes/no question, defaulting to no.
+func confirm(cmd *cobra.Command, question string) (bool, error) {
+	p, err := newPrompter(cmd)
+	if err != nil {
+		return false, err
+	}
+	return p.confirm(question)
+}
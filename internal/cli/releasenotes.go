@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlTagPattern      = regexp.MustCompile(`(?s)<[a-zA-Z/!][^>]*>`)
+	htmlBreakPattern    = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|h[1-6]|ul|ol|tr)>`)
+	htmlListItemPattern = regexp.MustCompile(`(?i)<li[^>]*>`)
+	htmlDropPattern     = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+
+	markdownBulletPattern  = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	markdownHeadingPattern = regexp.MustCompile(`^#{1,6}\s+`)
+	markdownLinkPattern    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	markdownEmphasis       = strings.NewReplacer("**", "", "__", "", "`", "")
+)
+
+// releaseNotesText turns release notes given as HTML or markdown into plain
+// lines for the terminal: tags and emphasis are dropped, list items become
+// bullets and links keep their URL. Blank runs collapse to one empty line.
+func releaseNotesText(raw string, symbols symbolSet) []string {
+	raw = strings.ReplaceAll(strings.TrimSpace(raw), "\r\n", "\n")
+	if raw == "" {
+		return nil
+	}
+	if htmlTagPattern.MatchString(raw) {
+		raw = htmlNotesText(raw)
+	}
+
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(raw, "\n") {
+		line = markdownLineText(strings.TrimRight(line, " \t"), symbols)
+		if strings.TrimSpace(line) == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func htmlNotesText(raw string) string {
+	raw = htmlDropPattern.ReplaceAllString(raw, "")
+	// Source newlines are just formatting in HTML.
+	raw = strings.Join(strings.Fields(raw), " ")
+	raw = htmlListItemPattern.ReplaceAllString(raw, "\n- ")
+	raw = htmlBreakPattern.ReplaceAllString(raw, "\n")
+	raw = htmlTagPattern.ReplaceAllString(raw, "")
+	raw = html.UnescapeString(raw)
+
+	// Block tags each end a line; the empty lines between them carry no
+	// meaning.
+	var lines []string
+	for _, line := range strings.Split(raw, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func markdownLineText(line string, symbols symbolSet) string {
+	if m := markdownBulletPattern.FindStringSubmatch(line); m != nil {
+		line = m[1] + symbols.Status + " " + line[len(m[0]):]
+	} else {
+		line = markdownHeadingPattern.ReplaceAllString(line, "")
+	}
+	line = markdownLinkPattern.ReplaceAllString(line, "$1 ($2)")
+	return markdownEmphasis.Replace(line)
+}
+
+// printReleaseNotes writes notes under a heading in the layout of verbose
+// details. Missing or empty notes print nothing.
+func printReleaseNotes(w io.Writer, notes *string) {
+	if notes == nil {
+		return
+	}
+	lines := releaseNotesText(*notes, consoleFor(w).symbols)
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "  Release Notes:")
+	for _, line := range lines {
+		if line == "" {
+			fmt.Fprintln(w)
+			continue
+		}
+		fmt.Fprintf(w, "    %s\n", line)
+	}
+}
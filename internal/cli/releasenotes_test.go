@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+func TestReleaseNotesTextStripsHTML(t *testing.T) {
+	raw := `<h2>What's new</h2>
+<p>Faster <strong>sync</strong> &amp; fewer crashes.</p>
+<ul>
+  <li>Dark mode</li>
+  <li>Fixed <a href="https://example.com/42">#42</a></li>
+</ul>
+<script>alert("x")</script>`
+
+	got := releaseNotesText(raw, unicodeSymbols)
+	want := []string{
+		"What's new",
+		"Faster sync & fewer crashes.",
+		unicodeSymbols.Status + " Dark mode",
+		unicodeSymbols.Status + " Fixed #42",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("releaseNotesText() = %q, want %q", got, want)
+	}
+}
+
+func TestReleaseNotesTextRendersMarkdown(t *testing.T) {
+	raw := "## 1.4.0\n\n\n* **Dark mode** for the menu bar\n- Fixed `sync` stalls\n  + See [the docs](https://example.com/docs)\n"
+
+	got := releaseNotesText(raw, unicodeSymbols)
+	want := []string{
+		"1.4.0",
+		"",
+		unicodeSymbols.Status + " Dark mode for the menu bar",
+		unicodeSymbols.Status + " Fixed sync stalls",
+		"  " + unicodeSymbols.Status + " See the docs (https://example.com/docs)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("releaseNotesText() = %q, want %q", got, want)
+	}
+}
+
+func TestPrintReleaseNotesSkipsEmptyNotes(t *testing.T) {
+	blank := "  \n "
+	for _, notes := range []*string{nil, &blank} {
+		var out bytes.Buffer
+		printReleaseNotes(&out, notes)
+		if out.Len() != 0 {
+			t.Fatalf("expected no output for %v, got %q", notes, out.String())
+		}
+	}
+}
+
+func TestReleaseNotesInVerboseAndJSONOutput(t *testing.T) {
+	notes := "<ul><li>Dark mode</li></ul>"
+	resp := api.BuildResponse{
+		Build: api.Build{ID: 42, Status: api.BuildStatusAvailable, Metadata: &api.BuildMetadata{ReleaseNotes: &notes}},
+	}
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	if err := renderOutputWithOptions(cmd, outputOptions{Verbose: true, NoAppcast: true}, resp); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if !strings.Contains(out.String(), "  Release Notes:\n    "+unicodeSymbols.Status+" Dark mode\n") {
+		t.Fatalf("expected rendered release notes, got %q", out.String())
+	}
+
+	out.Reset()
+	if err := renderOutputWithOptions(cmd, outputOptions{JSON: true}, resp); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	var decoded api.BuildResponse
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got := decoded.Build.Metadata.ReleaseNotes; got == nil || *got != notes {
+		t.Fatalf("expected raw notes under --json, got %v", got)
+	}
+}
@@ -4,8 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 
 	"github.com/twinkle-apps/cli/internal/api"
@@ -18,27 +26,93 @@ const (
 	defaultBaseURL = "https://app.usetwinkle.com"
 	envAPIKey      = "TWINKLE_API_KEY"
 	envBaseURL     = "TWINKLE_BASE_URL"
+	envSigningKey  = "TWINKLE_SIGNING_SECRET"
 )
 
 type appContextKey struct{}
 
 type AppContext struct {
-	Client  *api.Client
-	JSON    bool
-	Verbose bool
+	Client       *api.Client
+	JSON         bool
+	Verbose      bool
+	Verbosity    int
+	NoAppcast    bool
+	OutputFile   string
+	AppendOutput bool
+	Pretty       bool
+	Template     *template.Template
+	JSONEnvelope bool
+	// JSONCanonical requests byte-stable JSON with sorted keys.
+	JSONCanonical bool
+	// RequestID is sent as X-Request-Id on every API request of this
+	// invocation.
+	RequestID string
+	// PendingAppcastStatuses are appcast states that keep a wait going in
+	// addition to notarizing.
+	PendingAppcastStatuses []string
 }
 
+func (a *AppContext) outputOptions() outputOptions {
+	return outputOptions{JSON: a.JSON, Verbose: a.Verbose, Verbosity: a.Verbosity, NoAppcast: a.NoAppcast, OutputFile: a.OutputFile, AppendOutput: a.AppendOutput, Pretty: a.Pretty, Template: a.Template, JSONEnvelope: a.JSONEnvelope, JSONCanonical: a.JSONCanonical}
+}
+
+// Execute runs the CLI and reports any error on stderr. The returned error
+// only decides the exit code. SIGINT and SIGTERM cancel the command's
+// context.
 func Execute() error {
 	root := newRootCmd()
-	return root.Execute()
+	root.SilenceErrors = true
+	ctx, stop := notifyShutdown(context.Background(), root.ErrOrStderr())
+	defer stop()
+	err := root.ExecuteContext(ctx)
+	if err != nil {
+		jsonOut, _ := root.PersistentFlags().GetBool("json")
+		printError(root.ErrOrStderr(), sanitizeError(err, rootSecrets(root)...), jsonOut)
+	}
+	return err
+}
+
+// headerValue looks up name in headers case-insensitively.
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
 }
 
 func newRootCmd() *cobra.Command {
 	var (
-		apiKey  string
-		baseURL string
-		jsonOut bool
-		verbose bool
+		apiKey        string
+		baseURL       string
+		signingSecret string
+		jsonOut       bool
+		jsonEnvelope  bool
+		jsonCanonical bool
+		verbosity     int
+		headers       []string
+		headersFile   string
+		noAppcast     bool
+		ascii         bool
+		noColor       bool
+		colorMode     string
+		outputFile    string
+		appendOutput  bool
+		pretty        bool
+		strictVersion bool
+		strictScheme  bool
+		templateText  string
+		templateFile  string
+		preset        string
+		pendingStates []string
+		waitIdle      time.Duration
+		dialTimeout   time.Duration
+		noIdemKey     bool
+		maxRespSize   string
+		printCurl     bool
+		allowHosts    []string
+		noInput       bool
 	)
 
 	cmd := &cobra.Command{
@@ -46,11 +120,54 @@ func newRootCmd() *cobra.Command {
 		Short: "Twinkle CLI",
 		Long:  "Command-line interface for the Twinkle build API.",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Attach a console to each of the root's streams, which every
+			// command writes through, rather than keeping it in package state.
+			if noColor {
+				colorMode = colorNever
+			}
+			root := cmd.Root()
+			symbols := selectSymbols(ascii, os.Getenv, runtime.GOOS)
+			stdout, err := attachConsole(root.OutOrStdout(), symbols, colorMode)
+			if err != nil {
+				return err
+			}
+			stderr, err := attachConsole(root.ErrOrStderr(), symbols, colorMode)
+			if err != nil {
+				return err
+			}
+			root.SetOut(stdout)
+			root.SetErr(stderr)
+
 			// Skip API key requirement for certain commands
-			if cmd.Name() == "version" || cmd.Name() == "demo" {
+			if cmd.Name() == "version" || cmd.Name() == "demo" || cmd.Name() == "self-update" {
 				return nil
 			}
 
+			// The envelope and canonical output are JSON formats of their own.
+			if jsonEnvelope || jsonCanonical {
+				jsonOut = true
+			}
+
+			if appendOutput && (outputFile == "" || outputFile == "-") {
+				return errors.New("--append-output needs --output-file")
+			}
+
+			tmpl, err := parseOutputTemplate(templateText, templateFile)
+			if err != nil {
+				return err
+			}
+			if tmpl != nil && jsonOut {
+				return errors.New("--template and --template-file can't be combined with --json")
+			}
+			if preset != "" {
+				if tmpl != nil || jsonOut {
+					return errors.New("--preset can't be combined with --template, --template-file or --json")
+				}
+				if tmpl, err = presetTemplate(preset); err != nil {
+					return err
+				}
+			}
+
 			if apiKey == "" {
 				apiKey = os.Getenv(envAPIKey)
 			}
@@ -61,7 +178,72 @@ func newRootCmd() *cobra.Command {
 				}
 			}
 
-			client, err := api.NewClient(baseURL, apiKey, nil)
+			if err := checkBaseURLScheme(cmd.ErrOrStderr(), baseURL, strictScheme); err != nil {
+				return err
+			}
+
+			if signingSecret == "" {
+				signingSecret = os.Getenv(envSigningKey)
+			}
+
+			extraHeaders, err := keyValuesFromFlags("header", headersFile, headers)
+			if err != nil {
+				return err
+			}
+			// One ID per invocation ties all of its requests together in
+			// server logs. A --header value takes precedence.
+			requestID := headerValue(extraHeaders, api.RequestIDHeader)
+			if requestID == "" {
+				requestID = uuid.NewString()
+				extraHeaders = withKeyValue(extraHeaders, api.RequestIDHeader, requestID)
+			}
+			if verbosity > 0 && !jsonOut {
+				Statusf(cmd.ErrOrStderr(), "Request ID: %s", requestID)
+			}
+
+			var clientOpts []api.ClientOption
+			clientOpts = append(clientOpts, api.WithHeaders(extraHeaders), api.WithRetryPolicy(api.TransientRetryPolicy))
+			if waitIdle > 0 {
+				clientOpts = append(clientOpts, api.WithWaitIdleTimeout(waitIdle))
+			}
+			if dialTimeout > 0 {
+				clientOpts = append(clientOpts, api.WithDialTimeout(dialTimeout))
+			}
+			if noIdemKey {
+				clientOpts = append(clientOpts, api.WithoutIdempotencyKey())
+			}
+			if maxRespSize != "" {
+				limit, err := parseByteRate(maxRespSize)
+				if err != nil {
+					return fmt.Errorf("invalid --max-response-size: %w", err)
+				}
+				clientOpts = append(clientOpts, api.WithMaxResponseSize(limit))
+			}
+			if len(allowHosts) > 0 {
+				clientOpts = append(clientOpts, api.WithAllowedHosts(allowHosts...))
+			}
+			if verbosity > 0 && !jsonOut {
+				clientOpts = append(clientOpts, api.WithCallObserver(retryReporter(cmd.ErrOrStderr())))
+			}
+			if verbosity >= 2 && !jsonOut {
+				level := api.TraceHeaders
+				if verbosity >= 3 {
+					level = api.TraceBodies
+				}
+				clientOpts = append(clientOpts, api.WithTrace(cmd.ErrOrStderr(), level))
+			}
+			if printCurl {
+				clientOpts = append(clientOpts, api.WithCurlPrinter(cmd.ErrOrStderr()))
+			}
+			if signingSecret != "" {
+				clientOpts = append(clientOpts, api.WithRequestSigner(api.NewHMACSigner(signingSecret)))
+			}
+
+			if !strictVersion {
+				clientOpts = append(clientOpts, api.WithVersionObserver(warnServerVersion(cmd.ErrOrStderr())))
+			}
+
+			client, err := api.NewClient(baseURL, apiKey, nil, clientOpts...)
 			if err != nil {
 				if errors.Is(err, api.ErrMissingAPIKey) {
 					return fmt.Errorf("api key is required: set --api-key or %s", envAPIKey)
@@ -69,7 +251,27 @@ func newRootCmd() *cobra.Command {
 				return err
 			}
 
-			ctx := context.WithValue(cmd.Context(), appContextKey{}, &AppContext{Client: client, JSON: jsonOut, Verbose: verbose})
+			if strictVersion {
+				if err := checkServerVersion(cmd.Context(), client); err != nil {
+					return err
+				}
+			}
+
+			ctx := context.WithValue(cmd.Context(), appContextKey{}, &AppContext{
+				Client:                 client,
+				JSON:                   jsonOut,
+				Verbose:                verbosity > 0,
+				Verbosity:              verbosity,
+				NoAppcast:              noAppcast,
+				OutputFile:             outputFile,
+				AppendOutput:           appendOutput,
+				Pretty:                 pretty,
+				Template:               tmpl,
+				JSONEnvelope:           jsonEnvelope,
+				JSONCanonical:          jsonCanonical,
+				RequestID:              requestID,
+				PendingAppcastStatuses: pendingStates,
+			})
 			cmd.SetContext(ctx)
 			return nil
 		},
@@ -77,12 +279,40 @@ func newRootCmd() *cobra.Command {
 
 	cmd.PersistentFlags().StringVar(&apiKey, "api-key", "", "Twinkle API key (overrides "+envAPIKey+")")
 	cmd.PersistentFlags().StringVar(&baseURL, "base-url", "", "Twinkle API base URL (overrides "+envBaseURL+")")
+	cmd.PersistentFlags().StringVar(&signingSecret, "signing-secret", "", "Sign requests with HMAC-SHA256 using this secret (overrides "+envSigningKey+")")
+	cmd.PersistentFlags().StringArrayVar(&headers, "header", nil, "Send an extra header with every API request as Name=value (repeatable)")
+	cmd.PersistentFlags().StringVar(&headersFile, "headers-file", "", "Read extra API request headers from a Name=value or JSON file; --header values override it")
 	cmd.PersistentFlags().BoolVar(&jsonOut, "json", false, "Output JSON")
-	cmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output with timing and metadata")
+	cmd.PersistentFlags().BoolVar(&jsonEnvelope, "json-envelope", false, "Output JSON wrapped as {\"schema_version\": N, \"data\": ...} (implies --json)")
+	cmd.PersistentFlags().BoolVar(&jsonCanonical, "json-canonical", false, "Output byte-stable JSON with every object's keys sorted, for golden tests (implies --json)")
+	cmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "Verbose output: -v metadata and step timings, -vv adds HTTP timings and headers, -vvv full request/response traces")
+	cmd.PersistentFlags().BoolVar(&noAppcast, "no-appcast", false, "Hide appcast status for successful builds")
+	cmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (same as --color=never)")
+	cmd.PersistentFlags().StringVar(&colorMode, "color", colorAuto, "Color output: auto (terminals only), always, or never")
+	cmd.MarkFlagsMutuallyExclusive("color", "no-color")
+	cmd.PersistentFlags().BoolVar(&ascii, "ascii", false, "Use ASCII symbols instead of Unicode")
+	cmd.PersistentFlags().BoolVar(&strictVersion, "strict-version", false, "Fail instead of warning when the server API version is unsupported")
+	cmd.PersistentFlags().BoolVar(&strictScheme, "strict-scheme", false, "Fail instead of warning when the base URL uses plain http for a non-local host")
+	cmd.PersistentFlags().StringSliceVar(&pendingStates, "pending-appcast-status", nil, "Keep waiting while an available build's appcast is in this state, in addition to notarizing (repeatable)")
+	cmd.PersistentFlags().DurationVar(&waitIdle, "wait-idle-timeout", 0, "Give up on a wait request that gets no response for this long (e.g. 60s) and send a new one; 0 waits for the whole window")
+	cmd.PersistentFlags().DurationVar(&dialTimeout, "dial-timeout", 0, "Give up connecting to a host, DNS lookup included, after this long (default 30s)")
+	cmd.PersistentFlags().BoolVar(&noIdemKey, "no-idempotency-key", false, "Don't send an Idempotency-Key when creating uploads, for gateways that reject it (failed creates are then not retried)")
+	cmd.PersistentFlags().StringVar(&maxRespSize, "max-response-size", "", "Fail on API responses larger than this (e.g. 64MB; default 32MB)")
+	cmd.PersistentFlags().StringSliceVar(&allowHosts, "allow-host", nil, "Also follow status and wait URLs on this host, besides the API's own (repeatable)")
+	cmd.PersistentFlags().BoolVar(&printCurl, "print-curl", false, "Print an equivalent curl command for each API request to stderr (credentials masked)")
+	cmd.PersistentFlags().BoolVar(&noInput, "no-input", false, "Never prompt: fail where a prompt would be needed (pass --yes to confirm deletions)")
+	cmd.PersistentFlags().BoolVar(&pretty, "pretty", false, "Draw verbose details as an aligned table when writing to a terminal")
+	cmd.PersistentFlags().StringVar(&templateText, "template", "", "Render the result with a Go template (fields match --json output)")
+	cmd.PersistentFlags().StringVar(&templateFile, "template-file", "", "Render the result with a Go template read from this file")
+	cmd.PersistentFlags().StringVar(&preset, "preset", "", "Render the result with a built-in template: "+strings.Join(presetNames(), ", "))
+	cmd.PersistentFlags().StringVar(&outputFile, "output-file", "-", "Write the result to this file instead of stdout (- for stdout)")
+	cmd.PersistentFlags().BoolVar(&appendOutput, "append-output", false, "Append to --output-file instead of replacing it; JSON results are written one per line")
 
 	cmd.AddCommand(newBuildCmd())
+	cmd.AddCommand(newAppsCmd())
 	cmd.AddCommand(newShipCmd())
 	cmd.AddCommand(newVersionCmd())
+	cmd.AddCommand(newSelfUpdateCmd())
 
 	// Register debug-only commands (no-op in release builds)
 	if registerDemoCommand != nil {
@@ -92,6 +322,55 @@ func newRootCmd() *cobra.Command {
 	return cmd
 }
 
+// rootSecrets lists the credentials that must never appear in error output,
+// from flags or the environment.
+func rootSecrets(root *cobra.Command) []string {
+	apiKey, _ := root.PersistentFlags().GetString("api-key")
+	signingSecret, _ := root.PersistentFlags().GetString("signing-secret")
+	return []string{apiKey, os.Getenv(envAPIKey), signingSecret, os.Getenv(envSigningKey)}
+}
+
+// retryReporter returns a call observer that reports calls which needed
+// more than one attempt.
+func retryReporter(w io.Writer) func(api.CallStats) {
+	return func(stats api.CallStats) {
+		if stats.Attempts <= 1 {
+			return
+		}
+		outcome := "succeeded"
+		if stats.Err != nil {
+			outcome = "failed"
+		}
+		VerboseStatus(w, fmt.Sprintf("%s %s %s after %d attempts", stats.Method, stats.Path, outcome, stats.Attempts), stats.Elapsed)
+	}
+}
+
+// checkBaseURLScheme warns when the API key would be sent over plain http to
+// a host other than the local machine. With strict set, it is an error.
+func checkBaseURLScheme(w io.Writer, baseURL string, strict bool) error {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || !strings.EqualFold(parsed.Scheme, "http") || isLocalHost(parsed.Hostname()) {
+		return nil
+	}
+
+	problem := fmt.Sprintf("base URL %s uses http; the API key will be sent unencrypted", baseURL)
+	if strict {
+		return fmt.Errorf("%s (use https)", problem)
+	}
+	Statusf(w, "Warning: %s", problem)
+	return nil
+}
+
+// isLocalHost reports whether host refers to the local machine, where plain
+// http is fine for testing.
+func isLocalHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 func getAppContext(cmd *cobra.Command) (*AppContext, error) {
 	ctx := cmd.Context().Value(appContextKey{})
 	if ctx == nil {
@@ -0,0 +1,322 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+// executeCLI runs the root command against the given API server and returns
+// what was written to stdout and stderr.
+func executeCLI(t *testing.T, serverURL string, args ...string) (string, string, error) {
+	t.Helper()
+	return executeCLIWithInput(t, serverURL, "", args...)
+}
+
+// executeCLIWithInput is executeCLI with scripted stdin.
+func executeCLIWithInput(t *testing.T, serverURL, input string, args ...string) (string, string, error) {
+	t.Helper()
+
+	// Pin a UTF-8 locale so output uses the Unicode symbols whatever the
+	// host's locale is.
+	t.Setenv("LC_ALL", "en_US.UTF-8")
+
+	cmd := newRootCmd()
+	var stdout, stderr bytes.Buffer
+	cmd.SetIn(strings.NewReader(input))
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs(append([]string{"--api-key", "test-key", "--base-url", serverURL}, args...))
+
+	err := cmd.Execute()
+	return stdout.String(), stderr.String(), err
+}
+
+// fakeTerminal makes prompts treat stdin as interactive for the duration of
+// the test.
+func fakeTerminal(t *testing.T) {
+	t.Helper()
+	original := isTerminal
+	isTerminal = func(interface{}) bool { return true }
+	t.Cleanup(func() { isTerminal = original })
+}
+
+func TestRetryReporter(t *testing.T) {
+	var buf bytes.Buffer
+	report := retryReporter(&buf)
+
+	report(api.CallStats{Method: "GET", Path: "/api/v1/apps/app_123/builds/42", Attempts: 1})
+	if buf.Len() != 0 {
+		t.Fatalf("expected no output for a single attempt, got %q", buf.String())
+	}
+
+	report(api.CallStats{Method: "GET", Path: "/api/v1/apps/app_123/builds/42", Attempts: 2, Elapsed: 1500 * time.Millisecond})
+	if !strings.Contains(buf.String(), "GET /api/v1/apps/app_123/builds/42 succeeded after 2 attempts (1.5s)") {
+		t.Fatalf("expected attempt summary, got %q", buf.String())
+	}
+}
+
+func TestCheckBaseURLScheme(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		strict  bool
+		warn    bool
+		wantErr bool
+	}{
+		{name: "https", baseURL: "https://app.usetwinkle.com", strict: true},
+		{name: "http production warns", baseURL: "http://app.usetwinkle.com", warn: true},
+		{name: "http production strict", baseURL: "http://app.usetwinkle.com", strict: true, wantErr: true},
+		{name: "http localhost", baseURL: "http://localhost:3000", strict: true},
+		{name: "http loopback", baseURL: "http://127.0.0.1:3000", strict: true},
+		{name: "http ipv6 loopback", baseURL: "http://[::1]:3000", strict: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := checkBaseURLScheme(&buf, tt.baseURL, tt.strict)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkBaseURLScheme error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := strings.Contains(buf.String(), "Warning: base URL"); got != tt.warn {
+				t.Fatalf("warning = %v, want %v (output %q)", got, tt.warn, buf.String())
+			}
+		})
+	}
+}
+
+func TestStrictSchemeRejectsPlainHTTP(t *testing.T) {
+	_, _, err := executeCLI(t, "http://app.usetwinkle.com", "--strict-scheme", "build", "status", "app_123", "42")
+	if err == nil || !strings.Contains(err.Error(), "uses http") {
+		t.Fatalf("expected scheme error, got %v", err)
+	}
+}
+
+func TestPrintErrorRendersAPIErrorDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": "invalid_request",
+			"details": map[string]interface{}{
+				"build": map[string]interface{}{
+					"version": []string{"is required"},
+					"notes":   []string{"is too long", "contains invalid markup"},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	_, _, err := executeCLI(t, server.URL, "--header", "X-Request-Id=req-123", "build", "status", "app_123", "42")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	var buf bytes.Buffer
+	printError(&buf, err, false)
+	want := "Error: api error status 422: invalid_request\n" +
+		"  " + unicodeSymbols.Detail + " build.notes: is too long\n" +
+		"  " + unicodeSymbols.Detail + " build.notes: contains invalid markup\n" +
+		"  " + unicodeSymbols.Detail + " build.version: is required\n" +
+		"  " + unicodeSymbols.Detail + " Request ID: req-123\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected text error:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	printError(&buf, err, true)
+	if !strings.Contains(buf.String(), `{"build":`) || strings.Contains(buf.String(), unicodeSymbols.Detail) || !strings.HasSuffix(buf.String(), "\nRequest ID: req-123\n") {
+		t.Fatalf("expected JSON mode to keep the raw details, got %q", buf.String())
+	}
+}
+
+func TestPrintErrorShowsHelpURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": "build_number_too_low",
+			"details": map[string]interface{}{
+				"build_number": []string{"must be greater than 42"},
+				"help_url":     "https://docs.example.com/errors/build-number",
+			},
+		})
+	}))
+	defer server.Close()
+
+	_, _, err := executeCLI(t, server.URL, "--header", "X-Request-Id=req-123", "build", "status", "app_123", "42")
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) || apiErr.HelpURL != "https://docs.example.com/errors/build-number" {
+		t.Fatalf("expected the help URL on the API error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	printError(&buf, err, false)
+	want := "Error: api error status 422: build_number_too_low\n" +
+		"  " + unicodeSymbols.Detail + " build_number: must be greater than 42\n" +
+		"  See: https://docs.example.com/errors/build-number\n" +
+		"  " + unicodeSymbols.Detail + " Request ID: req-123\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected text error:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	printError(&buf, err, true)
+	if !strings.Contains(buf.String(), "\nSee: https://docs.example.com/errors/build-number\n") {
+		t.Fatalf("expected the help URL in JSON mode, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"help_url":"https://docs.example.com/errors/build-number"`) {
+		t.Fatalf("expected help_url in the JSON details payload, got %q", buf.String())
+	}
+}
+
+func TestPrintErrorMasksHelpURL(t *testing.T) {
+	apiErr := &api.APIError{StatusCode: http.StatusForbidden, Message: "forbidden", HelpURL: "https://docs.example.com/keys?key=sk_live_abcdef123456"}
+
+	var buf bytes.Buffer
+	printError(&buf, sanitizeError(apiErr, "sk_live_abcdef123456"), false)
+	if strings.Contains(buf.String(), "sk_live_abcdef123456") || !strings.Contains(buf.String(), "See: https://docs.example.com/keys?key=") {
+		t.Fatalf("expected the key masked in the help URL, got %q", buf.String())
+	}
+}
+
+func TestCLIRetriesTransientFailures(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	_, _, err := executeCLI(t, server.URL, "build", "status", "app_123", "42")
+	if err == nil {
+		t.Fatal("expected the status request to fail")
+	}
+	if got := atomic.LoadInt32(&requests); got != int32(api.TransientRetryPolicy.MaxAttempts) {
+		t.Fatalf("expected %d attempts, got %d", api.TransientRetryPolicy.MaxAttempts, got)
+	}
+
+	var buf bytes.Buffer
+	printError(&buf, err, false)
+	if !strings.Contains(buf.String(), "Gave up after 3 attempts") {
+		t.Fatalf("expected the attempt count in the error, got %q", buf.String())
+	}
+	buf.Reset()
+	printError(&buf, err, true)
+	if !strings.Contains(buf.String(), "\nAttempts: 3\n") {
+		t.Fatalf("expected the attempt count in JSON mode, got %q", buf.String())
+	}
+}
+
+func TestPrintErrorSkipsSilentExit(t *testing.T) {
+	var buf bytes.Buffer
+	printError(&buf, &ExitError{Code: 3}, false)
+	if buf.Len() != 0 {
+		t.Fatalf("expected no output, got %q", buf.String())
+	}
+}
+
+func TestSanitizeErrorMasksSecrets(t *testing.T) {
+	const key = "tw_live_abcdef123456"
+	inner := &ExitError{Code: 4, Err: errors.New("sent Authorization: Bearer tok_0987654321")}
+	err := fmt.Errorf("request https://example.com/builds?api_key=%s failed: %w", key, inner)
+
+	sanitized := sanitizeError(err, key, "")
+	var buf bytes.Buffer
+	printError(&buf, sanitized, false)
+
+	out := buf.String()
+	if strings.Contains(out, key) || strings.Contains(out, "tok_0987654321") {
+		t.Fatalf("expected secrets to be masked, got %q", out)
+	}
+	if !strings.Contains(out, "3456 failed") || !strings.Contains(out, "4321\n") {
+		t.Fatalf("expected masked values, got %q", out)
+	}
+
+	var exitErr *ExitError
+	if !errors.As(sanitized, &exitErr) || exitErr.Code != 4 {
+		t.Fatalf("expected the wrapped exit error to survive sanitizing, got %v", sanitized)
+	}
+}
+
+func TestPrintErrorMasksOnceWithASCIISymbols(t *testing.T) {
+	const key = "tw_live_abcdef123456"
+	err := fmt.Errorf("request with api_key=%s failed, sent Authorization: Bearer tok_0987654321", key)
+
+	var buf bytes.Buffer
+	printError(withConsole(&buf, &console{symbols: asciiSymbols}), sanitizeError(err, key), false)
+
+	want := "Error: request with api_key=" + strings.Repeat("*", len(key)-4) + "3456 failed, sent Authorization: Bearer " + strings.Repeat("*", len("tok_0987654321")-4) + "4321\n"
+	if buf.String() != want {
+		t.Fatalf("expected the secrets masked once in ASCII, got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestSanitizeErrorMasksAPIErrorDetails(t *testing.T) {
+	const key = "tw_live_abcdef123456"
+	err := fmt.Errorf("get build: %w", &api.APIError{
+		StatusCode: http.StatusUnprocessableEntity,
+		Message:    "invalid_request",
+		Details:    map[string]interface{}{"api_key": "unknown key " + key},
+	})
+
+	var buf bytes.Buffer
+	printError(&buf, sanitizeError(err, key), false)
+	if strings.Contains(buf.String(), key) {
+		t.Fatalf("expected the key to be masked in details, got %q", buf.String())
+	}
+	if !strings.HasPrefix(buf.String(), "Error: get build: api error status 422: invalid_request\n") {
+		t.Fatalf("expected details to render as lines, got %q", buf.String())
+	}
+}
+
+func TestRequestIDIsSharedAcrossAnInvocation(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		ids []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ids = append(ids, r.Header.Get("X-Request-Id"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(api.BuildResponse{Build: api.Build{ID: 42, Status: api.BuildStatusAvailable}})
+	}))
+	defer server.Close()
+
+	_, stderr, err := executeCLI(t, server.URL, "-v", "build", "diff", "app_123", "41", "42")
+	if err != nil {
+		t.Fatalf("build diff: %v", err)
+	}
+	firstRun := append([]string(nil), ids...)
+	if len(firstRun) < 2 {
+		t.Fatalf("expected several requests, got %d", len(firstRun))
+	}
+	for _, id := range firstRun {
+		if id == "" || id != firstRun[0] {
+			t.Fatalf("expected one request ID across the invocation, got %v", firstRun)
+		}
+	}
+	if !strings.Contains(stderr, "Request ID: "+firstRun[0]) {
+		t.Fatalf("expected verbose output to show the request ID, got %q", stderr)
+	}
+
+	if _, _, err := executeCLI(t, server.URL, "build", "status", "app_123", "42"); err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if last := ids[len(ids)-1]; last == firstRun[0] {
+		t.Fatalf("expected a new request ID per invocation, got %s again", last)
+	}
+}
@@ -0,0 +1,370 @@
+package cli
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultUpdateURL = "https://github.com/twinkle-apps/cli/releases"
+	envUpdateURL     = "TWINKLE_UPDATE_URL"
+
+	// managedMarker is a file package managers install next to the binary.
+	// Its contents name the manager, e.g. "homebrew".
+	managedMarker = "twinkle.managed"
+
+	updateTimeout = 5 * time.Minute
+
+	// Download limits: the checksum file is a few lines, the archive and the
+	// binary in it a few tens of megabytes. Anything bigger is not a release.
+	maxMetadataDownload = 1 << 20
+	maxBinaryDownload   = 256 << 20
+)
+
+// executablePath locates the running binary. Tests replace it.
+var executablePath = func() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(path)
+}
+
+// release is a verified binary extracted from a release archive.
+type release struct {
+	Version string
+	Asset   string
+	Binary  []byte
+}
+
+func newSelfUpdateCmd() *cobra.Command {
+	var (
+		updateURL string
+		check     bool
+		force     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Replace this binary with the latest release",
+		Long: "Download the latest release archive for this platform, verify its SHA-256 checksum and replace the running binary with the one inside.\n\n" +
+			"The checksum only guards against a corrupted download: the checksum file comes from the same server as the archive and is not signed, so use --url only with a mirror you trust.\n\n" +
+			"The update URL is laid out like a GitHub releases page: <url>/latest redirects to <url>/tag/<tag>, and <url>/download/<tag>/ serves twinkle_<version>_checksums.txt and the archives twinkle_<version>_<os>_<arch>.tar.gz (.zip on Windows).",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if updateURL == "" {
+				updateURL = os.Getenv(envUpdateURL)
+				if updateURL == "" {
+					updateURL = defaultUpdateURL
+				}
+			}
+			stderr := cmd.ErrOrStderr()
+			ctx, cancel := context.WithTimeout(cmd.Context(), updateTimeout)
+			defer cancel()
+			client := &http.Client{}
+
+			tag, err := fetchLatestTag(ctx, client, updateURL)
+			if err != nil {
+				return err
+			}
+			latest := strings.TrimPrefix(tag, "v")
+			if !newerVersion(latest, Version) && !force {
+				Successf(cmd.OutOrStdout(), "twinkle %s is up to date", Version)
+				return nil
+			}
+			if check {
+				fmt.Fprintf(cmd.OutOrStdout(), "twinkle %s is available (installed: %s)\n", latest, Version)
+				return nil
+			}
+			if Version == "dev" && !force {
+				return errors.New("refusing to replace a development build: pass --force to update anyway")
+			}
+
+			exe, err := executablePath()
+			if err != nil {
+				return fmt.Errorf("locate twinkle binary: %w", err)
+			}
+			if err := checkReplaceable(exe); err != nil {
+				return err
+			}
+
+			Statusf(stderr, "Downloading twinkle %s for %s/%s…", latest, runtime.GOOS, runtime.GOARCH)
+			rel, err := fetchRelease(ctx, client, updateURL, tag, runtime.GOOS, runtime.GOARCH)
+			if err != nil {
+				return err
+			}
+			if err := replaceExecutable(exe, rel.Binary); err != nil {
+				return err
+			}
+			Successf(cmd.OutOrStdout(), "Updated twinkle %s → %s", Version, rel.Version)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&updateURL, "url", "", "Release download URL (overrides "+envUpdateURL+")")
+	cmd.Flags().BoolVar(&check, "check", false, "Only report whether a newer release is available")
+	cmd.Flags().BoolVar(&force, "force", false, "Reinstall even when up to date, and allow replacing development builds")
+
+	return cmd
+}
+
+// newerVersion reports whether latest is a later dotted version than
+// current.
+func newerVersion(latest, current string) bool {
+	return compareVersions(latest, current) > 0
+}
+
+// checkReplaceable refuses binaries a package manager owns and directories
+// the user can't write to.
+func checkReplaceable(exe string) error {
+	dir := filepath.Dir(exe)
+	if data, err := os.ReadFile(filepath.Join(dir, managedMarker)); err == nil {
+		manager := strings.TrimSpace(string(data))
+		if manager == "" {
+			manager = "a package manager"
+		}
+		return fmt.Errorf("twinkle was installed by %s: update it there instead", manager)
+	}
+
+	probe, err := os.CreateTemp(dir, ".twinkle-update-*")
+	if err != nil {
+		return fmt.Errorf("can't replace %s: %s is not writable", exe, dir)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return nil
+}
+
+// fetchLatestTag reads the latest release's tag from where <baseURL>/latest
+// redirects, e.g. .../releases/tag/v1.4.0.
+func fetchLatestTag(ctx context.Context, client *http.Client, baseURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(baseURL, "/")+"/latest", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "twinkle/"+Version)
+	noRedirect := *client
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err := noRedirect.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("find latest release: %w", err)
+	}
+	resp.Body.Close()
+	location, err := resp.Location()
+	if err != nil {
+		return "", fmt.Errorf("find latest release: %s did not redirect to a release (%s)", req.URL, resp.Status)
+	}
+	dir, tag := path.Split(location.Path)
+	if !strings.HasSuffix(dir, "/tag/") || tag == "" {
+		return "", fmt.Errorf("find latest release: unexpected redirect to %s", location.Path)
+	}
+	return tag, nil
+}
+
+// releaseAssets returns the names goreleaser gives the archive for
+// goos/goarch, the checksum file and the binary inside the archive.
+func releaseAssets(version, goos, goarch string) (archive, checksums, binary string) {
+	archive = fmt.Sprintf("twinkle_%s_%s_%s.tar.gz", version, goos, goarch)
+	binary = "twinkle"
+	if goos == "windows" {
+		archive = strings.TrimSuffix(archive, ".tar.gz") + ".zip"
+		binary += ".exe"
+	}
+	return archive, fmt.Sprintf("twinkle_%s_checksums.txt", version), binary
+}
+
+// fetchRelease downloads the archive tag published for goos/goarch, checks
+// it against the release's checksum file and extracts the binary. Nothing is
+// returned unless the checksum matches.
+func fetchRelease(ctx context.Context, client *http.Client, baseURL, tag, goos, goarch string) (release, error) {
+	version := strings.TrimPrefix(tag, "v")
+	archive, checksums, binaryName := releaseAssets(version, goos, goarch)
+	dir := strings.TrimRight(baseURL, "/") + "/download/" + tag
+
+	sums, err := download(ctx, client, dir, checksums, maxMetadataDownload)
+	if err != nil {
+		return release{}, err
+	}
+	want, err := checksumFor(sums, checksums, archive)
+	if err != nil {
+		return release{}, err
+	}
+	data, err := download(ctx, client, dir, archive, maxBinaryDownload)
+	if err != nil {
+		return release{}, err
+	}
+	if err := verifyChecksum(data, want); err != nil {
+		return release{}, fmt.Errorf("%s: %w", archive, err)
+	}
+	binary, err := extractBinary(data, archive, binaryName)
+	if err != nil {
+		return release{}, err
+	}
+	return release{Version: version, Asset: archive, Binary: binary}, nil
+}
+
+// checksumFor finds asset's SHA-256 in sums, sha256sum output read from the
+// file named file.
+func checksumFor(sums []byte, file, asset string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s has no entry for %s", file, asset)
+}
+
+// extractBinary returns the regular file named binary from archive, a
+// .tar.gz or .zip named name. goreleaser puts it at the top level, but any
+// directory is accepted.
+func extractBinary(archive []byte, name, binary string) ([]byte, error) {
+	if strings.HasSuffix(name, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", name, err)
+		}
+		for _, f := range zr.File {
+			if path.Base(f.Name) != binary || !f.Mode().IsRegular() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, fmt.Errorf("extract %s from %s: %w", binary, name, err)
+			}
+			defer rc.Close()
+			return readBinary(rc, name, binary)
+		}
+		return nil, fmt.Errorf("%s has no %s", name, binary)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", name, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s has no %s", name, binary)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", name, err)
+		}
+		if hdr.Typeflag == tar.TypeReg && path.Base(hdr.Name) == binary {
+			return readBinary(tr, name, binary)
+		}
+	}
+}
+
+func readBinary(r io.Reader, name, binary string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBinaryDownload+1))
+	if err != nil {
+		return nil, fmt.Errorf("extract %s from %s: %w", binary, name, err)
+	}
+	if int64(len(data)) > maxBinaryDownload {
+		return nil, fmt.Errorf("extract %s from %s: larger than %s", binary, name, formatBytes(maxBinaryDownload))
+	}
+	return data, nil
+}
+
+func verifyChecksum(data []byte, want string) error {
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("checksum mismatch: got %s, want %s", got, want)
+	}
+	return nil
+}
+
+// download fetches name from baseURL, failing if it is larger than limit
+// bytes.
+func download(ctx context.Context, client *http.Client, baseURL, name string, limit int64) ([]byte, error) {
+	url := strings.TrimRight(baseURL, "/") + "/" + name
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "twinkle/"+Version)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: %s", name, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", name, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("download %s: larger than %s", name, formatBytes(int(limit)))
+	}
+	return data, nil
+}
+
+// replaceExecutable writes binary next to exe and renames it into place, so
+// an interrupted update leaves the old binary intact. Windows can't replace a
+// running executable, so the old one is moved aside first and moved back if
+// the new one can't take its place.
+func replaceExecutable(exe string, binary []byte) error {
+	dir := filepath.Dir(exe)
+	tmp, err := os.CreateTemp(dir, ".twinkle-update-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return err
+	}
+
+	if runtime.GOOS != "windows" {
+		if err := os.Rename(tmp.Name(), exe); err != nil {
+			return fmt.Errorf("replace %s: %w", exe, err)
+		}
+		return nil
+	}
+
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		if restoreErr := os.Rename(old, exe); restoreErr != nil {
+			return fmt.Errorf("replace %s: %w (the previous binary is at %s)", exe, err, old)
+		}
+		return fmt.Errorf("replace %s: %w", exe, err)
+	}
+	return nil
+}
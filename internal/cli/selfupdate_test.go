@@ -0,0 +1,271 @@
+package cli
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestNewerVersion(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"1.4.0", "1.3.9", true},
+		{"1.4.1", "1.4", true},
+		{"v2.0.0", "1.9.9", true},
+		{"1.4.0", "1.4.0", false},
+		{"1.4", "1.4.0", false},
+		{"1.4.0.1", "1.4.0", true},
+		{"1.4.1", "1.4.1.0", false},
+		{"1.4.0", "1.4.1", false},
+		{"1.3.0", "1.4.0", false},
+		{"1.0.0", "dev", true},
+	}
+	for _, tt := range tests {
+		if got := newerVersion(tt.latest, tt.current); got != tt.want {
+			t.Errorf("newerVersion(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+		}
+	}
+}
+
+// releaseArchive packs binary as a goreleaser archive would for goos: a
+// tar.gz, or a zip on Windows, with the binary at the top level next to the
+// README.
+func releaseArchive(t *testing.T, goos, binary string) []byte {
+	t.Helper()
+	_, _, name := releaseAssets("9.9.9", goos, "amd64")
+	files := []struct{ name, body string }{{"README.md", "readme"}, {name, binary}}
+	var buf bytes.Buffer
+	if goos == "windows" {
+		zw := zip.NewWriter(&buf)
+		for _, f := range files {
+			w, err := zw.Create(f.name)
+			if err != nil {
+				t.Fatalf("zip: %v", err)
+			}
+			_, _ = io.WriteString(w, f.body)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("zip: %v", err)
+		}
+		return buf.Bytes()
+	}
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o755, Size: int64(len(f.body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("tar: %v", err)
+		}
+		_, _ = io.WriteString(tw, f.body)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// newReleaseServer serves release v9.9.9 the way GitHub serves goreleaser's
+// output: /latest redirects to the tag, and /download/v9.9.9/ holds the
+// checksum file and the linux/amd64 and windows/amd64 archives. sum
+// overrides the linux archive's listed checksum when set.
+func newReleaseServer(t *testing.T, binary, sum string) *httptest.Server {
+	t.Helper()
+	archives := map[string][]byte{}
+	var sums strings.Builder
+	for _, goos := range []string{"linux", "windows"} {
+		name, _, _ := releaseAssets("9.9.9", goos, "amd64")
+		archives[name] = releaseArchive(t, goos, binary)
+		digest := sha256.Sum256(archives[name])
+		listed := hex.EncodeToString(digest[:])
+		if goos == "linux" && sum != "" {
+			listed = sum
+		}
+		fmt.Fprintf(&sums, "%s  %s\n", listed, name)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/download/v9.9.9/")
+		switch {
+		case r.URL.Path == "/latest":
+			http.Redirect(w, r, "/tag/v9.9.9", http.StatusFound)
+		case name == "twinkle_9.9.9_checksums.txt":
+			fmt.Fprint(w, sums.String())
+		case archives[name] != nil:
+			_, _ = w.Write(archives[name])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchLatestTagReadsRedirect(t *testing.T) {
+	server := newReleaseServer(t, "new binary", "")
+
+	tag, err := fetchLatestTag(context.Background(), server.Client(), server.URL)
+	if err != nil || tag != "v9.9.9" {
+		t.Fatalf("expected v9.9.9, got %q (%v)", tag, err)
+	}
+	if _, err := fetchLatestTag(context.Background(), server.Client(), server.URL+"/download"); err == nil || !strings.Contains(err.Error(), "did not redirect") {
+		t.Fatalf("expected a missing redirect to fail, got %v", err)
+	}
+}
+
+func TestFetchReleaseVerifiesChecksum(t *testing.T) {
+	server := newReleaseServer(t, "new binary", "")
+
+	for _, tt := range []struct{ goos, asset string }{
+		{"linux", "twinkle_9.9.9_linux_amd64.tar.gz"},
+		{"windows", "twinkle_9.9.9_windows_amd64.zip"},
+	} {
+		rel, err := fetchRelease(context.Background(), server.Client(), server.URL, "v9.9.9", tt.goos, "amd64")
+		if err != nil {
+			t.Fatalf("fetch %s release: %v", tt.goos, err)
+		}
+		if string(rel.Binary) != "new binary" || rel.Asset != tt.asset || rel.Version != "9.9.9" {
+			t.Fatalf("unexpected %s release: %+v", tt.goos, rel)
+		}
+	}
+}
+
+func TestFetchReleaseRejectsChecksumMismatch(t *testing.T) {
+	server := newReleaseServer(t, "tampered binary", strings.Repeat("a", 64))
+
+	_, err := fetchRelease(context.Background(), server.Client(), server.URL, "v9.9.9", "linux", "amd64")
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestFetchReleaseRequiresChecksumEntry(t *testing.T) {
+	server := newReleaseServer(t, "new binary", "")
+
+	_, err := fetchRelease(context.Background(), server.Client(), server.URL, "v9.9.9", "freebsd", "amd64")
+	if err == nil || !strings.Contains(err.Error(), "twinkle_9.9.9_checksums.txt has no entry for twinkle_9.9.9_freebsd_amd64.tar.gz") {
+		t.Fatalf("expected missing checksum error, got %v", err)
+	}
+}
+
+func TestExtractBinaryRequiresBinary(t *testing.T) {
+	archive := releaseArchive(t, "linux", "new binary")
+
+	_, err := extractBinary(archive, "twinkle_9.9.9_linux_amd64.tar.gz", "twinkle.exe")
+	if err == nil || !strings.Contains(err.Error(), "has no twinkle.exe") {
+		t.Fatalf("expected a missing binary error, got %v", err)
+	}
+}
+
+func TestDownloadRejectsOversizedFile(t *testing.T) {
+	server := newReleaseServer(t, "new binary", "")
+	dir := server.URL + "/download/v9.9.9"
+	size := len(releaseArchive(t, "linux", "new binary"))
+
+	_, err := download(context.Background(), server.Client(), dir, "twinkle_9.9.9_linux_amd64.tar.gz", int64(size-1))
+	if err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("expected a size limit error, got %v", err)
+	}
+	if _, err := download(context.Background(), server.Client(), dir, "twinkle_9.9.9_linux_amd64.tar.gz", int64(size)); err != nil {
+		t.Fatalf("expected a file at the limit to download, got %v", err)
+	}
+}
+
+// fakeExecutable points executablePath at a file in a temp dir.
+func fakeExecutable(t *testing.T) string {
+	t.Helper()
+	exe := filepath.Join(t.TempDir(), "twinkle")
+	if err := os.WriteFile(exe, []byte("old binary"), 0o755); err != nil {
+		t.Fatalf("write executable: %v", err)
+	}
+	original := executablePath
+	executablePath = func() (string, error) { return exe, nil }
+	t.Cleanup(func() { executablePath = original })
+	return exe
+}
+
+func TestSelfUpdateRefusesManagedInstall(t *testing.T) {
+	exe := fakeExecutable(t)
+	if err := os.WriteFile(filepath.Join(filepath.Dir(exe), managedMarker), []byte("homebrew\n"), 0o644); err != nil {
+		t.Fatalf("write marker: %v", err)
+	}
+	server := newReleaseServer(t, "new binary", "")
+
+	_, _, err := executeCLI(t, server.URL, "self-update", "--url", server.URL, "--force")
+	if err == nil || !strings.Contains(err.Error(), "installed by homebrew") {
+		t.Fatalf("expected package manager refusal, got %v", err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old binary" {
+		t.Fatalf("expected the binary to be untouched, got %q", data)
+	}
+}
+
+func TestSelfUpdateCheckOnlyReports(t *testing.T) {
+	exe := fakeExecutable(t)
+	server := newReleaseServer(t, "new binary", "")
+
+	stdout, _, err := executeCLI(t, server.URL, "self-update", "--url", server.URL, "--check")
+	if err != nil {
+		t.Fatalf("self-update --check: %v", err)
+	}
+	if !strings.Contains(stdout, "twinkle 9.9.9 is available") {
+		t.Fatalf("expected availability notice, got %q", stdout)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old binary" {
+		t.Fatalf("expected --check to leave the binary alone, got %q", data)
+	}
+}
+
+func TestSelfUpdateInstallsReleaseBinary(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("the release server only publishes linux/amd64 and windows/amd64")
+	}
+	exe := fakeExecutable(t)
+	server := newReleaseServer(t, "new binary", "")
+
+	stdout, stderr, err := executeCLI(t, server.URL, "self-update", "--url", server.URL, "--force")
+	if err != nil {
+		t.Fatalf("self-update: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "→ 9.9.9") {
+		t.Fatalf("expected an update notice, got %q", stdout)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "new binary" {
+		t.Fatalf("expected the binary from the archive, got %q", data)
+	}
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	exe := fakeExecutable(t)
+
+	if err := replaceExecutable(exe, []byte("new binary")); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil || string(data) != "new binary" {
+		t.Fatalf("expected the new binary, got %q (%v)", data, err)
+	}
+	info, err := os.Stat(exe)
+	if err != nil || info.Mode().Perm()&0o100 == 0 {
+		t.Fatalf("expected an executable file, got %v (%v)", info.Mode(), err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(exe))
+	if len(entries) != 1 {
+		t.Fatalf("expected no leftover temp files, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// shutdownSignals cancel the running command. CI runners send SIGTERM when a
+// job is cancelled, ahead of SIGKILL.
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// notifyShutdown returns a context that is cancelled on the first shutdown
+// signal, so in-flight requests and uploads stop cleanly. A second signal
+// gets the default behavior and terminates the process. Call stop to release
+// the handler.
+func notifyShutdown(parent context.Context, w io.Writer) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(parent)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, shutdownSignals...)
+	stop = func() {
+		signal.Stop(sigs)
+		cancel()
+	}
+	go handleShutdownSignal(ctx, w, sigs, stop)
+	return ctx, stop
+}
+
+// handleShutdownSignal waits for a signal on sigs, reports it and cancels.
+// It returns without cancelling once ctx is done.
+func handleShutdownSignal(ctx context.Context, w io.Writer, sigs <-chan os.Signal, cancel func()) {
+	select {
+	case sig := <-sigs:
+		Statusf(w, "Received %s, cleaning up", sig)
+		cancel()
+	case <-ctx.Done():
+	}
+}
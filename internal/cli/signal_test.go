@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestShutdownSignalCancelsRunningCommand(t *testing.T) {
+	requested := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/wait") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// Hold the wait until the client gives up.
+		close(requested)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigs := make(chan os.Signal, 1)
+	var stderr bytes.Buffer
+	handled := make(chan struct{})
+	go func() {
+		handleShutdownSignal(ctx, &stderr, sigs, cancel)
+		close(handled)
+	}()
+
+	cmd := newRootCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--api-key", "test-key", "--base-url", server.URL, "--json", "build", "wait", "app_123", "42", "--timeout", "60"})
+	result := make(chan error, 1)
+	go func() { result <- cmd.ExecuteContext(ctx) }()
+
+	select {
+	case <-requested:
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait request never started")
+	}
+	sigs <- syscall.SIGTERM
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context cancellation, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("command kept running after the signal")
+	}
+	<-handled
+	if !strings.Contains(stderr.String(), "Received terminated, cleaning up") {
+		t.Fatalf("expected a cleanup status, got %q", stderr.String())
+	}
+}
+
+func TestShutdownHandlerReturnsWhenContextEnds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var stderr bytes.Buffer
+	handleShutdownSignal(ctx, &stderr, make(chan os.Signal), func() { t.Fatal("unexpected cancel") })
+	if stderr.Len() != 0 {
+		t.Fatalf("expected no output, got %q", stderr.String())
+	}
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs is available to every output template, inline or from a file.
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join": func(sep string, values []interface{}) string {
+		parts := make([]string, len(values))
+		for i, value := range values {
+			parts[i] = fmt.Sprint(value)
+		}
+		return strings.Join(parts, sep)
+	},
+}
+
+// parseOutputTemplate builds the --template or --template-file template.
+// It returns nil when neither is set.
+func parseOutputTemplate(inline, path string) (*template.Template, error) {
+	switch {
+	case inline != "" && path != "":
+		return nil, errors.New("use either --template or --template-file, not both")
+	case inline != "":
+		tmpl, err := template.New("template").Funcs(templateFuncs).Parse(inline)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --template: %w", err)
+		}
+		return tmpl, nil
+	case path != "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("template file %s does not exist", path)
+			}
+			return nil, fmt.Errorf("read template file: %w", err)
+		}
+		tmpl, err := template.New(path).Funcs(templateFuncs).Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid template file %s: %w", path, err)
+		}
+		return tmpl, nil
+	default:
+		return nil, nil
+	}
+}
+
+// renderTemplate executes tmpl against payload's JSON form, so templates use
+// the same field names as --json output (e.g. {{.build.status}}).
+func renderTemplate(w io.Writer, tmpl *template.Template, payload interface{}) error {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode template data: %w", err)
+	}
+	var data interface{}
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		return fmt.Errorf("encode template data: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("render template: %w", err)
+	}
+	if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplateFileRendersPayload(t *testing.T) {
+	server, _ := newStatusSequenceServer(t, "available")
+	path := filepath.Join(t.TempDir(), "status.tmpl")
+	if err := os.WriteFile(path, []byte(`{{.build.id}} {{upper .build.status}} {{.appcast.feed_url}}`), 0644); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+
+	stdout, _, err := executeCLI(t, server.URL, "--template-file", path, "build", "status", "app_123", "42")
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if stdout != "42 AVAILABLE https://example.com/feed.xml\n" {
+		t.Fatalf("unexpected template output %q", stdout)
+	}
+}
+
+func TestTemplateFileMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.tmpl")
+
+	_, _, err := executeCLI(t, "https://example.com", "--template-file", path, "build", "status", "app_123", "42")
+	if err == nil || !strings.Contains(err.Error(), "template file "+path+" does not exist") {
+		t.Fatalf("expected missing file error, got %v", err)
+	}
+}
+
+func TestTemplateFileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.tmpl")
+	if err := os.WriteFile(path, []byte(`{{.build.id`), 0644); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+
+	_, _, err := executeCLI(t, "https://example.com", "--template-file", path, "build", "status", "app_123", "42")
+	if err == nil || !strings.Contains(err.Error(), "invalid template file") {
+		t.Fatalf("expected parse error, got %v", err)
+	}
+}
+
+func TestInlineTemplateSharesFuncs(t *testing.T) {
+	server, _ := newStatusSequenceServer(t, "available")
+
+	stdout, _, err := executeCLI(t, server.URL, "--template", `{{json .build.id}}`, "build", "status", "app_123", "42")
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if stdout != "42\n" {
+		t.Fatalf("unexpected template output %q", stdout)
+	}
+}
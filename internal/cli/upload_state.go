@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+// uploadState records an upload whose file has been sent but which hasn't
+// been completed, so a later CI job can finish it with
+// `build complete --from-state`.
+type uploadState struct {
+	AppID  string                  `json:"app_id"`
+	File   string                  `json:"file"`
+	Upload api.BuildUploadResponse `json:"upload"`
+	// Size is the number of bytes uploaded, checked against the build size
+	// the server reports on completion.
+	Size int64 `json:"size,omitempty"`
+}
+
+// saveUploadState writes state to path readable only by its owner, since the
+// presigned upload URL in it grants write access to storage until it expires.
+func saveUploadState(path string, state uploadState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode upload state: %w", err)
+	}
+	if err := writeFileAtomic(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("save upload state: %w", err)
+	}
+	return nil
+}
+
+func loadUploadState(path string) (uploadState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return uploadState{}, fmt.Errorf("read upload state: %w", err)
+	}
+	var state uploadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return uploadState{}, fmt.Errorf("parse upload state %s: %w", path, err)
+	}
+	if state.AppID == "" {
+		return uploadState{}, errors.New("upload state is missing app_id")
+	}
+	if state.Upload.BuildID.Int() == 0 {
+		return uploadState{}, errors.New("upload state is missing upload.build_id")
+	}
+	return state, nil
+}
+
+func init() {
+	registerPrinter(printUploadState)
+}
+
+func printUploadState(cmd *cobra.Command, state uploadState, opts outputOptions) {
+	out := cmd.OutOrStdout()
+	Successf(out, "Uploaded build %d (not yet completed)", state.Upload.BuildID.Int())
+	if opts.Verbose {
+		printDetails(out, []detailRow{
+			{Key: "App ID", Value: state.AppID},
+			{Key: "File", Value: state.File},
+		}, opts.Pretty)
+	}
+}
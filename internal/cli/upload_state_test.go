@@ -0,0 +1,376 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twinkle-apps/cli/internal/api"
+)
+
+// uploadServerApps are the apps newUploadServer knows.
+var uploadServerApps = []api.App{
+	{ID: "app_123", Name: "MyApp", BundleID: "com.example.app"},
+	{ID: "app_456", Name: "MyHelper", BundleID: "com.example.helper"},
+}
+
+// uploadServer is a fake API for the upload flow. Any app can create build
+// 7, PUT it to /storage/7 and complete it, after which the build is
+// available with a published appcast. It records every request it sees.
+type uploadServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	recorded []recordedRequest
+}
+
+// recordedRequest is one request an uploadServer received.
+type recordedRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Body   []byte
+}
+
+// uploadServerOption replaces or adds routes, keyed by ServeMux pattern.
+type uploadServerOption func(routes map[string]http.HandlerFunc)
+
+func newUploadServer(t *testing.T, opts ...uploadServerOption) *uploadServer {
+	t.Helper()
+
+	available := func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("build"))
+		_ = json.NewEncoder(w).Encode(api.BuildResponse{
+			Build:   api.Build{ID: id, Status: api.BuildStatusAvailable},
+			Appcast: api.Appcast{Status: "published", FeedURL: "https://example.com/appcast.xml"},
+		})
+	}
+	routes := map[string]http.HandlerFunc{
+		"/api/v1/apps": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(api.AppsResponse{Apps: uploadServerApps})
+		},
+		"/api/v1/apps/{app}": func(w http.ResponseWriter, r *http.Request) {
+			for _, app := range uploadServerApps {
+				if app.ID == r.PathValue("app") {
+					_ = json.NewEncoder(w).Encode(api.AppResponse{App: app})
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		},
+		"/api/v1/apps/{app}/uploads": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"build_id":     7,
+				"upload_url":   "http://" + r.Host + "/storage/7",
+				"upload_state": "pending_upload",
+			})
+		},
+		"/storage/{build}": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		},
+		"/api/v1/apps/{app}/uploads/{build}/complete": func(w http.ResponseWriter, r *http.Request) {
+			build := "http://" + r.Host + "/api/v1/apps/" + r.PathValue("app") + "/builds/" + r.PathValue("build")
+			id, _ := strconv.Atoi(r.PathValue("build"))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"build_id":     id,
+				"upload_state": "complete",
+				"status_url":   build,
+				"wait_url":     build + "/wait",
+			})
+		},
+		"/api/v1/apps/{app}/builds": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(api.BuildListResponse{Builds: []api.Build{}})
+		},
+		"GET /api/v1/apps/{app}/builds/{build}":  available,
+		"/api/v1/apps/{app}/builds/{build}/wait": available,
+		"DELETE /api/v1/apps/{app}/builds/{build}": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		},
+	}
+	for _, opt := range opts {
+		opt(routes)
+	}
+	mux := http.NewServeMux()
+	for pattern, handler := range routes {
+		mux.HandleFunc(pattern, handler)
+	}
+
+	server := &uploadServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		server.mu.Lock()
+		server.recorded = append(server.recorded, recordedRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Body: body})
+		server.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// requests returns the requests received so far.
+func (s *uploadServer) requests() []recordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]recordedRequest(nil), s.recorded...)
+}
+
+// paths returns the path of each request received so far.
+func (s *uploadServer) paths() []string {
+	var paths []string
+	for _, req := range s.requests() {
+		paths = append(paths, req.Path)
+	}
+	return paths
+}
+
+// requestsTo returns the requests with method to path.
+func (s *uploadServer) requestsTo(method, path string) []recordedRequest {
+	var matched []recordedRequest
+	for _, req := range s.requests() {
+		if req.Method == method && req.Path == path {
+			matched = append(matched, req)
+		}
+	}
+	return matched
+}
+
+// createdUploads decodes the bodies of the create requests for appID.
+func (s *uploadServer) createdUploads(t *testing.T, appID string) []api.BuildUploadParams {
+	t.Helper()
+	var params []api.BuildUploadParams
+	for _, req := range s.requestsTo(http.MethodPost, "/api/v1/apps/"+appID+"/uploads") {
+		var body api.BuildUploadRequest
+		if err := json.Unmarshal(req.Body, &body); err != nil {
+			t.Fatalf("decode create request: %v", err)
+		}
+		params = append(params, body.Build)
+	}
+	return params
+}
+
+// withRoute serves pattern with handler, replacing the default route.
+func withRoute(pattern string, handler http.HandlerFunc) uploadServerOption {
+	return func(routes map[string]http.HandlerFunc) {
+		routes[pattern] = handler
+	}
+}
+
+// withBuilds lists builds as every app's build history.
+func withBuilds(builds []api.Build) uploadServerOption {
+	return withRoute("/api/v1/apps/{app}/builds", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(api.BuildListResponse{Builds: builds})
+	})
+}
+
+// withExpiringUploadURLs numbers builds by create request, starting at 1,
+// and gives the nth upload URL the nth expiry.
+func withExpiringUploadURLs(expiries ...time.Time) uploadServerOption {
+	var (
+		mu      sync.Mutex
+		creates int
+	)
+	return withRoute("/api/v1/apps/{app}/uploads", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		creates++
+		n := creates
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"build_id":     n,
+			"upload_url":   fmt.Sprintf("http://%s/storage/%d", r.Host, n),
+			"upload_state": "pending_upload",
+			"expires_at":   expiries[n-1].Format(time.RFC3339),
+		})
+	})
+}
+
+// withFailingStorage answers the first storage PUT with status, sending
+// retryAfter as Retry-After when set.
+func withFailingStorage(status int, retryAfter string) uploadServerOption {
+	var (
+		mu   sync.Mutex
+		puts int
+	)
+	return withRoute("/storage/{build}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		puts++
+		first := puts == 1
+		mu.Unlock()
+		if first {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestUploadSaveStateSkipsCompletion(t *testing.T) {
+	server := newUploadServer(t)
+	dir := t.TempDir()
+	zipPath := writeTestZip(t, dir, "MyApp.zip", nil)
+	statePath := filepath.Join(dir, "state", "upload.json")
+
+	if _, _, err := executeCLI(t, server.URL, "build", "upload", "app_123", zipPath, "--save-state", statePath); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	for _, path := range server.paths() {
+		if strings.HasSuffix(path, "/complete") {
+			t.Fatalf("expected no completion request, got %v", server.paths())
+		}
+	}
+
+	state, err := loadUploadState(statePath)
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if state.AppID != "app_123" || state.Upload.BuildID.Int() != 7 || state.File != zipPath {
+		t.Fatalf("unexpected state: %+v", state)
+	}
+	// The state holds a presigned upload URL, so only its owner may read it.
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(statePath)
+		if err != nil {
+			t.Fatalf("stat state: %v", err)
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Fatalf("expected a 0600 state file, got %v", info.Mode())
+		}
+	}
+}
+
+func TestBuildCompleteFromState(t *testing.T) {
+	server := newUploadServer(t)
+	dir := t.TempDir()
+	zipPath := writeTestZip(t, dir, "MyApp.zip", nil)
+	statePath := filepath.Join(dir, "upload.json")
+
+	if _, _, err := executeCLI(t, server.URL, "build", "upload", "app_123", zipPath, "--save-state", statePath); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	stdout, _, err := executeCLI(t, server.URL, "--json", "build", "complete", "--from-state", statePath)
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	var resp api.BuildUploadCompleteResponse
+	if err := json.Unmarshal([]byte(stdout), &resp); err != nil {
+		t.Fatalf("invalid JSON output: %v\nraw: %s", err, stdout)
+	}
+	if resp.BuildID.Int() != 7 || resp.UploadState != "complete" {
+		t.Fatalf("unexpected complete response: %+v", resp)
+	}
+
+	got := server.paths()
+	if last := got[len(got)-1]; last != "/api/v1/apps/app_123/uploads/7/complete" {
+		t.Fatalf("expected completion request last, got %v", got)
+	}
+}
+
+func TestUploadSaveStateRejectsWait(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := writeTestZip(t, dir, "MyApp.zip", nil)
+
+	_, _, err := executeCLI(t, "https://example.com", "build", "upload", "app_123", zipPath, "--save-state", filepath.Join(dir, "s.json"), "--wait")
+	if err == nil || !strings.Contains(err.Error(), "--save-state") {
+		t.Fatalf("expected --save-state/--wait conflict, got %v", err)
+	}
+}
+
+func TestLoadUploadStateRequiresBuildID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upload.json")
+	if err := saveUploadState(path, uploadState{AppID: "app_123"}); err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+	if _, err := loadUploadState(path); err == nil || !strings.Contains(err.Error(), "build_id") {
+		t.Fatalf("expected missing build_id error, got %v", err)
+	}
+}
+
+func TestUploadVerbosityReportsTransferRate(t *testing.T) {
+	server := newUploadServer(t)
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+	_, stderr, err := executeCLI(t, server.URL, "-v", "build", "upload", "app_123", zipPath)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if !strings.Contains(stderr, "Uploaded (") || strings.Contains(stderr, "/s)") {
+		t.Fatalf("expected a plain upload timing at -v, got:\n%s", stderr)
+	}
+
+	_, stderr, err = executeCLI(t, server.URL, "-vv", "build", "upload", "app_123", zipPath)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if !strings.Contains(stderr, "Uploaded ") || !strings.Contains(stderr, "/s)") || !strings.Contains(stderr, "> PUT "+server.URL+"/storage/7") {
+		t.Fatalf("expected transfer rate and HTTP trace at -vv, got:\n%s", stderr)
+	}
+}
+
+func TestUploadFailsOnStoredSizeMismatch(t *testing.T) {
+	server := newUploadServer(t, withRoute("/storage/{build}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Goog-Stored-Content-Length", "1")
+		w.WriteHeader(http.StatusOK)
+	}))
+	zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+	_, _, err := executeCLI(t, server.URL, "build", "upload", "app_123", zipPath)
+	if !errors.Is(err, api.ErrUploadSizeMismatch) {
+		t.Fatalf("expected a size mismatch error, got %v", err)
+	}
+	if containsPath(server.paths(), "/api/v1/apps/app_123/uploads/7/complete") {
+		t.Fatal("expected the upload not to be completed")
+	}
+}
+
+func TestUploadFailsOnBuildSizeMismatch(t *testing.T) {
+	wrongSize := 1
+
+	t.Run("complete response", func(t *testing.T) {
+		server := newUploadServer(t, withRoute("/api/v1/apps/{app}/uploads/{build}/complete", func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(api.BuildUploadCompleteResponse{UploadState: "complete", Metadata: &api.BuildMetadata{BuildSize: &wrongSize}})
+		}))
+		zipPath := writeTestZip(t, t.TempDir(), "MyApp.zip", nil)
+
+		_, _, err := executeCLI(t, server.URL, "build", "upload", "app_123", zipPath)
+		if !errors.Is(err, api.ErrUploadSizeMismatch) {
+			t.Fatalf("expected a size mismatch error, got %v", err)
+		}
+	})
+
+	t.Run("processed build from saved state", func(t *testing.T) {
+		server := newUploadServer(t, withRoute("/api/v1/apps/{app}/builds/{build}/wait", func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(api.BuildResponse{Build: api.Build{ID: 7, Status: api.BuildStatusAvailable, Metadata: &api.BuildMetadata{BuildSize: &wrongSize}}})
+		}))
+		dir := t.TempDir()
+		zipPath := writeTestZip(t, dir, "MyApp.zip", nil)
+		statePath := filepath.Join(dir, "upload.json")
+		if _, _, err := executeCLI(t, server.URL, "build", "upload", "app_123", zipPath, "--save-state", statePath); err != nil {
+			t.Fatalf("upload: %v", err)
+		}
+
+		_, _, err := executeCLI(t, server.URL, "build", "complete", "--from-state", statePath, "--wait")
+		if !errors.Is(err, api.ErrUploadSizeMismatch) || !strings.Contains(err.Error(), "reports a 1-byte build") {
+			t.Fatalf("expected a size mismatch error, got %v", err)
+		}
+	})
+}